@@ -1,10 +1,21 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mwhite7112/woodpantry-matching/internal/api"
 	"github.com/mwhite7112/woodpantry-matching/internal/clients"
@@ -13,6 +24,10 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "score" {
+		os.Exit(runScore(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	logging.Setup()
 	logger := slog.Default()
 
@@ -39,18 +54,479 @@ func main() {
 		os.Exit(1)
 	}
 
-	svc := service.New(
-		clients.NewPantryClient(pantryURL),
-		clients.NewRecipeClient(recipeURL),
-		clients.NewDictionaryClient(dictionaryURL),
-	)
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		logger.Error("tracing setup failed", "error", err)
+		os.Exit(1)
+	}
 
-	handler := api.NewRouter(svc)
+	var clientOpts []clients.ClientOption
+	if v := os.Getenv("UPSTREAM_MAX_RESPONSE_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			logger.Error("invalid UPSTREAM_MAX_RESPONSE_BYTES, expected a positive integer", "value", v)
+			os.Exit(1)
+		}
+		clientOpts = append(clientOpts, clients.WithMaxResponseBytes(n))
+	}
 
-	addr := fmt.Sprintf(":%s", port)
-	logger.Info("matching service listening", "addr", addr)
-	if err := http.ListenAndServe(addr, handler); err != nil {
-		logger.Error("server error", "error", err)
+	if v := os.Getenv("UPSTREAM_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("invalid UPSTREAM_TIMEOUT, expected a positive duration like 5s", "value", v)
+			os.Exit(1)
+		}
+		clientOpts = append(clientOpts, clients.WithTimeout(d))
+	}
+	if v := os.Getenv("UPSTREAM_RETRIES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			logger.Error("invalid UPSTREAM_RETRIES, expected a non-negative integer", "value", v)
+			os.Exit(1)
+		}
+		retry := clients.RetryConfig{MaxRetries: n}
+		if v := os.Getenv("UPSTREAM_RETRY_BASE_DELAY"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				logger.Error("invalid UPSTREAM_RETRY_BASE_DELAY, expected a positive duration like 100ms", "value", v)
+				os.Exit(1)
+			}
+			retry.BaseDelay = d
+		}
+		clientOpts = append(clientOpts, clients.WithRetry(retry))
+	}
+
+	// Responses cached pantry data can serve are at most one pantry TTL stale,
+	// so that is also how long /matches may be cached downstream by default.
+	var cacheMaxAge time.Duration
+	pantryOpts := withToken(clientOpts, "PANTRY_TOKEN")
+	switch strategy := clients.PantryFetchStrategy(os.Getenv("PANTRY_FETCH_STRATEGY")); strategy {
+	case "", clients.PantryFetchFresh: // default strategy
+	case clients.PantryFetchShortCache, clients.PantryFetchConditional:
+		var ttl time.Duration
+		if v := os.Getenv("PANTRY_CACHE_TTL"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				logger.Error("invalid PANTRY_CACHE_TTL, expected a positive duration like 5s", "value", v)
+				os.Exit(1)
+			}
+			ttl = d
+		}
+		if strategy == clients.PantryFetchShortCache {
+			cacheMaxAge = cmp.Or(ttl, clients.DefaultPantryCacheTTL)
+		}
+		pantryOpts = append(slices.Clone(pantryOpts), clients.WithPantryFetchStrategy(strategy, ttl))
+	default:
+		logger.Error("invalid PANTRY_FETCH_STRATEGY, expected fresh, short-cache, or conditional", "value", strategy)
+		os.Exit(1)
+	}
+
+	nameCacheTTL := clients.DefaultNameCacheTTL
+	if v := os.Getenv("DICTIONARY_NAME_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			logger.Error("invalid DICTIONARY_NAME_CACHE_TTL, expected a non-negative duration like 10m", "value", v)
+			os.Exit(1)
+		}
+		nameCacheTTL = d
+	}
+	subCacheTTL := clients.DefaultSubstituteCacheTTL
+	if v := os.Getenv("DICTIONARY_SUBSTITUTE_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			logger.Error("invalid DICTIONARY_SUBSTITUTE_CACHE_TTL, expected a non-negative duration like 10m", "value", v)
+			os.Exit(1)
+		}
+		subCacheTTL = d
+	}
+	breaker := clients.BreakerConfig{Threshold: clients.DefaultBreakerThreshold}
+	if v := os.Getenv("DICTIONARY_BREAKER_THRESHOLD"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			logger.Error("invalid DICTIONARY_BREAKER_THRESHOLD, expected a non-negative integer", "value", v)
+			os.Exit(1)
+		}
+		breaker.Threshold = n
+	}
+	if v := os.Getenv("DICTIONARY_BREAKER_COOLDOWN"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("invalid DICTIONARY_BREAKER_COOLDOWN, expected a positive duration like 30s", "value", v)
+			os.Exit(1)
+		}
+		breaker.Cooldown = d
+	}
+	dictionaryOpts := append(slices.Clone(withToken(clientOpts, "DICTIONARY_TOKEN")),
+		clients.WithNameCacheTTL(nameCacheTTL), clients.WithSubstituteCacheTTL(subCacheTTL),
+		clients.WithCircuitBreaker(breaker))
+
+	var opts []service.Option
+
+	defaultMaxMissing, err := defaultMaxMissingFromEnv()
+	if err != nil {
+		logger.Error("invalid DEFAULT_MAX_MISSING, expected a non-negative integer", "error", err)
+		os.Exit(1)
+	}
+	opts = append(opts, service.WithDefaultMaxMissing(defaultMaxMissing))
+
+	if v := os.Getenv("TAG_MAX_MISSING"); v != "" {
+		tagMaxMissing, err := parseTagMaxMissing(v)
+		if err != nil {
+			logger.Error("invalid TAG_MAX_MISSING", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithTagMaxMissing(tagMaxMissing))
+	}
+
+	if v := os.Getenv("SCORE_BUDGET"); v != "" {
+		budget, err := time.ParseDuration(v)
+		if err != nil || budget <= 0 {
+			logger.Error("invalid SCORE_BUDGET, expected a positive duration like 3s", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithScoreBudget(budget))
+	}
+
+	if v := os.Getenv("MAX_SUBSTITUTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			logger.Error("invalid MAX_SUBSTITUTES, expected a non-negative integer", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithMaxSubstitutes(n))
+	}
+
+	if v := os.Getenv("VERSATILITY_WEIGHTS"); v != "" {
+		weights, err := parseVersatilityWeights(v)
+		if err != nil {
+			logger.Error("invalid VERSATILITY_WEIGHTS", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithVersatilityWeights(weights))
+	}
+
+	if v := os.Getenv("OPTIONAL_SUBSTITUTES"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Error("invalid OPTIONAL_SUBSTITUTES, expected true or false", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithOptionalSubstitutes(enabled))
+	}
+
+	if v := os.Getenv("SUBSTITUTION_PENALTY"); v != "" {
+		penalty, err := strconv.ParseFloat(v, 64)
+		if err != nil || penalty < 0 {
+			logger.Error("invalid SUBSTITUTION_PENALTY, expected a non-negative number", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithSubstitutionPenalty(penalty))
+	}
+
+	if v := os.Getenv("SUBSTITUTE_USAGE"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Error("invalid SUBSTITUTE_USAGE, expected true or false", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithSubstituteUsage(enabled))
+	}
+
+	if v := os.Getenv("SUBSTITUTE_MIN_COVERAGE"); v != "" {
+		pct, err := strconv.ParseFloat(v, 64)
+		if err != nil || pct < 0 || pct > 100 {
+			logger.Error("invalid SUBSTITUTE_MIN_COVERAGE, expected a number between 0 and 100", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithSubstituteMinCoverage(pct))
+	}
+
+	if v := os.Getenv("STATS_EXCLUDE_ZERO_REQUIRED"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Error("invalid STATS_EXCLUDE_ZERO_REQUIRED, expected true or false", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithStatsExcludeZeroRequired(enabled))
+	}
+
+	if v := os.Getenv("VALIDATE_SUBSTITUTES"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Error("invalid VALIDATE_SUBSTITUTES, expected true or false", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithSubstituteValidation(enabled))
+	}
+
+	if v := os.Getenv("MAX_NAME_LOOKUPS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			logger.Error("invalid MAX_NAME_LOOKUPS, expected a positive integer", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithMaxNameLookups(n))
+	}
+
+	if v := os.Getenv("DICTIONARY_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			logger.Error("invalid DICTIONARY_CONCURRENCY, expected a positive integer", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithDictionaryConcurrency(n))
+	}
+
+	switch policy := service.SubstitutionReportPolicy(os.Getenv("SUBSTITUTION_REPORT")); policy {
+	case "": // default policy
+	case service.SubstitutionReportNecessary, service.SubstitutionReportAvailable:
+		opts = append(opts, service.WithSubstitutionReport(policy))
+	default:
+		logger.Error("invalid SUBSTITUTION_REPORT, expected necessary or available", "value", policy)
+		os.Exit(1)
+	}
+
+	switch policy := service.NameFallbackPolicy(os.Getenv("NAME_FALLBACK")); policy {
+	case "": // default policy
+	case service.NameFallbackEmpty, service.NameFallbackID, service.NameFallbackPlaceholder:
+		opts = append(opts, service.WithNameFallback(policy))
+	default:
+		logger.Error("invalid NAME_FALLBACK, expected empty, id, or placeholder", "value", policy)
+		os.Exit(1)
+	}
+
+	switch policy := service.NullRecipePolicy(os.Getenv("NULL_RECIPE_POLICY")); policy {
+	case "": // default policy
+	case service.NullRecipeEmpty, service.NullRecipeError:
+		opts = append(opts, service.WithNullRecipePolicy(policy))
+	default:
+		logger.Error("invalid NULL_RECIPE_POLICY, expected empty or error", "value", policy)
+		os.Exit(1)
+	}
+
+	switch policy := service.DuplicateRecipePolicy(os.Getenv("DUPLICATE_RECIPE_POLICY")); policy {
+	case "": // default policy
+	case service.DuplicateRecipeKeepFirst, service.DuplicateRecipeKeepLast:
+		opts = append(opts, service.WithDuplicateRecipePolicy(policy))
+	default:
+		logger.Error("invalid DUPLICATE_RECIPE_POLICY, expected first or last", "value", policy)
+		os.Exit(1)
+	}
+
+	if v := os.Getenv("COVERAGE_DECIMALS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n > 10 {
+			logger.Error("invalid COVERAGE_DECIMALS, expected an integer up to 10 (negative disables)", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithCoverageDecimals(n))
+	}
+
+	if v := os.Getenv("PANTRY_STAPLES"); v != "" {
+		opts = append(opts, service.WithPantryStaples(parseStaples(v)))
+	}
+
+	if v := os.Getenv("PANTRY_QUANTITY_FLOOR"); v != "" {
+		floor, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(floor) {
+			logger.Error("invalid PANTRY_QUANTITY_FLOOR, expected a number", "value", v)
+			os.Exit(1)
+		}
+		opts = append(opts, service.WithPantryQuantityFloor(floor))
+	}
+
+	switch policy := service.EmptyRecipePolicy(os.Getenv("EMPTY_RECIPE_POLICY")); policy {
+	case "": // default policy
+	case service.EmptyRecipeMakeable, service.EmptyRecipeExclude:
+		opts = append(opts, service.WithEmptyRecipePolicy(policy))
+	default:
+		logger.Error("invalid EMPTY_RECIPE_POLICY, expected makeable or exclude", "value", policy)
+		os.Exit(1)
+	}
+
+	pantry := clients.NewPantryClient(pantryURL, pantryOpts...)
+	recipes := clients.NewRecipeClient(recipeURL, withToken(clientOpts, "RECIPE_TOKEN")...)
+	dictionary := clients.NewDictionaryClient(dictionaryURL, dictionaryOpts...)
+	svc := service.New(pantry, recipes, dictionary, opts...)
+
+	if v := os.Getenv("MATCHES_CACHE_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			logger.Error("invalid MATCHES_CACHE_MAX_AGE, expected a non-negative duration like 30s", "value", v)
+			os.Exit(1)
+		}
+		cacheMaxAge = d
+	}
+
+	routerOpts := []api.RouterOption{
+		api.WithCacheMaxAge(cacheMaxAge),
+		api.WithCacheStats(dictionary),
+		api.WithReadinessChecks(map[string]api.Pinger{
+			"pantry":     pantry,
+			"recipes":    recipes,
+			"dictionary": dictionary,
+		}),
+	}
+	if v := os.Getenv("REQUEST_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("invalid REQUEST_TIMEOUT, expected a positive duration like 10s", "value", v)
+			os.Exit(1)
+		}
+		routerOpts = append(routerOpts, api.WithRequestTimeout(d))
+	}
+	if key := os.Getenv("API_KEY"); key != "" {
+		routerOpts = append(routerOpts, api.WithAPIKey(os.Getenv("API_KEY_HEADER"), key))
+	}
+	if v := os.Getenv("REQUEST_LOG_LEVEL"); v != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(v)); err != nil {
+			logger.Error("invalid REQUEST_LOG_LEVEL, expected debug, info, warn, or error", "value", v)
+			os.Exit(1)
+		}
+		routerOpts = append(routerOpts, api.WithRequestLogLevel(level))
+	}
+
+	handler := api.NewRouter(svc, routerOpts...)
+
+	drainTimeout := defaultDrainTimeout
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Error("invalid SHUTDOWN_TIMEOUT, expected a positive duration like 15s", "value", v)
+			os.Exit(1)
+		}
+		drainTimeout = d
+	}
+
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		logger.Error("listen failed", "error", err)
 		os.Exit(1)
 	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = run(ctx, ln, handler, drainTimeout)
+	flushCtx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Warn("flushing spans failed", "error", err)
+	}
+	cancel()
+	logger.Info("dictionary cache stats",
+		"names", dictionary.NameCacheStats(),
+		"substitutes", dictionary.SubstituteCacheStats(),
+	)
+	if err != nil {
+		logger.Error("server error", "error", err)
+		os.Exit(1) //nolint:gocritic // stop only releases the signal handler
+	}
+}
+
+// tracingFlushTimeout bounds exporting the spans still buffered at exit.
+const tracingFlushTimeout = 5 * time.Second
+
+// defaultDrainTimeout is how long in-flight requests may take to finish after
+// a shutdown signal when SHUTDOWN_TIMEOUT is unset.
+const defaultDrainTimeout = 15 * time.Second
+
+// run serves handler on ln until ctx is cancelled, then stops accepting
+// connections and waits up to drainTimeout for in-flight requests to finish.
+func run(ctx context.Context, ln net.Listener, handler http.Handler, drainTimeout time.Duration) error {
+	logger := slog.Default()
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("matching service listening", "addr", ln.Addr().String())
+		serveErr <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutdown started", "drain_timeout", drainTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), drainTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.Info("shutdown complete")
+	return nil
+}
+
+// withToken adds the bearer token in env, if set, to a copy of opts.
+func withToken(opts []clients.ClientOption, env string) []clients.ClientOption {
+	token := os.Getenv(env)
+	if token == "" {
+		return opts
+	}
+	return append(slices.Clone(opts), clients.WithToken(token))
+}
+
+// defaultMaxMissingFromEnv reads DEFAULT_MAX_MISSING, the max_missing used
+// by requests that do not set one. Unset means 0.
+func defaultMaxMissingFromEnv() (int, error) {
+	v := os.Getenv("DEFAULT_MAX_MISSING")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("value %q must be a non-negative integer", v)
+	}
+	return n, nil
+}
+
+// parseStaples parses a comma-separated list of ingredient IDs, e.g.
+// "salt, water,pepper", skipping empty entries.
+func parseStaples(v string) []string {
+	var ids []string
+	for id := range strings.SplitSeq(v, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// parseTagMaxMissing parses a comma-separated list of tag=N pairs,
+// e.g. "flexible=3,weeknight=1".
+func parseTagMaxMissing(v string) (map[string]int, error) {
+	m := make(map[string]int)
+	for pair := range strings.SplitSeq(v, ",") {
+		tag, n, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || tag == "" {
+			return nil, fmt.Errorf("malformed entry %q, expected tag=N", pair)
+		}
+		maxMissing, err := strconv.Atoi(n)
+		if err != nil || maxMissing < 0 {
+			return nil, fmt.Errorf("tag %q: max_missing must be a non-negative integer", tag)
+		}
+		m[tag] = maxMissing
+	}
+	return m, nil
+}
+
+// parseVersatilityWeights parses "makeable,near_miss" weights, e.g. "1,0.5".
+func parseVersatilityWeights(v string) (service.VersatilityWeights, error) {
+	m, n, ok := strings.Cut(v, ",")
+	if !ok {
+		return service.VersatilityWeights{}, fmt.Errorf("malformed value %q, expected makeable,near_miss", v)
+	}
+	makeable, err := strconv.ParseFloat(strings.TrimSpace(m), 64)
+	if err != nil || makeable <= 0 {
+		return service.VersatilityWeights{}, errors.New("makeable weight must be a positive number")
+	}
+	nearMiss, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+	if err != nil || nearMiss < 0 {
+		return service.VersatilityWeights{}, errors.New("near_miss weight must be a non-negative number")
+	}
+	return service.VersatilityWeights{Makeable: makeable, NearMiss: nearMiss}, nil
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/api"
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+	"github.com/mwhite7112/woodpantry-matching/internal/service"
+)
+
+func TestRun_DrainsInFlightRequests(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- run(ctx, ln, handler, time.Second) }()
+
+	type response struct {
+		body string
+		err  error
+	}
+	resp := make(chan response, 1)
+	go func() {
+		res, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			resp <- response{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		resp <- response{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	got := <-resp
+	require.NoError(t, got.err)
+	assert.Equal(t, "done", got.body)
+	require.NoError(t, <-runErr)
+}
+
+func TestRun_DrainTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- run(ctx, ln, handler, 20*time.Millisecond) }()
+	go http.Get("http://" + ln.Addr().String()) //nolint:errcheck,bodyclose // request never completes
+
+	<-started
+	cancel()
+
+	require.ErrorIs(t, <-runErr, context.DeadlineExceeded)
+}
+
+func TestDefaultMaxMissingFromEnv(t *testing.T) {
+	t.Setenv("DEFAULT_MAX_MISSING", "1")
+	n, err := defaultMaxMissingFromEnv()
+	require.NoError(t, err)
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "pasta"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "pasta"}, {IngredientID: "basil"}}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(nil, clients.ErrIngredientNotFound).Maybe()
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+
+	svc := service.New(pantryMock, recipeMock, dictMock, service.WithDefaultMaxMissing(n))
+	handler := api.NewRouter(svc)
+
+	count := func(target string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var results []json.RawMessage
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+		return len(results)
+	}
+	assert.Equal(t, 1, count("/matches"), "unparameterized request uses DEFAULT_MAX_MISSING")
+	assert.Equal(t, 0, count("/matches?max_missing=0"), "explicit max_missing overrides it")
+}
+
+func TestDefaultMaxMissingFromEnv_Invalid(t *testing.T) {
+	for _, v := range []string{"-1", "two"} {
+		t.Setenv("DEFAULT_MAX_MISSING", v)
+		_, err := defaultMaxMissingFromEnv()
+		assert.Error(t, err, v)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/service"
+)
+
+// runScore implements `matching score`: it scores recipes from local JSON
+// files with [service.ScoreRecipes] and prints the results as JSON, without
+// contacting any service. It returns the process exit code.
+func runScore(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("score", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	pantryPath := fs.String("pantry", "", "JSON file with the pantry items (required)")
+	recipesPath := fs.String("recipes", "", "JSON file with the recipes (required)")
+	subsPath := fs.String("subs", "", "JSON file mapping ingredient IDs to substitutes; enables substitutes")
+	maxMissing := fs.Int("max-missing", 0, "return recipes missing at most N required ingredients")
+	includeUnmakeable := fs.Bool("include-unmakeable", false, "also return recipes that exceed max-missing")
+	strategy := fs.String("strategy", "", "scoring strategy: presence (default) or quantity")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if *pantryPath == "" || *recipesPath == "" {
+		fmt.Fprintln(stderr, "score: --pantry and --recipes are required")
+		fs.Usage()
+		return 2
+	}
+
+	var (
+		pantry  []clients.PantryItem
+		recipes []clients.Recipe
+		subs    map[string][]clients.IngredientSubstitute
+	)
+	if err := readJSONFile(*pantryPath, &pantry); err != nil {
+		fmt.Fprintln(stderr, "score:", err)
+		return 1
+	}
+	if err := readJSONFile(*recipesPath, &recipes); err != nil {
+		fmt.Fprintln(stderr, "score:", err)
+		return 1
+	}
+	if *subsPath != "" {
+		if err := readJSONFile(*subsPath, &subs); err != nil {
+			fmt.Fprintln(stderr, "score:", err)
+			return 1
+		}
+	}
+
+	results, err := service.ScoreRecipes(pantry, recipes, subs, service.ScoreOptions{
+		AllowSubs:         subs != nil,
+		MaxMissing:        max(*maxMissing, 0),
+		IncludeUnmakeable: *includeUnmakeable,
+		Strategy:          *strategy,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "score:", err)
+		return 2
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(results); err != nil {
+		fmt.Fprintln(stderr, "score:", err)
+		return 1
+	}
+	return 0
+}
+
+// readJSONFile decodes the JSON file at path into v.
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/service"
+)
+
+func TestRunScore(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		wantIDs  []string
+		wantSubs map[string]string
+	}{
+		{
+			name:    "makeable only",
+			args:    []string{"--pantry", "testdata/pantry.json", "--recipes", "testdata/recipes.json"},
+			wantIDs: []string{"aglio-olio"},
+		},
+		{
+			name: "with substitutes",
+			args: []string{
+				"--pantry", "testdata/pantry.json",
+				"--recipes", "testdata/recipes.json",
+				"--subs", "testdata/subs.json",
+			},
+			wantIDs:  []string{"aglio-olio", "buttered-noodles"},
+			wantSubs: map[string]string{"butter": "olive-oil"},
+		},
+		{
+			name: "include unmakeable",
+			args: []string{
+				"--pantry", "testdata/pantry.json",
+				"--recipes", "testdata/recipes.json",
+				"--include-unmakeable",
+			},
+			wantIDs: []string{"aglio-olio", "buttered-noodles"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := runScore(tc.args, &stdout, &stderr)
+			require.Equal(t, 0, code, stderr.String())
+
+			var results []service.MatchResult
+			require.NoError(t, json.Unmarshal(stdout.Bytes(), &results))
+			ids := make([]string, 0, len(results))
+			for _, r := range results {
+				ids = append(ids, r.Recipe.ID)
+			}
+			assert.Equal(t, tc.wantIDs, ids)
+			assert.True(t, results[0].CanMake)
+			assert.InDelta(t, 100.0, results[0].CoveragePct, 0.0001)
+			if tc.wantSubs != nil {
+				assert.Equal(t, tc.wantSubs, results[1].SubstitutedWith)
+			}
+		})
+	}
+}
+
+func TestRunScore_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "missing flags", args: []string{"--pantry", "testdata/pantry.json"}, wantCode: 2,
+			wantErr: "--pantry and --recipes are required"},
+		{name: "missing file", args: []string{"--pantry", "testdata/nope.json", "--recipes", "testdata/recipes.json"},
+			wantCode: 1, wantErr: "nope.json"},
+		{name: "malformed file", args: []string{"--pantry", "testdata/subs.json", "--recipes", "testdata/recipes.json"},
+			wantCode: 1, wantErr: "testdata/subs.json"},
+		{name: "unknown strategy", args: []string{
+			"--pantry", "testdata/pantry.json", "--recipes", "testdata/recipes.json", "--strategy", "vibes",
+		}, wantCode: 2, wantErr: "unknown scoring strategy"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tc.wantCode, runScore(tc.args, &stdout, &stderr))
+			assert.Contains(t, stderr.String(), tc.wantErr)
+			assert.Empty(t, stdout.String())
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// DefaultAPIKeyHeader is the request header checked by [WithAPIKey] when no
+// header is given.
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// WithAPIKey requires every request except /healthz and /readyz to carry key
+// in header (DefaultAPIKeyHeader if empty). An empty key disables the check.
+func WithAPIKey(header, key string) RouterOption {
+	return func(c *routerConfig) {
+		if header == "" {
+			header = DefaultAPIKeyHeader
+		}
+		c.apiKeyHeader = header
+		c.apiKey = key
+	}
+}
+
+// requireAPIKey rejects requests whose header does not hold key with a 401.
+// The comparison is constant-time so the key cannot be probed by timing.
+func requireAPIKey(header, key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get(header)), []byte(key)) != 1 {
+				writeError(w, map[string]string{"error": "missing or invalid API key"}, http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
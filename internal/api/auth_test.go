@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+	"github.com/mwhite7112/woodpantry-matching/internal/service"
+)
+
+func TestAPIKey(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		opts       []RouterOption
+		header     string
+		key        string
+		wantStatus int
+	}{
+		{name: "disabled", wantStatus: http.StatusOK},
+		{name: "disabled by empty key", opts: []RouterOption{WithAPIKey("", "")}, wantStatus: http.StatusOK},
+		{
+			name:       "valid key",
+			opts:       []RouterOption{WithAPIKey("", "s3cret")},
+			header:     DefaultAPIKeyHeader,
+			key:        "s3cret",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "valid key in custom header",
+			opts:       []RouterOption{WithAPIKey("X-Gateway-Key", "s3cret")},
+			header:     "X-Gateway-Key",
+			key:        "s3cret",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid key",
+			opts:       []RouterOption{WithAPIKey("", "s3cret")},
+			header:     DefaultAPIKeyHeader,
+			key:        "guess",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{name: "missing key", opts: []RouterOption{WithAPIKey("", "s3cret")}, wantStatus: http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil).Maybe()
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil).Maybe()
+			svc := service.New(pantryMock, recipeMock, mocks.NewMockDictionaryFetcher(t))
+			router := NewRouter(svc, tc.opts...)
+
+			req := httptest.NewRequest(http.MethodGet, "/matches", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.key)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code)
+			if tc.wantStatus == http.StatusUnauthorized {
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				var body map[string]string
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Equal(t, "missing or invalid API key", body["error"])
+			}
+		})
+	}
+}
+
+func TestAPIKey_ProbesStayOpen(t *testing.T) {
+	svc := service.New(
+		mocks.NewMockPantryFetcher(t), mocks.NewMockRecipeFetcher(t), mocks.NewMockDictionaryFetcher(t))
+	router := NewRouter(svc, WithAPIKey("", "s3cret"))
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+	}
+}
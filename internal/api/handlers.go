@@ -1,26 +1,174 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
 	"github.com/mwhite7112/woodpantry-matching/internal/logging"
 	"github.com/mwhite7112/woodpantry-matching/internal/service"
 )
 
-func NewRouter(svc *service.Service) http.Handler {
+// readinessTimeout bounds each upstream check made by GET /readyz.
+const readinessTimeout = 2 * time.Second
+
+// routerConfig holds optional router settings.
+type routerConfig struct {
+	cacheControl    string
+	requestLogLevel slog.Level
+	readiness       map[string]Pinger
+	apiKeyHeader    string
+	apiKey          string
+	requestTimeout  time.Duration
+	cacheStats      CacheStatsReporter
+}
+
+// Pinger checks that an upstream dependency is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// RouterOption configures optional router behaviour.
+type RouterOption func(*routerConfig)
+
+// WithCacheMaxAge lets clients and intermediaries cache GET /matches
+// responses for d. Zero, the default, sends Cache-Control: no-cache.
+func WithCacheMaxAge(d time.Duration) RouterOption {
+	return func(c *routerConfig) {
+		c.cacheControl = cacheControlFor(d)
+	}
+}
+
+// WithRequestLogLevel sets the level successful requests are logged at.
+// The default is info; 4xx and 5xx responses are always logged at warn and
+// error.
+func WithRequestLogLevel(level slog.Level) RouterOption {
+	return func(c *routerConfig) {
+		c.requestLogLevel = level
+	}
+}
+
+// WithReadinessChecks makes GET /readyz ping each dependency, keyed by the
+// name reported in its response. Without checks /readyz always reports ready.
+func WithReadinessChecks(checks map[string]Pinger) RouterOption {
+	return func(c *routerConfig) {
+		c.readiness = checks
+	}
+}
+
+// WithRequestTimeout bounds each scoring request to d in total, across the
+// pantry, recipe and dictionary calls it makes; a request still waiting on
+// an upstream when d expires gets 504. Zero, the default, sets no deadline
+// beyond the clients' own timeouts. /healthz and /readyz are not affected.
+func WithRequestTimeout(d time.Duration) RouterOption {
+	return func(c *routerConfig) {
+		c.requestTimeout = d
+	}
+}
+
+// CacheStatsReporter reports the hits and misses of the dictionary client's
+// caches.
+type CacheStatsReporter interface {
+	NameCacheStats() clients.CacheStats
+	SubstituteCacheStats() clients.CacheStats
+}
+
+// WithCacheStats serves c's cache counters at GET /matches/stats/cache.
+// Without it the endpoint reports zeros.
+func WithCacheStats(c CacheStatsReporter) RouterOption {
+	return func(cfg *routerConfig) {
+		cfg.cacheStats = c
+	}
+}
+
+// timeoutContext bounds each request's context by d.
+func timeoutContext(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// forwardHeaders stores the request's correlation and trace headers in its
+// context so upstream calls carry them, and continues the incoming trace so
+// the request's spans join it. A request without X-Request-ID forwards the
+// ID the RequestID middleware generated instead.
+func forwardHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := r.Header
+		if h.Get(middleware.RequestIDHeader) == "" {
+			if id := middleware.GetReqID(r.Context()); id != "" {
+				h = h.Clone()
+				h.Set(middleware.RequestIDHeader, id)
+			}
+		}
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(clients.WithForwardedHeaders(ctx, h)))
+	})
+}
+
+// cacheControlFor returns the Cache-Control value allowing caching for d.
+func cacheControlFor(d time.Duration) string {
+	if secs := int(d / time.Second); secs > 0 {
+		return fmt.Sprintf("public, max-age=%d", secs)
+	}
+	return "no-cache"
+}
+
+func NewRouter(svc *service.Service, opts ...RouterOption) http.Handler {
+	cfg := routerConfig{cacheControl: cacheControlFor(0), requestLogLevel: slog.LevelInfo}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	r := chi.NewRouter()
-	r.Use(logging.Middleware)
+	r.Use(middleware.RequestID)
+	r.Use(forwardHeaders)
+	r.Use(logging.RequestLogger(cfg.requestLogLevel))
 	r.Use(middleware.Recoverer)
 
 	r.Get("/healthz", handleHealth)
-	r.Get("/matches", handleGetMatches(svc))
-	r.Post("/matches/query", handlePostMatchQuery(svc))
+	r.Get("/readyz", handleReady(cfg.readiness))
+
+	r.Group(func(r chi.Router) {
+		if cfg.apiKey != "" {
+			r.Use(requireAPIKey(cfg.apiKeyHeader, cfg.apiKey))
+		}
+		if cfg.requestTimeout > 0 {
+			r.Use(timeoutContext(cfg.requestTimeout))
+		}
+		r.Get("/matches", handleGetMatches(svc, cfg.cacheControl))
+		r.Get("/matches/stats", handleGetStats(svc))
+		r.Get("/matches/bands", handleGetBands(svc))
+		r.Get("/matches/preview", handleGetPreview(svc))
+		r.Get("/matches/stats/substitutes", handleGetSubstituteUsage(svc))
+		r.Get("/matches/stats/cache", handleGetCacheStats(cfg.cacheStats))
+		r.Get("/matches/{recipeID}/explain", handleGetExplain(svc))
+		r.Post("/matches/query", handlePostMatchQuery(svc))
+		r.Post("/matches/meal", handlePostMeal(svc))
+		r.Post("/matches/diff", handlePostDiff(svc))
+		r.Post("/matches/batch", handlePostBatch(svc))
+		r.Get("/recipes/{recipeID}/missing", handleGetRecipeMissing(svc))
+	})
 
 	return r
 }
@@ -29,42 +177,490 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("ok")) //nolint:errcheck
 }
 
+// readyResponse is the body of GET /readyz: "ok" overall and per dependency,
+// or the failing dependencies' errors.
+type readyResponse struct {
+	Status       string            `json:"status"`
+	Dependencies map[string]string `json:"dependencies"`
+}
+
+// handleReady pings every dependency concurrently and responds 503 if any is
+// unreachable. Unlike /healthz it is meant for readiness probes.
+func handleReady(checks map[string]Pinger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		resp := readyResponse{Status: "ok", Dependencies: make(map[string]string, len(checks))}
+		var (
+			mu sync.Mutex
+			wg sync.WaitGroup
+		)
+		for name, p := range checks {
+			wg.Go(func() {
+				status := "ok"
+				if err := p.Ping(ctx); err != nil {
+					status = err.Error()
+				}
+				mu.Lock()
+				resp.Dependencies[name] = status
+				mu.Unlock()
+			})
+		}
+		wg.Wait()
+
+		for _, status := range resp.Dependencies {
+			if status != "ok" {
+				resp.Status = "unavailable"
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(resp) //nolint:errcheck
+	}
+}
+
 // handleGetMatches scores all recipes against the current pantry.
 //
 // Query params:
 //   - allow_subs=true — treat substitute ingredients as equivalent when scoring
-//   - max_missing=N   — include recipes missing at most N required ingredients (default 0)
-func handleGetMatches(svc *service.Service) http.HandlerFunc {
+//   - sub_depth=N — follow substitute chains up to N hops with allow_subs (default 1)
+//   - max_missing=N   — include recipes missing at most N required ingredients (default DEFAULT_MAX_MISSING)
+//   - can_make_min_coverage=P — also require P% coverage (0-100) for can_make
+//   - min_coverage=P  — drop recipes below P% coverage (0-100), composing with max_missing
+//   - treat_optional_as_required=true — optional ingredients count toward coverage and can_make
+//   - include_unmakeable=true — also return recipes that fail max_missing (can_make=false)
+//   - max_calories=N  — drop recipes with more than N calories per serving
+//   - max_total_minutes=N — drop recipes whose prep plus cook time exceeds N
+//   - tags=T          — keep recipes carrying every listed tag (repeatable); tags_any=true keeps any
+//   - staples=ID      — replace PANTRY_STAPLES for this request (repeatable); staples= disables them
+//   - use_groups=true — treat members of an ingredient's substitution group as equivalent
+//   - exclude_subs=ID — never use this substitute; "ingredientID:substituteID" scopes it (repeatable)
+//   - exclude_allergens=A — drop recipes with any ingredient tagged with, or not checkable for, allergen A (repeatable)
+//   - include_matched=true — list the required ingredients the pantry satisfies
+//   - include_names=true — name every ingredient of each returned recipe
+//   - include_have=true — report how much of each missing ingredient the pantry already holds
+//   - near_miss_missing=N — also return unmakeable recipes missing at most N ingredients, flagged near_miss
+//   - near_miss_coverage=P — also return unmakeable recipes with at least P% coverage, flagged near_miss
+//   - substitution_summary=true — wrap results in an object with a summary of substitutions used
+//   - suggest_subs=true — list known substitutes on missing ingredients, in-pantry first; independent of allow_subs
+//   - seed=S          — deterministically shuffle tied recipes (falls back to the X-Rank-Seed header)
+//   - strategy=NAME   — scoring strategy: presence (default) or quantity
+//   - quantity_check=true — shorthand for strategy=quantity
+//   - servings=N      — scale recipe quantities from each recipe's base servings to N
+//   - include_summary=true — add a one-line summary such as "Ready to cook" to each result
+//   - sort=MODE       — coverage (default), use_most (most distinct pantry ingredients used first),
+//     time (quickest first) or missing (fewest missing first); ties keep the coverage order
+//   - meal_plan=true  — allocate pantry quantities down the ranking and report the remaining pantry
+//   - paginated=true  — wrap results with their total; limit=N and offset=N select a page
+//   - include_meta=true — wrap results with meta counts of recipes considered and pantry items
+//   - strict=true     — reject unknown query params with 400 instead of logging a warning
+//   - pretty=true     — indent the JSON response for reading in a browser
+//
+// With Accept: application/x-ndjson the (paginated) results are streamed one
+// JSON object per line instead.
+func handleGetMatches(svc *service.Service, cacheControl string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		allowSubs := r.URL.Query().Get("allow_subs") == "true"
+		if !checkParams(w, r, matchesParams) {
+			return
+		}
+		opts, err := parseScoreOptions(r, svc)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		pg, err := parsePage(r.URL.Query())
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
-		maxMissing := 0
-		if s := r.URL.Query().Get("max_missing"); s != "" {
-			n, err := strconv.Atoi(s)
+		res, err := svc.Score(r.Context(), opts)
+		if err != nil {
+			scoringError(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+		// The same URL serves JSON or NDJSON, and the seed header reorders ties.
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "X-Rank-Seed")
+		if etag := matchesETag(res, r); etag != "" {
+			w.Header().Set("ETag", etag)
+			if etagMatches(r.Header.Get("If-None-Match"), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		if acceptsNDJSON(r) {
+			streamMatches(w, res, pg)
+			return
+		}
+		writeMatches(w, r, res, opts, pg, r.URL.Query().Get("include_meta") == "true")
+	}
+}
+
+// handleGetStats summarises pantry-to-catalog fit as a coverage histogram.
+// Accepts the same scoring query params as GET /matches.
+func handleGetStats(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseScoreOptions(r, svc)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		stats, err := svc.Stats(r.Context(), opts)
+		if err != nil {
+			scoringError(w, r, err)
+			return
+		}
+		jsonOK(w, r, stats)
+	}
+}
+
+// handleGetPreview returns the recipes that become makeable if the given
+// ingredients are bought. Accepts the same scoring query params as
+// GET /matches, plus:
+//   - buy=ID — ingredient to add to the pantry (required, repeatable)
+func handleGetPreview(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseScoreOptions(r, svc)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		buy := r.URL.Query()["buy"]
+		if len(buy) == 0 {
+			jsonError(w, "buy is required", http.StatusBadRequest)
+			return
+		}
+		opts.SubstitutionSummary = false
+		opts.MealPlan = false
+
+		res, err := svc.Preview(r.Context(), opts, buy)
+		if err != nil {
+			scoringError(w, r, err)
+			return
+		}
+		writeMatches(w, r, res, opts, page{}, false)
+	}
+}
+
+// handleGetExplain breaks down how one recipe scored: each required
+// ingredient in recipe order, whether it was matched directly, via a
+// substitute, or is missing, with the running coverage. Accepts the same
+// scoring query params as GET /matches; recipe filters are ignored.
+func handleGetExplain(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseScoreOptions(r, svc)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		exp, err := svc.Explain(r.Context(), chi.URLParam(r, "recipeID"), opts)
+		if errors.Is(err, service.ErrRecipeNotFound) {
+			jsonError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			scoringError(w, r, err)
+			return
+		}
+		setWarnings(w, exp.Warnings)
+		jsonOK(w, r, exp)
+	}
+}
+
+// handleGetRecipeMissing lists the required ingredients one recipe is missing
+// from the current pantry, with names, for a recipe detail page. It does not
+// score the rest of the catalog.
+func handleGetRecipeMissing(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		missing, err := svc.Missing(r.Context(), chi.URLParam(r, "recipeID"))
+		if errors.Is(err, service.ErrRecipeNotFound) {
+			jsonError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			scoringError(w, r, err)
+			return
+		}
+		setWarnings(w, missing.Warnings)
+		jsonOK(w, r, missing)
+	}
+}
+
+// handleGetSubstituteUsage reports how often each substitute has been used in
+// returned matches since the service started.
+func handleGetSubstituteUsage(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonOK(w, r, svc.SubstituteUsage())
+	}
+}
+
+// cacheStatsResponse is the body of GET /matches/stats/cache.
+type cacheStatsResponse struct {
+	Names       clients.CacheStats `json:"names"`
+	Substitutes clients.CacheStats `json:"substitutes"`
+}
+
+// handleGetCacheStats reports the dictionary cache counters since startup.
+func handleGetCacheStats(c CacheStatsReporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var resp cacheStatsResponse
+		if c != nil {
+			resp.Names = c.NameCacheStats()
+			resp.Substitutes = c.SubstituteCacheStats()
+		}
+		jsonOK(w, r, resp)
+	}
+}
+
+// handleGetBands groups the catalog into coverage bands with counts and
+// sample recipes. Accepts the same scoring query params as GET /matches, plus:
+//   - bands=100,80,50,0 — comma-separated band lower bounds (0-100)
+//   - samples=N — sample recipes listed per band (default 3)
+func handleGetBands(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseScoreOptions(r, svc)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var mins []float64
+		if v := r.URL.Query().Get("bands"); v != "" {
+			for part := range strings.SplitSeq(v, ",") {
+				n, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+				if err != nil || n < 0 || n > 100 {
+					jsonError(w, "bands must be a comma-separated list of numbers between 0 and 100", http.StatusBadRequest)
+					return
+				}
+				mins = append(mins, n)
+			}
+		}
+
+		samples := service.DefaultBandSamples
+		if v := r.URL.Query().Get("samples"); v != "" {
+			n, err := strconv.Atoi(v)
 			if err != nil || n < 0 {
-				jsonError(w, "max_missing must be a non-negative integer", http.StatusBadRequest)
+				jsonError(w, "samples must be a non-negative integer", http.StatusBadRequest)
 				return
 			}
-			maxMissing = n
+			samples = n
 		}
 
-		results, err := svc.Score(r.Context(), allowSubs, maxMissing)
+		bands, err := svc.Bands(r.Context(), opts, mins, samples)
 		if err != nil {
-			jsonError(w, "scoring failed: "+err.Error(), http.StatusBadGateway, err)
+			scoringError(w, r, err)
 			return
 		}
-		jsonOK(w, results)
+		jsonOK(w, r, bands)
 	}
 }
 
+// parseScoreOptions reads the GET /matches query params into scoring options.
+// The returned error is safe to show to the client.
+func parseScoreOptions(r *http.Request, svc *service.Service) (service.ScoreOptions, error) {
+	q := r.URL.Query()
+	opts := service.ScoreOptions{
+		AllowSubs:               q.Get("allow_subs") == "true",
+		IncludeUnmakeable:       q.Get("include_unmakeable") == "true",
+		TreatOptionalAsRequired: q.Get("treat_optional_as_required") == "true",
+		UseGroups:               q.Get("use_groups") == "true",
+		ExcludeSubs:             q["exclude_subs"],
+		ExcludeAllergens:        q["exclude_allergens"],
+		Tags:                    q["tags"],
+		TagsAny:                 q.Get("tags_any") == "true",
+		Staples:                 q["staples"],
+		MealPlan:                q.Get("meal_plan") == "true",
+		IncludeMatched:          q.Get("include_matched") == "true",
+		IncludeHave:             q.Get("include_have") == "true",
+		IncludeUsed:             q.Get("include_used") == "true",
+		IncludeNames:            q.Get("include_names") == "true",
+		IncludeSummary:          q.Get("include_summary") == "true",
+		SuggestSubs:             q.Get("suggest_subs") == "true",
+		SubstitutionSummary:     q.Get("substitution_summary") == "true",
+		MaxMissing:              svc.DefaultMaxMissing(),
+		RankSeed:                rankSeed(r, q.Get("seed")),
+		Strategy:                q.Get("strategy"),
+		Sort:                    q.Get("sort"),
+	}
+
+	if err := checkSort(opts.Sort); err != nil {
+		return opts, err
+	}
+
+	if err := checkStrategy(svc, opts.Strategy); err != nil {
+		return opts, err
+	}
+	if q.Get("quantity_check") == "true" {
+		if err := applyQuantityCheck(&opts); err != nil {
+			return opts, err
+		}
+	}
+
+	if s := q.Get("max_missing"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return opts, errors.New("max_missing must be a non-negative integer")
+		}
+		opts.MaxMissing = n
+	}
+
+	if s := q.Get("sub_depth"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > service.MaxSubDepth {
+			return opts, fmt.Errorf("sub_depth must be an integer between 1 and %d", service.MaxSubDepth)
+		}
+		opts.SubDepth = n
+	}
+
+	if s := q.Get("can_make_min_coverage"); s != "" {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || n < 0 || n > 100 {
+			return opts, errors.New("can_make_min_coverage must be a number between 0 and 100")
+		}
+		opts.CanMakeMinCoverage = n
+	}
+
+	if s := q.Get("min_coverage"); s != "" {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || n < 0 || n > 100 {
+			return opts, errors.New("min_coverage must be a number between 0 and 100")
+		}
+		opts.MinCoverage = n
+	}
+
+	if s := q.Get("near_miss_missing"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return opts, errors.New("near_miss_missing must be a non-negative integer")
+		}
+		opts.NearMissMaxMissing = n
+	}
+
+	if s := q.Get("near_miss_coverage"); s != "" {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || n < 0 || n > 100 {
+			return opts, errors.New("near_miss_coverage must be a number between 0 and 100")
+		}
+		opts.NearMissMinCoverage = n
+	}
+
+	if s := q.Get("servings"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return opts, errors.New("servings must be a positive integer")
+		}
+		opts.Servings = n
+	}
+
+	if s := q.Get("max_total_minutes"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return opts, errors.New("max_total_minutes must be a non-negative integer")
+		}
+		opts.MaxTotalMinutes = &n
+	}
+
+	if s := q.Get("max_calories"); s != "" {
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || n <= 0 {
+			return opts, errors.New("max_calories must be a positive number")
+		}
+		opts.MaxCalories = n
+	}
+
+	return opts, nil
+}
+
+// checkStrategy rejects scoring strategies the service has not registered.
+func checkStrategy(svc *service.Service, name string) error {
+	if svc.HasStrategy(name) {
+		return nil
+	}
+	return fmt.Errorf("strategy must be one of: %s", strings.Join(svc.Strategies(), ", "))
+}
+
+// checkSort rejects unknown result orderings. Empty selects the default.
+func checkSort(mode string) error {
+	if mode == "" || slices.Contains(service.SortModes(), mode) {
+		return nil
+	}
+	return fmt.Errorf("sort must be one of: %s", strings.Join(service.SortModes(), ", "))
+}
+
+// applyQuantityCheck selects the quantity strategy for quantity_check=true.
+func applyQuantityCheck(opts *service.ScoreOptions) error {
+	if opts.Strategy != "" && opts.Strategy != service.StrategyQuantity {
+		return errors.New("quantity_check cannot be combined with strategy=" + opts.Strategy)
+	}
+	opts.Strategy = service.StrategyQuantity
+	return nil
+}
+
 type matchQueryRequest struct {
-	Prompt            string `json:"prompt"`
-	PantryConstrained bool   `json:"pantry_constrained"`
-	MaxMissing        int    `json:"max_missing"`
+	Prompt                  string   `json:"prompt"`
+	PantryConstrained       bool     `json:"pantry_constrained"`
+	MaxMissing              *int     `json:"max_missing"`
+	TreatOptionalAsRequired bool     `json:"treat_optional_as_required"`
+	MaxCalories             float64  `json:"max_calories"`
+	MaxTotalMinutes         *int     `json:"max_total_minutes"`
+	UseGroups               bool     `json:"use_groups"`
+	ExcludeSubs             []string `json:"exclude_subs"`
+	ExcludeAllergens        []string `json:"exclude_allergens"`
+	Tags                    []string `json:"tags"`
+	TagsAny                 bool     `json:"tags_any"`
+	Staples                 []string `json:"staples"`
+	MealPlan                bool     `json:"meal_plan"`
+	IncludeMatched          bool     `json:"include_matched"`
+	IncludeHave             bool     `json:"include_have"`
+	IncludeUsed             bool     `json:"include_used"`
+	IncludeNames            bool     `json:"include_names"`
+	IncludeSummary          bool     `json:"include_summary"`
+	SuggestSubs             bool     `json:"suggest_subs"`
+	SubstitutionSummary     bool     `json:"substitution_summary"`
+	NearMissMissing         int      `json:"near_miss_missing"`
+	NearMissCoverage        float64  `json:"near_miss_coverage"`
+	Seed                    string   `json:"seed"`
+	Strategy                string   `json:"strategy"`
+	QuantityCheck           bool     `json:"quantity_check"`
+	Servings                *int     `json:"servings"`
+	Sort                    string   `json:"sort"`
+	IncludeMeta             bool     `json:"include_meta"`
+	// Pantry, when present, is scored instead of the live pantry.
+	Pantry []clients.PantryItem `json:"pantry"`
+}
+
+// checkInlinePantry rejects inline pantry items without an ingredient ID.
+// field names the items in the request body for the error.
+func checkInlinePantry(field string, items []clients.PantryItem) error {
+	for i, item := range items {
+		if strings.TrimSpace(item.IngredientID) == "" {
+			return fmt.Errorf("%s[%d].ingredient_id must not be empty", field, i)
+		}
+	}
+	return nil
+}
+
+// maxMissing returns the requested max_missing clamped to zero, or def
+// when the field was omitted. An explicit 0 is strict.
+func (req matchQueryRequest) maxMissing(def int) int {
+	if req.MaxMissing == nil {
+		return def
+	}
+	return max(*req.MaxMissing, 0)
 }
 
 // handlePostMatchQuery is the primary "what do I cook tonight?" interface.
-// Phase 1: prompt and pantry_constrained are ignored; deterministic scoring only.
+// Deterministic scoring builds the candidate set; prompt keywords re-rank it.
+// An empty or whitespace prompt means "no prompt". pantry_constrained returns
+// only fully makeable recipes and overrides max_missing. An inline pantry is
+// scored without fetching the live one.
 func handlePostMatchQuery(svc *service.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req matchQueryRequest
@@ -73,28 +669,456 @@ func handlePostMatchQuery(svc *service.Service) http.HandlerFunc {
 			return
 		}
 
-		maxMissing := max(req.MaxMissing, 0)
+		opts := service.ScoreOptions{
+			Prompt:                  req.Prompt,
+			PantryConstrained:       req.PantryConstrained,
+			MaxMissing:              req.maxMissing(svc.DefaultMaxMissing()),
+			TreatOptionalAsRequired: req.TreatOptionalAsRequired,
+			MaxCalories:             req.MaxCalories,
+			MaxTotalMinutes:         req.MaxTotalMinutes,
+			UseGroups:               req.UseGroups,
+			ExcludeSubs:             req.ExcludeSubs,
+			ExcludeAllergens:        req.ExcludeAllergens,
+			Tags:                    req.Tags,
+			TagsAny:                 req.TagsAny,
+			Staples:                 req.Staples,
+			MealPlan:                req.MealPlan,
+			IncludeMatched:          req.IncludeMatched,
+			IncludeHave:             req.IncludeHave,
+			IncludeUsed:             req.IncludeUsed,
+			IncludeNames:            req.IncludeNames,
+			IncludeSummary:          req.IncludeSummary,
+			SuggestSubs:             req.SuggestSubs,
+			SubstitutionSummary:     req.SubstitutionSummary,
+			NearMissMaxMissing:      max(req.NearMissMissing, 0),
+			NearMissMinCoverage:     min(max(req.NearMissCoverage, 0), 100),
+			RankSeed:                rankSeed(r, req.Seed),
+			Strategy:                req.Strategy,
+			Sort:                    req.Sort,
+			Pantry:                  req.Pantry,
+		}
+		if err := checkInlinePantry("pantry", req.Pantry); err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.MaxTotalMinutes != nil && *req.MaxTotalMinutes < 0 {
+			jsonError(w, "max_total_minutes must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		if req.Servings != nil {
+			if *req.Servings < 1 {
+				jsonError(w, "servings must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			opts.Servings = *req.Servings
+		}
+		if err := checkStrategy(svc, opts.Strategy); err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := checkSort(opts.Sort); err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.QuantityCheck {
+			if err := applyQuantityCheck(&opts); err != nil {
+				jsonError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		res, err := svc.Score(r.Context(), opts)
+		if err != nil {
+			scoringError(w, r, err)
+			return
+		}
+		writeMatches(w, r, res, opts, page{}, req.IncludeMeta)
+	}
+}
+
+type diffRequest struct {
+	Recipes []clients.Recipe `json:"recipes"`
+}
+
+// handlePostDiff reports recipes whose makeability differs between the live
+// catalog and the alternate catalog in the body. Accepts the same scoring
+// query params as GET /matches.
+func handlePostDiff(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseScoreOptions(r, svc)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req diffRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		diff, err := svc.DiffCatalog(r.Context(), opts, req.Recipes)
+		if err != nil {
+			scoringError(w, r, err)
+			return
+		}
+		setWarnings(w, diff.Warnings)
+		jsonOK(w, r, diff)
+	}
+}
+
+// maxBatchPantries bounds how many pantries one POST /matches/batch scores.
+const maxBatchPantries = 20
+
+type batchRequest struct {
+	Pantries []batchPantry `json:"pantries"`
+}
+
+// batchPantry is one inline pantry snapshot, in the Pantry Service's shape
+// plus an optional caller-chosen ID echoed in the response.
+type batchPantry struct {
+	ID    string               `json:"id"`
+	Items []clients.PantryItem `json:"items"`
+}
+
+type batchResponse struct {
+	Pantries []batchPantryResult `json:"pantries"`
+}
+
+type batchPantryResult struct {
+	ID string `json:"id,omitempty"`
+	matchesResponse
+}
+
+// handlePostBatch scores the live recipe catalog against each pantry in the
+// body instead of the live pantry, e.g. to compare store locations, fetching
+// the catalog once. Accepts the same scoring query params as GET /matches,
+// applied to every pantry, plus include_meta. Warnings from all pantries are
+// reported once each.
+func handlePostBatch(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseScoreOptions(r, svc)
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req batchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.Pantries) == 0 || len(req.Pantries) > maxBatchPantries {
+			jsonError(w, fmt.Sprintf("pantries must list between 1 and %d pantries", maxBatchPantries),
+				http.StatusBadRequest)
+			return
+		}
+
+		pantries := make([][]clients.PantryItem, len(req.Pantries))
+		for i, p := range req.Pantries {
+			if err := checkInlinePantry(fmt.Sprintf("pantries[%d].items", i), p.Items); err != nil {
+				jsonError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			pantries[i] = p.Items
+		}
+		results, err := svc.ScoreBatch(r.Context(), pantries, opts)
+		if err != nil {
+			scoringError(w, r, err)
+			return
+		}
 
-		results, err := svc.Score(r.Context(), false, maxMissing)
+		meta := r.URL.Query().Get("include_meta") == "true"
+		resp := batchResponse{Pantries: make([]batchPantryResult, len(results))}
+		var warnings []string
+		for i, res := range results {
+			resp.Pantries[i] = batchPantryResult{
+				ID: req.Pantries[i].ID,
+				matchesResponse: matchesResponse{
+					Results:             res.Results,
+					SubstitutionSummary: res.SubstitutionSummary,
+					RemainingPantry:     res.RemainingPantry,
+				},
+			}
+			if meta {
+				resp.Pantries[i].Meta = &res.Meta
+			}
+			for _, msg := range res.Warnings {
+				if !slices.Contains(warnings, msg) {
+					warnings = append(warnings, msg)
+				}
+			}
+		}
+		setWarnings(w, warnings)
+		jsonOK(w, r, resp)
+	}
+}
+
+type mealRequest struct {
+	RecipeIDs []string `json:"recipe_ids"`
+}
+
+// handlePostMeal checks whether a set of recipes can be cooked together,
+// with the recipes competing for the same pantry quantities.
+func handlePostMeal(svc *service.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req mealRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.RecipeIDs) == 0 {
+			jsonError(w, "recipe_ids is required", http.StatusBadRequest)
+			return
+		}
+
+		result, err := svc.ScoreMeal(r.Context(), req.RecipeIDs)
+		if errors.Is(err, service.ErrRecipeNotFound) {
+			jsonError(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		if err != nil {
-			jsonError(w, "scoring failed: "+err.Error(), http.StatusBadGateway, err)
+			scoringError(w, r, err)
+			return
+		}
+		jsonOK(w, r, result)
+	}
+}
+
+// rankSeed returns the explicit seed if set, otherwise the per-user
+// X-Rank-Seed header. An empty result leaves ranking jitter off.
+func rankSeed(r *http.Request, explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return r.Header.Get("X-Rank-Seed")
+}
+
+// matchesResponse is the object form of a match listing, returned instead of
+// a bare array when the request asks for result-set level data.
+type matchesResponse struct {
+	Total               *int                         `json:"total,omitempty"`
+	Results             []service.MatchResult        `json:"results"`
+	SubstitutionSummary []service.SubstitutionUnlock `json:"substitution_summary,omitempty"`
+	RemainingPantry     []service.PantryRemainder    `json:"remaining_pantry,omitempty"`
+	Meta                *service.ScoreMeta           `json:"meta,omitempty"`
+}
+
+// page is a requested window of results. The zero value returns every result
+// in the bare-array form.
+type page struct {
+	enabled bool
+	limit   int // zero means no limit
+	offset  int
+}
+
+// parsePage reads the paginated, limit, and offset query params. limit and
+// offset are only accepted with paginated=true so existing consumers keep the
+// bare-array response.
+func parsePage(q url.Values) (page, error) {
+	pg := page{enabled: q.Get("paginated") == "true"}
+	for _, param := range []struct {
+		name string
+		dst  *int
+	}{{"limit", &pg.limit}, {"offset", &pg.offset}} {
+		s := q.Get(param.name)
+		if s == "" {
+			continue
+		}
+		if !pg.enabled {
+			return pg, fmt.Errorf("%s requires paginated=true", param.name)
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return pg, fmt.Errorf("%s must be a non-negative integer", param.name)
+		}
+		*param.dst = n
+	}
+	return pg, nil
+}
+
+// apply returns the window of results the page selects.
+func (pg page) apply(results []service.MatchResult) []service.MatchResult {
+	start := min(pg.offset, len(results))
+	end := len(results)
+	if pg.limit > 0 {
+		end = min(start+pg.limit, end)
+	}
+	if start == end {
+		return []service.MatchResult{}
+	}
+	return results[start:end]
+}
+
+// writeMatches writes scoring results with their warnings. The response is a
+// bare array unless opts, pg or meta request data only the object form can
+// carry; a paginated response reports the unpaginated total.
+func writeMatches(
+	w http.ResponseWriter,
+	r *http.Request,
+	res *service.ScoreResult,
+	opts service.ScoreOptions,
+	pg page,
+	meta bool,
+) {
+	setWarnings(w, res.Warnings)
+	if !opts.SubstitutionSummary && !opts.MealPlan && !pg.enabled && !meta {
+		jsonOK(w, r, res.Results)
+		return
+	}
+	resp := matchesResponse{
+		Results:             res.Results,
+		SubstitutionSummary: res.SubstitutionSummary,
+		RemainingPantry:     res.RemainingPantry,
+	}
+	if meta {
+		resp.Meta = &res.Meta
+	}
+	if pg.enabled {
+		total := len(res.Results)
+		resp.Total = &total
+		resp.Results = pg.apply(res.Results)
+	}
+	jsonOK(w, r, resp)
+}
+
+// matchesETag returns a weak ETag for a GET /matches response: a hash of the
+// scored inputs, the results and warnings, the query params, and the request
+// headers that change the response. The results carry what was looked up in
+// the dictionary (names, substitutes, allergens), which the inputs hash does
+// not cover, so a response degraded by a dictionary outage never validates a
+// full one. It returns "" when the inputs could not be hashed.
+func matchesETag(res *service.ScoreResult, r *http.Request) string {
+	if res.InputsHash == "" {
+		return ""
+	}
+	h := fnv.New64a()
+	for _, part := range []string{
+		res.InputsHash,
+		r.URL.Query().Encode(),
+		r.Header.Get("X-Rank-Seed"),
+		strconv.FormatBool(acceptsNDJSON(r)),
+		strings.Join(res.Warnings, "\x00"),
+	} {
+		h.Write([]byte(part + "\x00")) //nolint:errcheck
+	}
+	if err := json.NewEncoder(h).Encode(res.Results); err != nil {
+		return ""
+	}
+	return `W/"` + strconv.FormatUint(h.Sum64(), 16) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value lists etag or
+// is "*". Comparison is weak, as RFC 9110 requires for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ndjsonContentType is the media type of newline-delimited JSON streams.
+const ndjsonContentType = "application/x-ndjson"
+
+// acceptsNDJSON reports whether the request's Accept header asks for
+// newline-delimited JSON.
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for mediaType := range strings.SplitSeq(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), ndjsonContentType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// streamMatches writes results as newline-delimited JSON, one MatchResult
+// per line, flushing after each so clients can start rendering before the
+// whole set is written. Scoring has already finished and sorted the results;
+// only the write is incremental. Summary and meal-plan data have no place in
+// the stream and are omitted.
+func streamMatches(w http.ResponseWriter, res *service.ScoreResult, pg page) {
+	setWarnings(w, res.Warnings)
+	w.Header().Set("Content-Type", ndjsonContentType)
+	results := res.Results
+	if pg.enabled {
+		results = pg.apply(results)
+	}
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
 			return
 		}
-		jsonOK(w, results)
+		rc.Flush() //nolint:errcheck
+	}
+}
+
+// setWarnings surfaces non-fatal scoring warnings as HTTP Warning headers
+// (code 199, miscellaneous warning).
+func setWarnings(w http.ResponseWriter, warnings []string) {
+	for _, msg := range warnings {
+		w.Header().Add("Warning", `199 - "`+msg+`"`)
 	}
 }
 
-func jsonOK(w http.ResponseWriter, v any) {
+// jsonOK writes v as a JSON response: compact, or indented for reading in a
+// browser when the request asks for pretty=true.
+func jsonOK(w http.ResponseWriter, r *http.Request, v any) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(v) //nolint:errcheck
+	enc := json.NewEncoder(w)
+	if r.URL.Query().Get("pretty") == "true" {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(v) //nolint:errcheck
 }
 
 func jsonError(w http.ResponseWriter, msg string, status int, errs ...error) {
+	writeError(w, map[string]string{"error": msg}, status, errs...)
+}
+
+// Error codes reported with 502 responses, naming the upstream that failed,
+// and with 504 responses when the request deadline passed.
+const (
+	codePantryUnavailable  = "pantry_unavailable"
+	codeRecipesUnavailable = "recipes_unavailable"
+	codeScoringFailed      = "scoring_failed"
+	codeTimeout            = "timeout"
+)
+
+// scoringError writes a 502 for a failed scoring call. The body's code names
+// the upstream that failed so monitoring can tell them apart. A call that
+// failed because the request's own deadline passed gets a 504 instead; an
+// upstream client timing out within it is still that upstream's failure.
+func scoringError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		body := map[string]string{"error": "scoring timed out: " + err.Error(), "code": codeTimeout}
+		writeError(w, body, http.StatusGatewayTimeout, err)
+		return
+	}
+	code := codeScoringFailed
+	switch {
+	case errors.Is(err, service.ErrPantryUnavailable):
+		code = codePantryUnavailable
+	case errors.Is(err, service.ErrRecipesUnavailable):
+		code = codeRecipesUnavailable
+	}
+	body := map[string]string{"error": "scoring failed: " + err.Error(), "code": code}
+	writeError(w, body, http.StatusBadGateway, err)
+}
+
+func writeError(w http.ResponseWriter, body map[string]string, status int, errs ...error) {
 	if status >= 500 && len(errs) > 0 {
-		logger := slog.Default()
-		logger.Error(msg, "status", status, "error", errs[0])
+		args := []any{"status", status, "error", errs[0]}
+		if code, ok := body["code"]; ok {
+			args = append(args, "code", code)
+		}
+		slog.Default().Error(body["error"], args...)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{"error": msg}) //nolint:errcheck
+	json.NewEncoder(w).Encode(body) //nolint:errcheck
 }
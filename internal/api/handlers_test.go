@@ -1,16 +1,27 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/mwhite7112/woodpantry-matching/internal/clients"
 	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
@@ -20,13 +31,20 @@ import (
 func setupRouter(
 	t *testing.T,
 ) (http.Handler, *mocks.MockPantryFetcher, *mocks.MockRecipeFetcher) {
+	router, pantryMock, recipeMock, _ := setupRouterWithDictionary(t)
+	return router, pantryMock, recipeMock
+}
+
+func setupRouterWithDictionary(
+	t *testing.T,
+) (http.Handler, *mocks.MockPantryFetcher, *mocks.MockRecipeFetcher, *mocks.MockDictionaryFetcher) {
 	pantryMock := mocks.NewMockPantryFetcher(t)
 	recipeMock := mocks.NewMockRecipeFetcher(t)
 	dictMock := mocks.NewMockDictionaryFetcher(t)
 
 	svc := service.New(pantryMock, recipeMock, dictMock)
 	router := NewRouter(svc)
-	return router, pantryMock, recipeMock
+	return router, pantryMock, recipeMock, dictMock
 }
 
 func TestHealthz(t *testing.T) {
@@ -40,6 +58,189 @@ func TestHealthz(t *testing.T) {
 	assert.Equal(t, "ok", rec.Body.String())
 }
 
+// fakePinger is a readiness check returning err.
+type fakePinger struct{ err error }
+
+func (p fakePinger) Ping(context.Context) error { return p.err }
+
+func TestReadyz(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		checks     map[string]Pinger
+		wantStatus int
+		want       readyResponse
+	}{
+		{
+			name:       "no checks",
+			wantStatus: http.StatusOK,
+			want:       readyResponse{Status: "ok", Dependencies: map[string]string{}},
+		},
+		{
+			name: "healthy",
+			checks: map[string]Pinger{
+				"pantry":     fakePinger{},
+				"recipes":    fakePinger{},
+				"dictionary": fakePinger{},
+			},
+			wantStatus: http.StatusOK,
+			want: readyResponse{Status: "ok", Dependencies: map[string]string{
+				"pantry": "ok", "recipes": "ok", "dictionary": "ok",
+			}},
+		},
+		{
+			name: "unhealthy",
+			checks: map[string]Pinger{
+				"pantry":     fakePinger{},
+				"recipes":    fakePinger{err: errors.New("health check returned 503")},
+				"dictionary": fakePinger{},
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			want: readyResponse{Status: "unavailable", Dependencies: map[string]string{
+				"pantry": "ok", "recipes": "health check returned 503", "dictionary": "ok",
+			}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := service.New(
+				mocks.NewMockPantryFetcher(t), mocks.NewMockRecipeFetcher(t), mocks.NewMockDictionaryFetcher(t))
+			router := NewRouter(svc, WithReadinessChecks(tc.checks))
+
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			var got readyResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+			assert.Equal(t, tc.want, got)
+
+			// Liveness never depends on upstreams.
+			req = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+			rec = httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
+
+func TestForwardsCorrelationHeaders(t *testing.T) {
+	received := make(chan http.Header, 1)
+	pantry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.Write([]byte(`{"items":[]}`)) //nolint:errcheck
+	}))
+	defer pantry.Close()
+
+	for _, tc := range []struct {
+		name      string
+		requestID string
+		wantID    func(string) bool
+	}{
+		{name: "incoming id", requestID: "gw-42", wantID: func(id string) bool { return id == "gw-42" }},
+		{name: "generated id", wantID: func(id string) bool { return id != "" }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil)
+			svc := service.New(clients.NewPantryClient(pantry.URL), recipeMock, mocks.NewMockDictionaryFetcher(t))
+			router := NewRouter(svc)
+
+			req := httptest.NewRequest(http.MethodGet, "/matches", nil)
+			req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			if tc.requestID != "" {
+				req.Header.Set("X-Request-Id", tc.requestID)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			got := <-received
+			assert.True(t, tc.wantID(got.Get("X-Request-Id")), got.Get("X-Request-Id"))
+			assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", got.Get("Traceparent"))
+		})
+	}
+}
+
+func TestGetMatches_Spans(t *testing.T) {
+	const incoming = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	upstreamParents := make(chan string, 16)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamParents <- r.Header.Get("Traceparent")
+		switch r.URL.Path {
+		case "/pantry":
+			w.Write([]byte(`{"items":[{"id":"p1","ingredient_id":"ing1","quantity":1}]}`)) //nolint:errcheck
+		case "/recipes":
+			w.Write([]byte(`[{"id":"r1","title":"Toast","ingredients":[` + //nolint:errcheck
+				`{"id":"ri1","ingredient_id":"ing1"},{"id":"ri2","ingredient_id":"ing2"}]}]`))
+		default:
+			w.Write([]byte(`{"ID":"ing","Name":"thing"}`)) //nolint:errcheck
+		}
+	}))
+	defer upstream.Close()
+
+	rec := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	svc := service.New(
+		clients.NewPantryClient(upstream.URL),
+		clients.NewRecipeClient(upstream.URL),
+		clients.NewDictionaryClient(upstream.URL),
+	)
+	req := httptest.NewRequest(http.MethodGet, "/matches?max_missing=1", nil)
+	req.Header.Set("Traceparent", incoming)
+	w := httptest.NewRecorder()
+	NewRouter(svc).ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	ended := rec.Ended()
+	names := make(map[trace.SpanID]string, len(ended))
+	for _, span := range ended {
+		names[span.SpanContext().SpanID()] = span.Name()
+	}
+	parents := make(map[string][]string)
+	httpSpans := make(map[string]bool)
+	for _, span := range ended {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String(), span.Name())
+		parent := names[span.Parent().SpanID()]
+		if span.Parent().IsRemote() {
+			parent = "remote:" + span.Parent().SpanID().String()
+		}
+		parents[span.Name()] = append(parents[span.Name()], parent)
+
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes() {
+			attrs[kv.Key] = kv.Value
+		}
+		switch span.Name() {
+		case "matching.Score":
+			assert.Equal(t, int64(1), attrs["matching.recipes"].AsInt64())
+			assert.Equal(t, int64(1), attrs["matching.missing"].AsInt64())
+		case "HTTP GET":
+			assert.Equal(t, strings.TrimPrefix(upstream.URL, "http://"), attrs["server.address"].AsString())
+			httpSpans[span.SpanContext().SpanID().String()] = true
+		}
+	}
+	assert.Equal(t, []string{"remote:00f067aa0ba902b7"}, parents["matching.Score"])
+	assert.Contains(t, parents["HTTP GET"], "matching.Score")
+	assert.Contains(t, parents["HTTP GET"], "dictionary.fanout")
+	assert.Contains(t, parents["dictionary.fanout"], "matching.Score")
+
+	// Upstreams see the client span as their parent, not the incoming one.
+	close(upstreamParents)
+	for got := range upstreamParents {
+		parts := strings.Split(got, "-")
+		require.Len(t, parts, 4, got)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", parts[1])
+		assert.True(t, httpSpans[parts[2]], got)
+	}
+}
+
 func TestGetMatches_Success(t *testing.T) {
 	router, pantryMock, recipeMock := setupRouter(t)
 
@@ -68,76 +269,1695 @@ func TestGetMatches_Success(t *testing.T) {
 	assert.InDelta(t, 100.0, results[0].CoveragePct, 0.0001)
 }
 
-func TestGetMatches_InvalidMaxMissing(t *testing.T) {
-	router, _, _ := setupRouter(t)
+func TestGetMatches_NDJSON(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
 
-	req := httptest.NewRequest(http.MethodGet, "/matches?max_missing=abc", nil)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "ing1"},
+		{ID: "p2", IngredientID: "ing2"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}}},
+		{ID: "r2", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing2"}}},
+		{ID: "r3", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}, {IngredientID: "ing2"}}},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches", nil)
+	req.Header.Set("Accept", "application/json;q=0.5, application/x-ndjson")
 	rec := httptest.NewRecorder()
 	router.ServeHTTP(rec, req)
 
-	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.Equal(t, []string{"Accept", "X-Rank-Seed"}, rec.Header().Values("Vary"))
+	assert.True(t, rec.Flushed)
+
+	lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+	var ids []string
+	for _, line := range lines {
+		var result service.MatchResult
+		require.NoError(t, json.Unmarshal([]byte(line), &result), line)
+		ids = append(ids, result.Recipe.ID)
+	}
+	assert.Equal(t, []string{"r1", "r2", "r3"}, ids)
 }
 
-func TestGetMatches_NegativeMaxMissing(t *testing.T) {
+func TestGetMatches_IncludeMeta(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		pantry  []clients.PantryItem
+		recipes []clients.Recipe
+		want    service.ScoreMeta
+	}{
+		{
+			name:   "no recipes",
+			pantry: []clients.PantryItem{{ID: "p1", IngredientID: "ing1", Quantity: 1}},
+			want:   service.ScoreMeta{RecipesConsidered: 0, PantryItems: 1},
+		},
+		{
+			name:    "no pantry items",
+			recipes: []clients.Recipe{{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}}}},
+			want:    service.ScoreMeta{RecipesConsidered: 1, RecipesScored: 1, PantryItems: 0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return(tc.pantry, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(tc.recipes, nil)
+			dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(nil, clients.ErrIngredientNotFound).Maybe()
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/matches?include_meta=true", nil))
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			var resp matchesResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Empty(t, resp.Results)
+			require.NotNil(t, resp.Meta)
+			assert.Equal(t, tc.want, *resp.Meta)
+		})
+	}
+}
+
+func TestPostMatchQuery_IncludeMeta(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/matches/query",
+		strings.NewReader(`{"include_meta":true}`)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"results":[],"meta":{"recipes_considered":0,"recipes_scored":0,"pantry_items":0}}`, rec.Body.String())
+}
+
+func TestGetMatches_Pretty(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "eggs", Quantity: 6}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "omelette", Title: "Omelette", Ingredients: []clients.RecipeIngredient{{IngredientID: "eggs"}}},
+	}, nil)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/matches"+query, nil))
+		require.Equal(t, http.StatusOK, rec.Code, query)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"), query)
+		return rec
+	}
+	compact := get("").Body.Bytes()
+	pretty := get("?pretty=true").Body.Bytes()
+
+	assert.Equal(t, 1, bytes.Count(compact, []byte("\n")))
+	var indented bytes.Buffer
+	require.NoError(t, json.Indent(&indented, compact, "", "  "))
+	assert.Equal(t, indented.String(), string(pretty))
+}
+
+func TestPostMatchQuery_InlinePantry(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "omelette", Ingredients: []clients.RecipeIngredient{{IngredientID: "eggs"}}},
+		{ID: "toast", Ingredients: []clients.RecipeIngredient{{IngredientID: "bread"}}},
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/matches/query",
+		strings.NewReader(`{"pantry":[{"ingredient_id":"eggs","quantity":6}]}`)))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "omelette", results[0].Recipe.ID)
+	pantryMock.AssertNotCalled(t, "GetPantry", mock.Anything)
+}
+
+func TestPostMatchQuery_InvalidInlinePantry(t *testing.T) {
 	router, _, _ := setupRouter(t)
 
-	req := httptest.NewRequest(http.MethodGet, "/matches?max_missing=-1", nil)
 	rec := httptest.NewRecorder()
-	router.ServeHTTP(rec, req)
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/matches/query",
+		strings.NewReader(`{"pantry":[{"ingredient_id":"eggs","quantity":6},{"ingredient_id":" ","quantity":1}]}`)))
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error":"pantry[1].ingredient_id must not be empty"}`, rec.Body.String())
 }
 
-func TestGetMatches_BackendError(t *testing.T) {
-	router, pantryMock, _ := setupRouter(t)
+func TestGetMatches_ETag(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
 
-	pantryMock.EXPECT().GetPantry(mock.Anything).Return(nil, errors.New("down"))
+	pantry := []clients.PantryItem{{ID: "p1", IngredientID: "ing1", Quantity: 1}}
+	pantryMock.EXPECT().GetPantry(mock.Anything).RunAndReturn(
+		func(context.Context) ([]clients.PantryItem, error) { return pantry, nil })
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}}},
+	}, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/matches", nil)
+	get := func(target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("/matches", "")
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, []string{"Accept", "X-Rank-Seed"}, first.Header().Values("Vary"))
+	etag := first.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `W/"`), etag)
+
+	again := get("/matches", etag)
+	assert.Equal(t, http.StatusNotModified, again.Code)
+	assert.Empty(t, again.Body.String())
+	assert.Equal(t, etag, again.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusNotModified, get("/matches", `"other", `+etag).Code)
+	assert.Equal(t, http.StatusNotModified, get("/matches", "*").Code)
+
+	// Other query params, or a changed pantry, produce a new ETag.
+	other := get("/matches?max_missing=1", etag)
+	require.Equal(t, http.StatusOK, other.Code)
+	assert.NotEqual(t, etag, other.Header().Get("ETag"))
+
+	pantry = append(pantry, clients.PantryItem{ID: "p2", IngredientID: "ing2", Quantity: 1})
+	changed := get("/matches", etag)
+	require.Equal(t, http.StatusOK, changed.Code)
+	assert.NotEqual(t, etag, changed.Header().Get("ETag"))
+}
+
+func TestGetMatches_ETagCoversDictionaryData(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{ID: "p1", IngredientID: "ing1"}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}, {IngredientID: "ing2"}}},
+	}, nil)
+	// The dictionary is down for the first request and back for the second.
+	dictMock.EXPECT().GetIngredient(mock.Anything, "ing2").Return(nil, errors.New("dictionary down")).Once()
+	dictMock.EXPECT().GetIngredient(mock.Anything, "ing2").Return(&clients.IngredientDetail{ID: "ing2", Name: "basil"}, nil)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/matches?max_missing=1", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	degraded := get("")
+	require.Equal(t, http.StatusOK, degraded.Code)
+	etag := degraded.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	full := get(etag)
+	require.Equal(t, http.StatusOK, full.Code)
+	assert.NotEqual(t, etag, full.Header().Get("ETag"))
+	assert.Contains(t, full.Body.String(), `"basil"`)
+}
+
+func TestGetMatches_IncludeMatched(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "ing1"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{
+			ID:    "r1",
+			Title: "Simple",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri1", IngredientID: "ing1", IsOptional: false},
+			},
+		},
+	}, nil)
+
+	dictMock.EXPECT().GetIngredient(mock.Anything, "ing1").Return(&clients.IngredientDetail{ID: "ing1", Name: "garlic"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?include_matched=true", nil)
 	rec := httptest.NewRecorder()
 	router.ServeHTTP(rec, req)
 
-	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	require.Len(t, results[0].MatchedIngredients, 1)
+	assert.Equal(t, "ing1", results[0].MatchedIngredients[0].IngredientID)
+	assert.Equal(t, "garlic", results[0].MatchedIngredients[0].Name)
+	assert.Equal(t, service.MatchSourceDirect, results[0].MatchedIngredients[0].Source)
 }
 
-func TestPostMatchQuery_Success(t *testing.T) {
+func TestGetMatches_IncludeUsed(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		query    string
+		wantUsed bool
+	}{
+		{name: "included", query: "/matches?include_used=true", wantUsed: true},
+		{name: "omitted", query: "/matches"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			router, pantryMock, recipeMock := setupRouter(t)
+
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+				{ID: "p1", IngredientID: "ing1", Quantity: 500, Unit: "g"},
+			}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+				{
+					ID:    "r1",
+					Title: "Simple",
+					Ingredients: []clients.RecipeIngredient{
+						{ID: "ri1", IngredientID: "ing1", Quantity: 200, Unit: "g"},
+					},
+				},
+			}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, tc.query, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var raw []map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &raw))
+			require.Len(t, raw, 1)
+			_, hasUsed := raw[0]["used_ingredients"]
+			assert.Equal(t, tc.wantUsed, hasUsed)
+
+			var results []service.MatchResult
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+			if tc.wantUsed {
+				assert.Equal(t, []service.UsedIngredient{
+					{IngredientID: "ing1", Quantity: 200, Unit: "g"},
+				}, results[0].UsedIngredients)
+			}
+		})
+	}
+}
+
+func TestGetMatches_SuggestSubs(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "oil"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{
+			ID:    "r1",
+			Title: "Shortbread",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri1", IngredientID: "butter", IsOptional: false},
+			},
+		},
+	}, nil)
+
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 0.75},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "butter").Return(&clients.IngredientDetail{ID: "butter", Name: "butter"}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "oil").Return(&clients.IngredientDetail{ID: "oil", Name: "vegetable oil"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?suggest_subs=true&include_unmakeable=true", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.False(t, results[0].CanMake)
+	require.Len(t, results[0].MissingIngredients, 1)
+	require.Len(t, results[0].MissingIngredients[0].Suggestions, 1)
+	assert.Equal(t, "vegetable oil", results[0].MissingIngredients[0].Suggestions[0].Name)
+	assert.True(t, results[0].MissingIngredients[0].Suggestions[0].InPantry)
+}
+
+func TestGetMatches_SubstitutionSummary(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "oil"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "butter"}}},
+		{ID: "r2", Ingredients: []clients.RecipeIngredient{{IngredientID: "butter"}}},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 1},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "butter").Return(&clients.IngredientDetail{ID: "butter", Name: "butter"}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "oil").Return(&clients.IngredientDetail{ID: "oil", Name: "oil"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?allow_subs=true&substitution_summary=true", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp matchesResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Len(t, resp.Results, 2)
+	require.Len(t, resp.SubstitutionSummary, 1)
+	assert.Equal(t, "butter", resp.SubstitutionSummary[0].Name)
+	assert.Equal(t, 2, resp.SubstitutionSummary[0].RecipeCount)
+	assert.Equal(t, []string{"oil"}, resp.SubstitutionSummary[0].SubstituteIDs)
+}
+
+func TestGetMatches_SeedHeaderMatchesQueryParam(t *testing.T) {
 	router, pantryMock, recipeMock := setupRouter(t)
 
+	recipes := make([]clients.Recipe, 0, 6)
+	for _, id := range []string{"r1", "r2", "r3", "r4", "r5", "r6"} {
+		recipes = append(recipes, clients.Recipe{ID: id, Title: id})
+	}
 	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
-	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
 
-	body := `{"prompt":"something quick","max_missing":0}`
-	req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+	order := func(req *http.Request) []string {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var results []service.MatchResult
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+		ids := make([]string, 0, len(results))
+		for _, r := range results {
+			ids = append(ids, r.Recipe.ID)
+		}
+		return ids
+	}
+
+	byParam := order(httptest.NewRequest(http.MethodGet, "/matches?seed=user-42", nil))
+	headerReq := httptest.NewRequest(http.MethodGet, "/matches", nil)
+	headerReq.Header.Set("X-Rank-Seed", "user-42")
+	byHeader := order(headerReq)
+
+	assert.Equal(t, byParam, byHeader)
+	assert.ElementsMatch(t, []string{"r1", "r2", "r3", "r4", "r5", "r6"}, byParam)
+}
+
+func TestGetMatches_CacheControl(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []RouterOption
+		want string
+	}{
+		{name: "default", want: "no-cache"},
+		{name: "zero", opts: []RouterOption{WithCacheMaxAge(0)}, want: "no-cache"},
+		{name: "max age", opts: []RouterOption{WithCacheMaxAge(30 * time.Second)}, want: "public, max-age=30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			dictMock := mocks.NewMockDictionaryFetcher(t)
+			router := NewRouter(service.New(pantryMock, recipeMock, dictMock), tt.opts...)
+
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/matches", nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.want, rec.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestGetMatches_Strategy(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     []string
+	}{
+		{strategy: "", want: []string{"r1"}},
+		{strategy: "presence", want: []string{"r1"}},
+		{strategy: "quantity", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			router, pantryMock, recipeMock := setupRouter(t)
+
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+				{ID: "p1", IngredientID: "ing1", Quantity: 1, Unit: "cup"},
+			}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+				{ID: "r1", Ingredients: []clients.RecipeIngredient{
+					{ID: "ri1", IngredientID: "ing1", Quantity: 2, Unit: "cup"},
+				}},
+			}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/matches?strategy="+tt.strategy, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var results []service.MatchResult
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+			ids := make([]string, 0, len(results))
+			for _, r := range results {
+				ids = append(ids, r.Recipe.ID)
+			}
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
+
+func TestGetMatches_QuantityCheck(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "flour", Quantity: 10, Unit: "g"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "flour", Quantity: 500, Unit: "g"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "flour").
+		Return(&clients.IngredientDetail{ID: "flour", Name: "flour"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?quantity_check=true&include_unmakeable=true", nil)
 	rec := httptest.NewRecorder()
 	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
 
-	assert.Equal(t, http.StatusOK, rec.Code)
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.False(t, results[0].CanMake)
+	require.Len(t, results[0].MissingIngredients, 1)
+	assert.InDelta(t, 490.0, results[0].MissingIngredients[0].Shortfall, 0.0001)
 }
 
-func TestPostMatchQuery_InvalidBody(t *testing.T) {
+func TestGetMatches_QuantityCheckConflictsWithStrategy(t *testing.T) {
 	router, _, _ := setupRouter(t)
 
-	req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(`{bad`))
-	req.Header.Set("Content-Type", "application/json")
+	req := httptest.NewRequest(http.MethodGet, "/matches?quantity_check=true&strategy=presence", nil)
 	rec := httptest.NewRecorder()
 	router.ServeHTTP(rec, req)
 
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
 
-func TestPostMatchQuery_NegativeMaxMissing(t *testing.T) {
+func TestGetMatches_UnknownSort(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?sort=random", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "sort must be one of: coverage, use_most, time, missing")
+}
+
+func TestGetMatches_UnknownStrategy(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?strategy=magic", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "presence, quantity")
+}
+
+func TestGetMatches_TreatOptionalAsRequired(t *testing.T) {
 	router, pantryMock, recipeMock := setupRouter(t)
 
-	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
-	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "ing1"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{
+			ID: "r1",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri1", IngredientID: "ing1"},
+				{ID: "ri2", IngredientID: "ing2", IsOptional: true},
+			},
+		},
+	}, nil)
 
-	body := `{"max_missing":-5}`
-	req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+	req := httptest.NewRequest(http.MethodGet, "/matches?treat_optional_as_required=true", nil)
 	rec := httptest.NewRecorder()
 	router.ServeHTTP(rec, req)
 
-	// Negative max_missing is clamped to 0, not an error
 	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	assert.Empty(t, results, "optional ingredient is now blocking")
+}
+
+func TestGetMatches_InvalidMaxMissing(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?max_missing=abc", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetMatches_NegativeMaxMissing(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?max_missing=-1", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetMatches_InvalidMaxCalories(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	for _, q := range []string{"max_calories=abc", "max_calories=0", "max_calories=-100"} {
+		req := httptest.NewRequest(http.MethodGet, "/matches?"+q, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
+
+func TestGetMatches_MaxCalories(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Title: "Salad", Nutrition: &clients.Nutrition{Calories: 350}},
+		{ID: "r2", Title: "Lasagne", Nutrition: &clients.Nutrition{Calories: 900}},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?max_calories=500", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "Salad", results[0].Recipe.Title)
+	require.NotNil(t, results[0].Recipe.Nutrition)
+	assert.InDelta(t, 350.0, results[0].Recipe.Nutrition.Calories, 0.0001)
+}
+
+func TestGetMatches_Tags(t *testing.T) {
+	recipes := []clients.Recipe{
+		{ID: "r1", Title: "Veg Curry", Tags: []string{"vegetarian"}},
+		{ID: "r2", Title: "Quick Salad", Tags: []string{"vegetarian", "quick"}},
+		{ID: "r3", Title: "Steak", Tags: []string{"quick"}},
+	}
+
+	for name, tc := range map[string]struct {
+		query string
+		want  []string
+	}{
+		"and": {query: "tags=vegetarian&tags=quick", want: []string{"r2"}},
+		"or":  {query: "tags=vegetarian&tags=quick&tags_any=true", want: []string{"r1", "r2", "r3"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			router, pantryMock, recipeMock := setupRouter(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/matches?"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			var results []service.MatchResult
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+			ids := make([]string, 0, len(results))
+			for _, r := range results {
+				ids = append(ids, r.Recipe.ID)
+			}
+			assert.ElementsMatch(t, tc.want, ids)
+		})
+	}
+}
+
+func TestGetMatches_ExcludeAllergens(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "bread"},
+		{ID: "p2", IngredientID: "peanut-butter"},
+		{ID: "p3", IngredientID: "jam"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "pbj", Ingredients: []clients.RecipeIngredient{{IngredientID: "bread"}, {IngredientID: "peanut-butter"}}},
+		{ID: "jam-toast", Ingredients: []clients.RecipeIngredient{{IngredientID: "bread"}, {IngredientID: "jam"}}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "bread").Return(&clients.IngredientDetail{ID: "bread"}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "jam").Return(&clients.IngredientDetail{ID: "jam"}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "peanut-butter").Return(
+		&clients.IngredientDetail{ID: "peanut-butter", Allergens: []string{"peanut"}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?exclude_allergens=peanut&exclude_allergens=sesame", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "jam-toast", results[0].Recipe.ID)
+}
+
+func TestGetMatches_InvalidMaxTotalMinutes(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	for _, q := range []string{"max_total_minutes=abc", "max_total_minutes=-1", "max_total_minutes=1.5"} {
+		req := httptest.NewRequest(http.MethodGet, "/matches?"+q, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
+
+func TestGetMatches_MaxTotalMinutes(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Title: "Toast", PrepMinutes: 5, CookMinutes: 10},
+		{ID: "r2", Title: "Roast", PrepMinutes: 20, CookMinutes: 90},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?max_total_minutes=15", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "r1", results[0].Recipe.ID)
+}
+
+func TestGetMatches_InvalidCanMakeMinCoverage(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	for _, q := range []string{"can_make_min_coverage=abc", "can_make_min_coverage=-1", "can_make_min_coverage=101"} {
+		req := httptest.NewRequest(http.MethodGet, "/matches?"+q, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
+
+func TestGetMatches_MinCoverage(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "ing1"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "half", Title: "Half", Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "ing1"}, {ID: "ri2", IngredientID: "ing2"},
+		}},
+		{ID: "quarter", Title: "Quarter", Ingredients: []clients.RecipeIngredient{
+			{ID: "ri3", IngredientID: "ing1"}, {ID: "ri4", IngredientID: "ing2"},
+			{ID: "ri5", IngredientID: "ing3"}, {ID: "ri6", IngredientID: "ing4"},
+		}},
+		{ID: "third", Title: "Third", Ingredients: []clients.RecipeIngredient{
+			{ID: "ri7", IngredientID: "ing1"}, {ID: "ri8", IngredientID: "ing2"}, {ID: "ri9", IngredientID: "ing3"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "ing2").Return(&clients.IngredientDetail{ID: "ing2", Name: "basil"}, nil)
+
+	// max_missing admits all three; the floor keeps the recipe exactly at 50%
+	// and drops the others.
+	req := httptest.NewRequest(http.MethodGet, "/matches?max_missing=3&min_coverage=50", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "half", results[0].Recipe.ID)
+	assert.InDelta(t, 50.0, results[0].CoveragePct, 0.0001)
+}
+
+func TestGetMatches_InvalidMinCoverage(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	for _, q := range []string{"min_coverage=abc", "min_coverage=-0.5", "min_coverage=100.1"} {
+		req := httptest.NewRequest(http.MethodGet, "/matches?"+q, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, q)
+		assert.Contains(t, rec.Body.String(), "min_coverage must be a number between 0 and 100", q)
+	}
+}
+
+func TestGetMatches_InvalidSubDepth(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	for _, q := range []string{"sub_depth=abc", "sub_depth=0", "sub_depth=6"} {
+		req := httptest.NewRequest(http.MethodGet, "/matches?allow_subs=true&"+q, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, q)
+		assert.Contains(t, rec.Body.String(), "sub_depth must be an integer between 1 and 5", q)
+	}
+}
+
+func TestGetMatches_InvalidServings(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	for _, q := range []string{"servings=abc", "servings=0", "servings=-2"} {
+		req := httptest.NewRequest(http.MethodGet, "/matches?"+q, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, q)
+		assert.Contains(t, rec.Body.String(), "servings must be a positive integer", q)
+	}
+}
+
+func TestGetMatches_InvalidNearMiss(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	for _, q := range []string{"near_miss_missing=abc", "near_miss_missing=-1", "near_miss_coverage=abc", "near_miss_coverage=101"} {
+		req := httptest.NewRequest(http.MethodGet, "/matches?"+q, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
+
+func TestGetMatches_NearMissMissing(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "ing1"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Title: "One short", Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "ing1"}, {ID: "ri2", IngredientID: "ing2"},
+		}},
+		{ID: "r2", Title: "Two short", Ingredients: []clients.RecipeIngredient{
+			{ID: "ri3", IngredientID: "ing2"}, {ID: "ri4", IngredientID: "ing3"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "ing2").Return(&clients.IngredientDetail{ID: "ing2", Name: "basil"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?near_miss_missing=1", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "r1", results[0].Recipe.ID)
+	assert.True(t, results[0].NearMiss)
+}
+
+func TestGetMatches_Paginated(t *testing.T) {
+	recipes := make([]clients.Recipe, 0, 5)
+	for _, id := range []string{"r1", "r2", "r3", "r4", "r5"} {
+		recipes = append(recipes, clients.Recipe{ID: id, Title: id})
+	}
+
+	for name, tc := range map[string]struct {
+		query string
+		want  []string
+	}{
+		"no window":        {query: "paginated=true", want: []string{"r1", "r2", "r3", "r4", "r5"}},
+		"first page":       {query: "paginated=true&limit=2", want: []string{"r1", "r2"}},
+		"second page":      {query: "paginated=true&limit=2&offset=2", want: []string{"r3", "r4"}},
+		"short last page":  {query: "paginated=true&limit=2&offset=4", want: []string{"r5"}},
+		"offset past end":  {query: "paginated=true&limit=2&offset=10", want: []string{}},
+		"offset, no limit": {query: "paginated=true&offset=3", want: []string{"r4", "r5"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			router, pantryMock, recipeMock := setupRouter(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/matches?"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			var resp struct {
+				Total   int                   `json:"total"`
+				Results []service.MatchResult `json:"results"`
+			}
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+			assert.Equal(t, 5, resp.Total)
+			require.NotNil(t, resp.Results)
+			ids := make([]string, 0, len(resp.Results))
+			for _, r := range resp.Results {
+				ids = append(ids, r.Recipe.ID)
+			}
+			assert.Equal(t, tc.want, ids)
+		})
+	}
+}
+
+func TestGetMatches_InvalidPagination(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	for _, q := range []string{
+		"limit=2",
+		"offset=1",
+		"paginated=true&limit=-1",
+		"paginated=true&offset=abc",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/matches?"+q, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
+
+func TestGetMatches_MealPlan(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "egg", Quantity: 3}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "egg", Quantity: 2}}},
+		{ID: "r2", Ingredients: []clients.RecipeIngredient{{IngredientID: "egg", Quantity: 2}}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "egg").Return(&clients.IngredientDetail{ID: "egg", Name: "egg"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?meal_plan=true&include_unmakeable=true", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp matchesResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	require.Len(t, resp.Results, 2)
+	assert.True(t, resp.Results[0].CanMake)
+	assert.False(t, resp.Results[1].CanMake)
+	assert.Equal(t, []service.PantryRemainder{{IngredientID: "egg", Quantity: 1}}, resp.RemainingPantry)
+}
+
+func TestGetMatches_BackendError(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return(nil, errors.New("down"))
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil).Maybe()
+
+	req := httptest.NewRequest(http.MethodGet, "/matches", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+func TestGetMatches_UpstreamErrorCodes(t *testing.T) {
+	t.Run("pantry", func(t *testing.T) {
+		router, pantryMock, recipeMock := setupRouter(t)
+		pantryMock.EXPECT().GetPantry(mock.Anything).Return(nil, errors.New("down"))
+		recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil).Maybe()
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/matches", nil))
+
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+		assert.Equal(t, "pantry_unavailable", decodeErrorCode(t, rec))
+	})
+
+	t.Run("recipes", func(t *testing.T) {
+		router, pantryMock, recipeMock := setupRouter(t)
+		pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+		recipeMock.EXPECT().GetRecipes(mock.Anything).Return(nil, errors.New("down"))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/matches", nil))
+
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+		assert.Equal(t, "recipes_unavailable", decodeErrorCode(t, rec))
+	})
+
+	t.Run("dictionary is non-fatal", func(t *testing.T) {
+		router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+		pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+		recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+			{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}}},
+		}, nil)
+		dictMock.EXPECT().GetSubstitutes(mock.Anything, "egg").Return(nil, errors.New("down"))
+		dictMock.EXPECT().GetIngredient(mock.Anything, "egg").Return(nil, errors.New("down"))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/matches?allow_subs=true&max_missing=1", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestRequestTimeout(t *testing.T) {
+	// slow answers after delay unless the request context ends first, like
+	// the HTTP clients do.
+	slow := func(ctx context.Context, delay time.Duration) error {
+		select {
+		case <-time.After(delay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{name: "get", method: http.MethodGet, target: "/matches"},
+		{name: "query", method: http.MethodPost, target: "/matches/query", body: `{}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			// Each upstream fits the budget alone; waiting on both does not.
+			pantryMock.EXPECT().GetPantry(mock.Anything).RunAndReturn(
+				func(ctx context.Context) ([]clients.PantryItem, error) {
+					return []clients.PantryItem{}, slow(ctx, 30*time.Millisecond)
+				})
+			recipeMock.EXPECT().GetRecipes(mock.Anything).RunAndReturn(
+				func(ctx context.Context) ([]clients.Recipe, error) {
+					if err := slow(ctx, 30*time.Millisecond); err != nil {
+						return nil, err
+					}
+					return []clients.Recipe{}, slow(ctx, time.Second)
+				}).Maybe()
+			svc := service.New(pantryMock, recipeMock, mocks.NewMockDictionaryFetcher(t))
+			router := NewRouter(svc, WithRequestTimeout(50*time.Millisecond))
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+
+			assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+			assert.Equal(t, "timeout", decodeErrorCode(t, rec))
+		})
+	}
+
+	t.Run("probes are not bounded", func(t *testing.T) {
+		svc := service.New(
+			mocks.NewMockPantryFetcher(t), mocks.NewMockRecipeFetcher(t), mocks.NewMockDictionaryFetcher(t))
+		router := NewRouter(svc, WithRequestTimeout(time.Nanosecond))
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestPostMeal_UpstreamErrorCode(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return(nil, errors.New("down"))
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil).Maybe()
+
+	body := `{"recipe_ids":["r1"]}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/matches/meal", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, "pantry_unavailable", decodeErrorCode(t, rec))
+}
+
+func TestGetMatches_UpstreamClientTimeout(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+	// What an http.Client.Timeout on the pantry client looks like.
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return(nil, &url.Error{
+		Op:  "Get",
+		URL: "http://pantry/pantry",
+		Err: fmt.Errorf("%w (Client.Timeout exceeded while awaiting headers)", context.DeadlineExceeded),
+	})
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil).Maybe()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/matches", nil))
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, "pantry_unavailable", decodeErrorCode(t, rec))
+}
+
+func decodeErrorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.NotEmpty(t, body.Error)
+	return body.Code
+}
+
+func TestPostMatchQuery_Success(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil)
+
+	body := `{"prompt":"something quick","max_missing":0}`
+	req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestPostMatchQuery_EmptyPromptFallsBackToFullScoring(t *testing.T) {
+	recipes := []clients.Recipe{
+		{ID: "r1", Title: "Garlic Pasta", Tags: []string{"italian"}},
+		{ID: "r2", Title: "Chicken Stir Fry", Tags: []string{"asian"}},
+	}
+
+	for name, tc := range map[string]struct {
+		prompt string
+		want   []string
+	}{
+		"empty":      {prompt: "", want: []string{"r1", "r2"}},
+		"whitespace": {prompt: "  \t ", want: []string{"r1", "r2"}},
+		"meaningful": {prompt: "something asian", want: []string{"r2", "r1"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			router, pantryMock, recipeMock := setupRouter(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+
+			body, err := json.Marshal(map[string]any{"prompt": tc.prompt})
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(string(body)))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			var results []service.MatchResult
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+			ids := make([]string, 0, len(results))
+			for _, r := range results {
+				ids = append(ids, r.Recipe.ID)
+			}
+			assert.Equal(t, tc.want, ids)
+		})
+	}
+}
+
+func TestPostMatchQuery_Tags(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Title: "Veg Curry", Tags: []string{"vegetarian"}},
+		{ID: "r2", Title: "Steak", Tags: []string{"quick"}},
+	}, nil)
+
+	body := `{"tags":["vegetarian"]}`
+	req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "r1", results[0].Recipe.ID)
+}
+
+func TestPostMatchQuery_NegativeMaxTotalMinutes(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	body := `{"max_total_minutes":-5}`
+	req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestPostMatchQuery_PantryConstrained(t *testing.T) {
+	recipes := []clients.Recipe{
+		{ID: "r1", Title: "Omelette", Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}}},
+		{ID: "r2", Title: "Quiche", Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}, {IngredientID: "cream"}}},
+	}
+
+	for name, tc := range map[string]struct {
+		body string
+		want []string
+	}{
+		"off":  {body: `{"pantry_constrained":false,"max_missing":1}`, want: []string{"r1", "r2"}},
+		"on":   {body: `{"pantry_constrained":true,"max_missing":1}`, want: []string{"r1"}},
+		"only": {body: `{"pantry_constrained":true}`, want: []string{"r1"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "egg"}}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+			dictMock.EXPECT().GetIngredient(mock.Anything, "cream").Return(&clients.IngredientDetail{ID: "cream", Name: "cream"}, nil).Maybe()
+
+			req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			var results []service.MatchResult
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+			ids := make([]string, 0, len(results))
+			for _, r := range results {
+				ids = append(ids, r.Recipe.ID)
+			}
+			assert.Equal(t, tc.want, ids)
+		})
+	}
+}
+
+func TestPostMatchQuery_InvalidBody(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(`{bad`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestPostMatchQuery_UnknownStrategy(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(`{"strategy":"magic"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestPostMatchQuery_NegativeMaxMissing(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil)
+
+	body := `{"max_missing":-5}`
+	req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	// Negative max_missing is clamped to 0, not an error
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMatchQueryRequest_MaxMissing(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body string
+		want int
+	}{
+		"omitted":       {body: `{}`, want: 2},
+		"null":          {body: `{"max_missing":null}`, want: 2},
+		"explicit zero": {body: `{"max_missing":0}`, want: 0},
+		"explicit":      {body: `{"max_missing":3}`, want: 3},
+		"negative":      {body: `{"max_missing":-5}`, want: 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var req matchQueryRequest
+			require.NoError(t, json.Unmarshal([]byte(tc.body), &req))
+			assert.Equal(t, tc.want, req.maxMissing(2))
+		})
+	}
+
+	var omitted, zero matchQueryRequest
+	require.NoError(t, json.Unmarshal([]byte(`{}`), &omitted))
+	require.NoError(t, json.Unmarshal([]byte(`{"max_missing":0}`), &zero))
+	assert.Nil(t, omitted.MaxMissing)
+	require.NotNil(t, zero.MaxMissing)
+	assert.Equal(t, 0, *zero.MaxMissing)
+}
+
+func TestPostMatchQuery_MaxMissing(t *testing.T) {
+	recipes := []clients.Recipe{
+		{ID: "r1", Title: "One short", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}}},
+	}
+
+	for name, tc := range map[string]struct {
+		body string
+		want int
+	}{
+		"omitted uses default": {body: `{}`, want: 0},
+		"explicit zero":        {body: `{"max_missing":0}`, want: 0},
+		"explicit one":         {body: `{"max_missing":1}`, want: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+			dictMock.EXPECT().GetIngredient(mock.Anything, "ing1").
+				Return(&clients.IngredientDetail{ID: "ing1", Name: "garlic"}, nil).Maybe()
+
+			req := httptest.NewRequest(http.MethodPost, "/matches/query", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			var results []service.MatchResult
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+			assert.Len(t, results, tc.want)
+		})
+	}
+}
+
+func TestGetExplain(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "flour"}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "bread", Title: "Bread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "yeast"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(&clients.IngredientDetail{Name: "x"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches/bread/explain", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var exp service.Explanation
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&exp))
+	assert.Equal(t, "bread", exp.RecipeID)
+	require.Len(t, exp.Ingredients, 2)
+	assert.Equal(t, service.MatchSourceDirect, exp.Ingredients[0].Status)
+	assert.Equal(t, service.ExplainMissing, exp.Ingredients[1].Status)
+	assert.InDelta(t, 50.0, exp.CoveragePct, 0.0001)
+}
+
+func TestGetExplain_IgnoresMinCoverage(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "flour"}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "bread", Title: "Bread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "yeast"}, {IngredientID: "salt"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(&clients.IngredientDetail{Name: "x"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches/bread/explain?min_coverage=50", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var exp service.Explanation
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&exp))
+	assert.Equal(t, 3, exp.RequiredCount)
+	assert.Equal(t, 1, exp.MatchedCount)
+	require.Len(t, exp.Ingredients, 3)
+	assert.Equal(t, service.MatchSourceDirect, exp.Ingredients[0].Status)
+	assert.Equal(t, service.ExplainMissing, exp.Ingredients[1].Status)
+	assert.InDelta(t, 33.3, exp.CoveragePct, 0.0001)
+}
+
+func TestGetExplain_UnknownRecipe(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{{ID: "bread"}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches/cake/explain", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetRecipeMissing(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "flour", Quantity: 1}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "bread", Title: "Bread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "yeast", Quantity: 7, Unit: "g"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "yeast").Return(&clients.IngredientDetail{Name: "yeast"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/recipes/bread/missing", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"recipe_id": "bread",
+		"title": "Bread",
+		"missing_ingredients": [{"ingredient_id": "yeast", "name": "yeast", "name_source": "exact", "quantity": 7, "unit": "g"}]
+	}`, rec.Body.String())
+}
+
+func TestGetRecipeMissing_UnknownRecipe(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{{ID: "bread"}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/recipes/cake/missing", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":"recipe not found: cake"}`, rec.Body.String())
+}
+
+func TestPostBatch(t *testing.T) {
+	router, _, recipeMock := setupRouter(t)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "omelette", Ingredients: []clients.RecipeIngredient{{IngredientID: "eggs"}}},
+		{ID: "toast", Ingredients: []clients.RecipeIngredient{{IngredientID: "bread"}}},
+	}, nil).Once()
+
+	body := `{"pantries": [
+		{"id": "north", "items": [{"ingredient_id": "eggs", "quantity": 12}]},
+		{"id": "south", "items": [{"ingredient_id": "bread", "quantity": 2}]}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/matches/batch?include_meta=true", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got struct {
+		Pantries []struct {
+			ID      string                `json:"id"`
+			Results []service.MatchResult `json:"results"`
+			Meta    *service.ScoreMeta    `json:"meta"`
+		} `json:"pantries"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	require.Len(t, got.Pantries, 2)
+	assert.Equal(t, "north", got.Pantries[0].ID)
+	require.Len(t, got.Pantries[0].Results, 1)
+	assert.Equal(t, "omelette", got.Pantries[0].Results[0].Recipe.ID)
+	assert.Equal(t, "south", got.Pantries[1].ID)
+	require.Len(t, got.Pantries[1].Results, 1)
+	assert.Equal(t, "toast", got.Pantries[1].Results[0].Recipe.ID)
+	require.NotNil(t, got.Pantries[1].Meta)
+	assert.Equal(t, 1, got.Pantries[1].Meta.PantryItems)
+}
+
+func TestPostBatch_InvalidPantries(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	many := strings.Repeat(`{"items": []},`, maxBatchPantries)
+	for _, body := range []string{`{}`, `{"pantries": []}`, `{"pantries": [` + many + `{"items": []}]}`, `nope`} {
+		req := httptest.NewRequest(http.MethodPost, "/matches/batch", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+}
+
+func TestPostMeal_Success(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "eggs", Quantity: 4, Unit: "whole"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "eggs", Quantity: 2, Unit: "whole"}}},
+		{ID: "r2", Ingredients: []clients.RecipeIngredient{{IngredientID: "eggs", Quantity: 2, Unit: "whole"}}},
+	}, nil)
+
+	body := `{"recipe_ids":["r1","r2"]}`
+	req := httptest.NewRequest(http.MethodPost, "/matches/meal", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var result service.MealResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+	assert.True(t, result.CanMake)
+	assert.Empty(t, result.Shortfall)
+}
+
+func TestPostMeal_UnknownRecipe(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/matches/meal", strings.NewReader(`{"recipe_ids":["missing"]}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPostMeal_EmptyRecipeIDs(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/matches/meal", strings.NewReader(`{"recipe_ids":[]}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetStats(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "ing1"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}}},
+		{ID: "r2", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing2"}}},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches/stats", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var stats service.CatalogStats
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	assert.Equal(t, 2, stats.TotalRecipes)
+	assert.Equal(t, 1, stats.MakeableCount)
+	assert.Equal(t, 1, stats.Histogram[0].Count)
+	assert.Equal(t, 1, stats.Histogram[len(stats.Histogram)-1].Count)
+}
+
+func TestGetBands(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "ing1"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Title: "Full", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}}},
+		{ID: "r2", Title: "Half", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}, {IngredientID: "ing2"}}},
+		{ID: "r3", Title: "None", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing2"}}},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches/bands?bands=100,50&samples=1", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var bands service.CoverageBands
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&bands))
+	assert.Equal(t, 3, bands.TotalRecipes)
+	require.Len(t, bands.Bands, 2)
+	assert.Equal(t, "100%", bands.Bands[0].Label)
+	assert.Equal(t, 1, bands.Bands[0].Count)
+	assert.Equal(t, "50-100%", bands.Bands[1].Label)
+	assert.Equal(t, 1, bands.Bands[1].Count)
+	require.Len(t, bands.Bands[1].Samples, 1)
+	assert.Equal(t, "Half", bands.Bands[1].Samples[0].Title)
+}
+
+func TestGetBands_InvalidParams(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	for _, q := range []string{"bands=abc", "bands=80,101", "bands=-5", "samples=-1", "samples=x"} {
+		req := httptest.NewRequest(http.MethodGet, "/matches/bands?"+q, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, q)
+	}
+}
+
+func TestGetSubstituteUsage(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "oil"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "butter"}}},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 1},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?allow_subs=true", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodGet, "/matches/stats/substitutes", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var usage []service.SubstituteUsage
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&usage))
+	assert.Equal(t, []service.SubstituteUsage{{IngredientID: "butter", SubstituteID: "oil", Count: 1}}, usage)
+}
+
+// fakeCacheStats is a fixed [CacheStatsReporter].
+type fakeCacheStats struct{ names, subs clients.CacheStats }
+
+func (f fakeCacheStats) NameCacheStats() clients.CacheStats       { return f.names }
+func (f fakeCacheStats) SubstituteCacheStats() clients.CacheStats { return f.subs }
+
+func TestGetCacheStats(t *testing.T) {
+	svc := service.New(mocks.NewMockPantryFetcher(t), mocks.NewMockRecipeFetcher(t), nil)
+	stats := fakeCacheStats{
+		names: clients.CacheStats{Hits: 12, Misses: 3},
+		subs:  clients.CacheStats{Hits: 4, Misses: 1},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		router http.Handler
+		want   string
+	}{
+		{
+			name:   "configured",
+			router: NewRouter(svc, WithCacheStats(stats)),
+			want:   `{"names":{"hits":12,"misses":3},"substitutes":{"hits":4,"misses":1}}`,
+		},
+		{
+			name:   "not configured",
+			router: NewRouter(svc),
+			want:   `{"names":{"hits":0,"misses":0},"substitutes":{"hits":0,"misses":0}}`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/matches/stats/cache", nil))
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, tc.want, rec.Body.String())
+		})
+	}
+}
+
+func TestGetPreview(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "flour"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Title: "Bread", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}}},
+		{ID: "r2", Title: "Pasta", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}, {IngredientID: "eggs"}}},
+		{ID: "r3", Title: "Omelette", Ingredients: []clients.RecipeIngredient{{IngredientID: "eggs"}, {IngredientID: "cheese"}}},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches/preview?buy=eggs", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.Equal(t, "r2", results[0].Recipe.ID)
+}
+
+func TestGetPreview_BuyRequired(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches/preview", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetMatches_IncludeUnmakeable(t *testing.T) {
+	router, pantryMock, recipeMock, dictMock := setupRouterWithDictionary(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "ing1").Return(nil, clients.ErrIngredientNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/matches?include_unmakeable=true", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var results []service.MatchResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	assert.False(t, results[0].CanMake)
+}
+
+func TestPostDiff(t *testing.T) {
+	router, pantryMock, recipeMock := setupRouter(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "ing1"}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}, {IngredientID: "ing2"}}},
+	}, nil)
+
+	body := `{"recipes":[{"id":"r1","ingredients":[{"ingredient_id":"ing1"}]}]}`
+	req := httptest.NewRequest(http.MethodPost, "/matches/diff", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var diff service.CatalogDiff
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&diff))
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "r1", diff.Changes[0].RecipeID)
+	assert.False(t, diff.Changes[0].CurrentCanMake)
+	assert.True(t, diff.Changes[0].AlternateCanMake)
+}
+
+func TestPostDiff_InvalidBody(t *testing.T) {
+	router, _, _ := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/matches/diff", strings.NewReader(`{bad`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
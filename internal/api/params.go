@@ -0,0 +1,55 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// matchesParams lists every query param GET /matches understands, including
+// strict itself. Keep it in step with parseScoreOptions, parsePage and
+// handleGetMatches.
+var matchesParams = []string{
+	"allow_subs", "sub_depth", "max_missing", "can_make_min_coverage", "min_coverage",
+	"treat_optional_as_required", "include_unmakeable", "max_calories", "max_total_minutes",
+	"tags", "tags_any", "staples", "use_groups", "exclude_subs", "exclude_allergens",
+	"include_matched", "include_names", "include_have", "include_used", "include_summary",
+	"near_miss_missing", "near_miss_coverage", "substitution_summary", "suggest_subs",
+	"seed", "strategy", "quantity_check", "servings", "sort", "meal_plan",
+	"paginated", "limit", "offset", "include_meta", "strict", "pretty",
+}
+
+// unknownParams returns the names in q that are not in known, sorted.
+func unknownParams(q url.Values, known []string) []string {
+	var unknown []string
+	for name := range q {
+		if !slices.Contains(known, name) {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// checkParams guards against typo'd query params, which would otherwise be
+// silently ignored. With strict=true it answers 400 naming them and returns
+// false; otherwise it logs a warning and returns true.
+func checkParams(w http.ResponseWriter, r *http.Request, known []string) bool {
+	q := r.URL.Query()
+	unknown := unknownParams(q, known)
+	if len(unknown) == 0 {
+		return true
+	}
+	if q.Get("strict") == "true" {
+		jsonError(w, "unknown query parameters: "+strings.Join(unknown, ", "), http.StatusBadRequest)
+		return false
+	}
+	slog.Default().WarnContext(r.Context(), "ignoring unknown query parameters",
+		"path", r.URL.Path,
+		"params", unknown,
+	)
+	return true
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+func TestGetMatches_UnknownParams(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	t.Run("strict rejects", func(t *testing.T) {
+		router, _, _ := setupRouter(t)
+
+		req := httptest.NewRequest(http.MethodGet, "/matches?strict=true&max_mising=2&allow_sub=true", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"unknown query parameters: allow_sub, max_mising"}`, rec.Body.String())
+	})
+
+	t.Run("lenient warns", func(t *testing.T) {
+		router, pantryMock, recipeMock := setupRouter(t)
+		pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+		recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil)
+		logs.Reset()
+
+		req := httptest.NewRequest(http.MethodGet, "/matches?max_mising=2", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, logs.String(), "ignoring unknown query parameters")
+		assert.Contains(t, logs.String(), "max_mising")
+	})
+}
+
+func TestUnknownParams(t *testing.T) {
+	q := url.Values{"max_missing": {"1"}, "strict": {"false"}, "zeta": {"1"}, "alpha": {""}}
+	assert.Equal(t, []string{"alpha", "zeta"}, unknownParams(q, matchesParams))
+	assert.Empty(t, unknownParams(url.Values{"allow_subs": {"true"}}, matchesParams))
+}
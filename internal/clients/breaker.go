@@ -0,0 +1,142 @@
+package clients
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults for [BreakerConfig] fields left unset.
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without contacting the upstream while its
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerConfig controls the circuit breaker [DictionaryClient] keeps around
+// its upstream calls. After Threshold consecutive failures (connection
+// errors, timeouts and 5xx responses) the breaker opens and calls fail fast
+// with [ErrCircuitOpen] until Cooldown has elapsed. Then a single trial call
+// is let through: success closes the breaker, failure reopens it for another
+// Cooldown.
+type BreakerConfig struct {
+	// Threshold is how many consecutive failures open the breaker. Zero or
+	// less disables the breaker.
+	Threshold int
+	Cooldown  time.Duration
+}
+
+// WithCircuitBreaker guards [DictionaryClient] calls with a circuit breaker
+// configured by cfg. Cache hits and Ping are never short-circuited. Other
+// clients ignore this option.
+func WithCircuitBreaker(cfg BreakerConfig) ClientOption {
+	return func(c *clientConfig) {
+		c.breaker = cfg
+	}
+}
+
+// breaker is a consecutive-failure circuit breaker. A nil *breaker allows
+// every call.
+type breaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time // zero while closed
+}
+
+func newBreaker(cfg BreakerConfig) *breaker {
+	if cfg.Threshold <= 0 {
+		return nil
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultBreakerCooldown
+	}
+	return &breaker{cfg: cfg}
+}
+
+func (b *breaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// allow reports whether a call may go ahead and whether it is the trial call.
+// Once the cooldown has elapsed it admits one trial call and holds the rest
+// back for another cooldown, which the trial's outcome then cuts short or
+// confirms.
+func (b *breaker) allow() (ok, trial bool) {
+	if b == nil {
+		return true, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return true, false
+	}
+	now := b.clock()
+	if now.Before(b.openUntil) {
+		return false, false
+	}
+	b.openUntil = now.Add(b.cfg.Cooldown)
+	return true, true
+}
+
+// release gives up the trial slot of a trial call that ended without a
+// verdict, so the next call becomes the trial instead of waiting out another
+// cooldown.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.openUntil.IsZero() {
+		b.openUntil = b.clock()
+	}
+}
+
+// record counts the outcome of an admitted call.
+func (b *breaker) record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if b.failures >= b.cfg.Threshold {
+		b.openUntil = b.clock().Add(b.cfg.Cooldown)
+	}
+}
+
+// do sends req through hc unless b is open, counting connection errors and
+// 5xx responses as failures. Errors caused by the caller's own context
+// ending say nothing about the upstream and are not counted; if that call was
+// the trial, its slot is released.
+func (b *breaker) do(hc *http.Client, req *http.Request) (*http.Response, error) {
+	ok, trial := b.allow()
+	if !ok {
+		return nil, ErrCircuitOpen
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		switch {
+		case req.Context().Err() == nil:
+			b.record(true)
+		case trial:
+			b.release()
+		}
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	b.record(resp.StatusCode >= http.StatusInternalServerError)
+	return resp, nil
+}
@@ -0,0 +1,127 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker_OpensAndRecovers(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"ID":"abc-123","Name":"garlic"}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := NewDictionaryClient(server.URL, WithCircuitBreaker(BreakerConfig{Threshold: 3, Cooldown: time.Minute}))
+	client.breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for range 3 {
+		_, err := client.GetIngredient(ctx, "abc-123")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+	// Open: neither lookup reaches the dictionary.
+	_, err := client.GetIngredient(ctx, "abc-123")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	_, err = client.GetSubstitutes(ctx, "abc-123")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(3), calls.Load())
+
+	// After the cooldown a failed trial reopens the breaker.
+	now = now.Add(time.Minute)
+	_, err = client.GetIngredient(ctx, "abc-123")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	_, err = client.GetIngredient(ctx, "abc-123")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(4), calls.Load())
+
+	// A successful trial closes it again.
+	now = now.Add(time.Minute)
+	healthy.Store(true)
+	for range 2 {
+		ing, err := client.GetIngredient(ctx, "abc-123")
+		require.NoError(t, err)
+		assert.Equal(t, "garlic", ing.Name)
+	}
+	assert.Equal(t, int32(6), calls.Load())
+}
+
+func TestCircuitBreaker_CancelledTrialReleasesSlot(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := NewDictionaryClient(server.URL, WithCircuitBreaker(BreakerConfig{Threshold: 1, Cooldown: time.Minute}))
+	client.breaker.now = func() time.Time { return now }
+
+	_, err := client.GetIngredient(context.Background(), "abc-123")
+	require.Error(t, err)
+	now = now.Add(time.Minute)
+
+	// The trial's caller gives up before a verdict; the next call is the trial.
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.GetIngredient(cancelled, "abc-123")
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = client.GetIngredient(context.Background(), "abc-123")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// That trial failed, so the breaker is open for another cooldown.
+	_, err = client.GetIngredient(context.Background(), "abc-123")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+}
+
+func TestCircuitBreaker_IgnoresNonFailures(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL, WithCircuitBreaker(BreakerConfig{Threshold: 1}))
+	for range 3 {
+		_, err := client.GetIngredient(context.Background(), "abc-123")
+		require.ErrorIs(t, err, ErrIngredientNotFound)
+	}
+	// The caller giving up is not the dictionary's fault either.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.GetSubstitutes(ctx, "abc-123")
+	require.Error(t, err)
+	_, err = client.GetIngredient(context.Background(), "abc-123")
+	require.ErrorIs(t, err, ErrIngredientNotFound)
+
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	t.Parallel()
+	assert.Nil(t, newBreaker(BreakerConfig{}))
+	assert.Nil(t, NewDictionaryClient("http://dictionary").breaker)
+}
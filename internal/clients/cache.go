@@ -0,0 +1,170 @@
+package clients
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultNameCacheTTL is a suggested TTL for [WithNameCacheTTL]; ingredient
+// names rarely change.
+const DefaultNameCacheTTL = 10 * time.Minute
+
+// DefaultSubstituteCacheTTL is a suggested TTL for [WithSubstituteCacheTTL];
+// substitute lists rarely change.
+const DefaultSubstituteCacheTTL = 10 * time.Minute
+
+// WithNameCacheTTL makes [DictionaryClient] cache GetIngredient results for
+// ttl. Zero or less disables the cache. Other clients ignore this option.
+func WithNameCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.nameCacheTTL = ttl
+	}
+}
+
+// WithSubstituteCacheTTL makes [DictionaryClient] cache GetSubstitutes
+// results for ttl, including the empty list returned while the endpoint is
+// not live. Zero or less disables the cache. Other clients ignore this
+// option.
+func WithSubstituteCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.subCacheTTL = ttl
+	}
+}
+
+// CacheStats counts lookups served by a client-side cache. Hits were served
+// from a cached entry; misses needed a fetch, possibly shared with concurrent
+// callers.
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// ttlCache caches values by ID and deduplicates concurrent fills of the same
+// ID so only one request reaches the upstream. Failed fetches are not cached.
+type ttlCache[V any] struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry[V]
+	inflight map[string]*cacheFill[V]
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cacheEntry[V any] struct {
+	val       V
+	fetchedAt time.Time
+}
+
+// cacheFill is an in-flight fetch that concurrent callers wait on.
+type cacheFill[V any] struct {
+	done chan struct{}
+	val  V
+	err  error
+}
+
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+	return &ttlCache[V]{
+		ttl:      ttl,
+		entries:  make(map[string]cacheEntry[V]),
+		inflight: make(map[string]*cacheFill[V]),
+	}
+}
+
+// get returns the cached value for id, or calls fetch once for all
+// concurrent callers and caches a successful result. fetch runs detached from
+// the first caller's cancellation so other waiters are not failed by it;
+// each caller still returns early when its own ctx is done.
+func (c *ttlCache[V]) get(
+	ctx context.Context,
+	id string,
+	fetch func(context.Context, string) (V, error),
+) (V, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[id]; ok && c.clock().Sub(e.fetchedAt) < c.ttl {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return e.val, nil
+	}
+	c.misses.Add(1)
+	fill, ok := c.inflight[id]
+	if !ok {
+		fill = &cacheFill[V]{done: make(chan struct{})}
+		c.inflight[id] = fill
+		go c.fill(context.WithoutCancel(ctx), id, fill, fetch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-fill.done:
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+	return fill.val, fill.err
+}
+
+func (c *ttlCache[V]) fill(
+	ctx context.Context,
+	id string,
+	fill *cacheFill[V],
+	fetch func(context.Context, string) (V, error),
+) {
+	fill.val, fill.err = fetch(ctx, id)
+
+	c.mu.Lock()
+	if fill.err == nil {
+		c.entries[id] = cacheEntry[V]{val: fill.val, fetchedAt: c.clock()}
+	}
+	delete(c.inflight, id)
+	c.mu.Unlock()
+	close(fill.done)
+}
+
+// lookup returns the cached value for id without fetching, counting a hit
+// when there is a fresh entry and a miss otherwise.
+func (c *ttlCache[V]) lookup(id string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[id]; ok && c.clock().Sub(e.fetchedAt) < c.ttl {
+		c.hits.Add(1)
+		return e.val, true
+	}
+	c.misses.Add(1)
+	var zero V
+	return zero, false
+}
+
+// set caches val for id as if it had just been fetched.
+func (c *ttlCache[V]) set(id string, val V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[id] = cacheEntry[V]{val: val, fetchedAt: c.clock()}
+}
+
+// clear drops every cached entry. In-flight fills still complete.
+func (c *ttlCache[V]) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// stats reports the hits and misses since the cache was created. A nil cache
+// reports zeros.
+func (c *ttlCache[V]) stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+func (c *ttlCache[V]) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
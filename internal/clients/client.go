@@ -0,0 +1,186 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultMaxResponseBytes bounds upstream response bodies when no limit is
+// configured.
+const DefaultMaxResponseBytes int64 = 32 << 20 // 32 MiB
+
+// DefaultTimeout bounds each upstream request when no timeout is configured.
+const DefaultTimeout = 5 * time.Second
+
+// tracerName names the OpenTelemetry tracer upstream request spans come from.
+const tracerName = "github.com/mwhite7112/woodpantry-matching/internal/clients"
+
+// ErrResponseTooLarge is returned when an upstream response body exceeds the
+// configured size limit.
+var ErrResponseTooLarge = errors.New("upstream response exceeds size limit")
+
+// ErrNullResponse is returned when an upstream answers with the JSON literal
+// null where a list was expected, which likely indicates an upstream bug.
+var ErrNullResponse = errors.New("upstream returned null")
+
+// clientConfig holds settings shared by all upstream clients.
+type clientConfig struct {
+	maxResponseBytes int64
+	timeout          time.Duration
+	retry            RetryConfig
+	breaker          BreakerConfig
+	token            string
+	pantryStrategy   PantryFetchStrategy
+	pantryCacheTTL   time.Duration
+	nameCacheTTL     time.Duration
+	subCacheTTL      time.Duration
+}
+
+// ClientOption configures an upstream client.
+type ClientOption func(*clientConfig)
+
+// WithMaxResponseBytes caps the size of response bodies the client will
+// decode. Larger responses fail with [ErrResponseTooLarge].
+func WithMaxResponseBytes(n int64) ClientOption {
+	return func(c *clientConfig) {
+		c.maxResponseBytes = n
+	}
+}
+
+// WithTimeout bounds each request the client makes, including reading the
+// response body. Zero or less disables the timeout.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.timeout = d
+	}
+}
+
+// WithToken sends token as a bearer credential on every request the client
+// makes. Each upstream can be given its own token.
+func WithToken(token string) ClientOption {
+	return func(c *clientConfig) {
+		c.token = token
+	}
+}
+
+// WithPantryFetchStrategy selects how [PantryClient] trades freshness for
+// latency. ttl applies to [PantryFetchShortCache]; zero uses
+// [DefaultPantryCacheTTL]. Other clients ignore this option.
+func WithPantryFetchStrategy(strategy PantryFetchStrategy, ttl time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.pantryStrategy = strategy
+		c.pantryCacheTTL = ttl
+	}
+}
+
+func newClientConfig(opts []ClientOption) clientConfig {
+	cfg := clientConfig{maxResponseBytes: DefaultMaxResponseBytes, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// newHTTPClient builds the HTTP client for an upstream from cfg.
+func newHTTPClient(cfg clientConfig) *http.Client {
+	var transport http.RoundTripper = http.DefaultTransport
+	if cfg.retry.MaxRetries > 0 {
+		transport = &retryTransport{cfg: cfg.retry, base: transport}
+	}
+	if cfg.token != "" {
+		transport = &bearerTransport{token: cfg.token, base: transport}
+	}
+	// Forward first so the client span's trace context replaces the
+	// incoming one.
+	transport = &tracingTransport{base: transport}
+	transport = &forwardTransport{base: transport}
+	return &http.Client{Timeout: max(cfg.timeout, 0), Transport: transport}
+}
+
+// ping checks that the upstream at baseURL answers GET /healthz with a 2xx
+// status.
+func ping(ctx context.Context, hc *http.Client, baseURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/healthz", nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<10)) //nolint:errcheck // drain for connection reuse
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// bearerTransport sets an Authorization bearer header on each request.
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
+
+// tracingTransport records a span per upstream request, covering retries,
+// and propagates it to the upstream in the request headers.
+type tracingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	defer span.End()
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	return resp, nil
+}
+
+// decodeJSON decodes body into v, reading at most limit bytes. A limit of
+// zero or less uses [DefaultMaxResponseBytes].
+func decodeJSON(body io.Reader, v any, limit int64) error {
+	if limit <= 0 {
+		limit = DefaultMaxResponseBytes
+	}
+
+	lr := &io.LimitedReader{R: body, N: limit + 1}
+	err := json.NewDecoder(lr).Decode(v)
+	if lr.N <= 0 {
+		return fmt.Errorf("%w of %d bytes", ErrResponseTooLarge, limit)
+	}
+	if err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+func TestPing(t *testing.T) {
+	t.Parallel()
+
+	clients := map[string]func(baseURL string) pinger{
+		"pantry":     func(u string) pinger { return NewPantryClient(u) },
+		"recipes":    func(u string) pinger { return NewRecipeClient(u) },
+		"dictionary": func(u string) pinger { return NewDictionaryClient(u) },
+	}
+	for name, newClient := range clients {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodGet, r.Method)
+				assert.Equal(t, "/healthz", r.URL.Path)
+				w.Write([]byte("ok"))
+			}))
+			defer healthy.Close()
+			unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer unhealthy.Close()
+			down := httptest.NewServer(http.NotFoundHandler())
+			down.Close()
+
+			require.NoError(t, newClient(healthy.URL).Ping(context.Background()))
+
+			err := newClient(unhealthy.URL).Ping(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "health check returned 503")
+
+			assert.Error(t, newClient(down.URL).Ping(context.Background()))
+		})
+	}
+}
+
+func TestForwardedHeaders(t *testing.T) {
+	t.Parallel()
+
+	received := make(chan http.Header, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer srv.Close()
+
+	incoming := http.Header{}
+	incoming.Set("X-Request-Id", "req-123")
+	incoming.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	incoming.Set("Cookie", "session=secret")
+	ctx := WithForwardedHeaders(context.Background(), incoming)
+
+	client := NewPantryClient(srv.URL, WithToken("tok"))
+	_, err := client.GetPantry(ctx)
+	require.NoError(t, err)
+	got := <-received
+	assert.Equal(t, "req-123", got.Get("X-Request-Id"))
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", got.Get("Traceparent"))
+	assert.Equal(t, "Bearer tok", got.Get("Authorization"))
+	assert.Empty(t, got.Get("Cookie"), "only correlation headers are forwarded")
+
+	_, err = client.GetPantry(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, (<-received).Get("X-Request-Id"))
+}
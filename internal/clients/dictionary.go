@@ -1,11 +1,15 @@
 package clients
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"sync"
+	"time"
 )
 
 var ErrIngredientNotFound = errors.New("ingredient not found")
@@ -16,6 +20,9 @@ var ErrIngredientNotFound = errors.New("ingredient not found")
 type IngredientDetail struct {
 	ID   string `json:"ID"`
 	Name string `json:"Name"`
+	// Allergens lists allergen tags such as "peanut"; absent when the
+	// dictionary does not report any.
+	Allergens []string `json:"Allergens,omitempty"`
 }
 
 // IngredientSubstitute mirrors the response from GET /ingredients/:id/substitutes.
@@ -26,26 +33,105 @@ type IngredientSubstitute struct {
 	Notes        string  `json:"notes"`
 }
 
+// SubstitutionGroup mirrors the response from GET /ingredients/:id/group.
+// Any member of a group is interchangeable with any other (e.g. leafy greens).
+type SubstitutionGroup struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	MemberIDs []string `json:"member_ids"`
+}
+
 type DictionaryClient struct {
 	baseURL string
 	http    *http.Client
+
+	maxResponseBytes int64
+	names            *ttlCache[IngredientDetail]       // nil when name caching is disabled
+	subs             *ttlCache[[]IngredientSubstitute] // nil when substitute caching is disabled
+	breaker          *breaker                          // nil when the circuit breaker is disabled
+
+	batchMu            sync.Mutex
+	batchUnsupportedAt time.Time // when the substitutes batch endpoint last answered 404/405
 }
 
-func NewDictionaryClient(baseURL string) *DictionaryClient {
-	return &DictionaryClient{baseURL: baseURL, http: &http.Client{}}
+func NewDictionaryClient(baseURL string, opts ...ClientOption) *DictionaryClient {
+	cfg := newClientConfig(opts)
+	c := &DictionaryClient{
+		baseURL:          baseURL,
+		http:             newHTTPClient(cfg),
+		maxResponseBytes: cfg.maxResponseBytes,
+		breaker:          newBreaker(cfg.breaker),
+	}
+	if cfg.nameCacheTTL > 0 {
+		c.names = newTTLCache[IngredientDetail](cfg.nameCacheTTL)
+	}
+	if cfg.subCacheTTL > 0 {
+		c.subs = newTTLCache[[]IngredientSubstitute](cfg.subCacheTTL)
+	}
+	return c
 }
 
-// GetIngredient fetches a single ingredient by ID.
+// GetIngredient fetches a single ingredient by ID, served from the name cache
+// when [WithNameCacheTTL] is set.
 // Returns [ErrIngredientNotFound] when the ingredient does not exist.
 func (c *DictionaryClient) GetIngredient(ctx context.Context, id string) (*IngredientDetail, error) {
+	if c.names == nil {
+		return c.fetchIngredient(ctx, id)
+	}
+	ing, err := c.names.get(ctx, id, func(ctx context.Context, id string) (IngredientDetail, error) {
+		ing, err := c.fetchIngredient(ctx, id)
+		if err != nil {
+			return IngredientDetail{}, err
+		}
+		return *ing, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ing, nil
+}
+
+// ClearNameCache drops all cached ingredient names, forcing the next
+// GetIngredient for each ID to query the dictionary.
+func (c *DictionaryClient) ClearNameCache() {
+	if c.names != nil {
+		c.names.clear()
+	}
+}
+
+// ClearSubstituteCache drops all cached substitute lists, forcing the next
+// GetSubstitutes for each ID to query the dictionary, and retries the batch
+// endpoint if it was found unsupported.
+func (c *DictionaryClient) ClearSubstituteCache() {
+	if c.subs != nil {
+		c.subs.clear()
+	}
+	c.batchMu.Lock()
+	c.batchUnsupportedAt = time.Time{}
+	c.batchMu.Unlock()
+}
+
+// NameCacheStats reports GetIngredient cache hits and misses; zero when the
+// name cache is disabled.
+func (c *DictionaryClient) NameCacheStats() CacheStats {
+	return c.names.stats()
+}
+
+// SubstituteCacheStats reports GetSubstitutes cache hits and misses; zero
+// when the substitute cache is disabled.
+func (c *DictionaryClient) SubstituteCacheStats() CacheStats {
+	return c.subs.stats()
+}
+
+func (c *DictionaryClient) fetchIngredient(ctx context.Context, id string) (*IngredientDetail, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/ingredients/"+id, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.breaker.do(c.http, req)
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -57,8 +143,8 @@ func (c *DictionaryClient) GetIngredient(ctx context.Context, id string) (*Ingre
 	}
 
 	var ing IngredientDetail
-	if err := json.NewDecoder(resp.Body).Decode(&ing); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+	if err := decodeJSON(resp.Body, &ing, c.maxResponseBytes); err != nil {
+		return nil, err
 	}
 	return &ing, nil
 }
@@ -66,7 +152,21 @@ func (c *DictionaryClient) GetIngredient(ctx context.Context, id string) (*Ingre
 // GetSubstitutes fetches substitute ingredients for the given ingredient ID.
 // Returns an empty slice without error if the endpoint is not yet available (404/405),
 // making this safe to call before the dictionary service exposes the endpoint.
+// Results, including that empty slice, are served from the substitute cache
+// when [WithSubstituteCacheTTL] is set.
 func (c *DictionaryClient) GetSubstitutes(ctx context.Context, ingredientID string) ([]IngredientSubstitute, error) {
+	if c.subs == nil {
+		return c.fetchSubstitutes(ctx, ingredientID)
+	}
+	subs, err := c.subs.get(ctx, ingredientID, c.fetchSubstitutes)
+	if err != nil {
+		return nil, err
+	}
+	// Callers own the returned slice; keep the cached one intact.
+	return slices.Clone(subs), nil
+}
+
+func (c *DictionaryClient) fetchSubstitutes(ctx context.Context, ingredientID string) ([]IngredientSubstitute, error) {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
@@ -77,9 +177,9 @@ func (c *DictionaryClient) GetSubstitutes(ctx context.Context, ingredientID stri
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.breaker.do(c.http, req)
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -91,8 +191,168 @@ func (c *DictionaryClient) GetSubstitutes(ctx context.Context, ingredientID stri
 	}
 
 	var subs []IngredientSubstitute
-	if err := json.NewDecoder(resp.Body).Decode(&subs); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+	if err := decodeJSON(resp.Body, &subs, c.maxResponseBytes); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// ErrSubstitutesBatchUnsupported is returned by GetSubstitutesBatch when the
+// dictionary does not serve the batch endpoint (404/405); callers fall back
+// to GetSubstitutes per ID.
+var ErrSubstitutesBatchUnsupported = errors.New("substitutes batch endpoint not available")
+
+// substitutesBatchRequest is the body of POST /ingredients/substitutes/batch.
+type substitutesBatchRequest struct {
+	IngredientIDs []string `json:"ingredient_ids"`
+}
+
+// GetSubstitutesBatch fetches substitutes for many ingredient IDs in one
+// request, keyed by ingredient ID; IDs without substitutes may be absent.
+// With [WithSubstituteCacheTTL] set, cached IDs are served from the
+// substitute cache and only the rest are requested, and the lists fetched are
+// cached per ID for GetSubstitutes as well. When the batch endpoint is not
+// available (404/405) it returns the cached lists together with
+// [ErrSubstitutesBatchUnsupported], and skips the batch request for the
+// substitute cache TTL.
+func (c *DictionaryClient) GetSubstitutesBatch(
+	ctx context.Context,
+	ids []string,
+) (map[string][]IngredientSubstitute, error) {
+	out := make(map[string][]IngredientSubstitute, len(ids))
+	pending := ids
+	if c.subs != nil {
+		pending = make([]string, 0, len(ids))
+		for _, id := range ids {
+			if subs, ok := c.subs.lookup(id); ok {
+				out[id] = slices.Clone(subs)
+			} else {
+				pending = append(pending, id)
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return out, nil
+	}
+	if c.batchUnsupported() {
+		return out, ErrSubstitutesBatchUnsupported
+	}
+
+	fetched, err := c.fetchSubstitutesBatch(ctx, pending)
+	if errors.Is(err, ErrSubstitutesBatchUnsupported) {
+		c.markBatchUnsupported()
+		return out, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range pending {
+		subs := fetched[id]
+		if subs == nil {
+			subs = []IngredientSubstitute{}
+		}
+		if c.subs != nil {
+			c.subs.set(id, subs)
+		}
+		out[id] = slices.Clone(subs)
+	}
+	return out, nil
+}
+
+func (c *DictionaryClient) fetchSubstitutesBatch(
+	ctx context.Context,
+	ids []string,
+) (map[string][]IngredientSubstitute, error) {
+	body, err := json.Marshal(substitutesBatchRequest{IngredientIDs: ids})
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		c.baseURL+"/ingredients/substitutes/batch",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.breaker.do(c.http, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, ErrSubstitutesBatchUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dictionary service returned %d", resp.StatusCode)
+	}
+
+	var subs map[string][]IngredientSubstitute
+	if err := decodeJSON(resp.Body, &subs, c.maxResponseBytes); err != nil {
+		return nil, err
 	}
 	return subs, nil
 }
+
+// batchUnsupported reports whether the batch endpoint answered 404/405 within
+// the substitute cache TTL. Without the substitute cache it is always retried.
+func (c *DictionaryClient) batchUnsupported() bool {
+	if c.subs == nil {
+		return false
+	}
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+	return !c.batchUnsupportedAt.IsZero() && c.subs.clock().Sub(c.batchUnsupportedAt) < c.subs.ttl
+}
+
+func (c *DictionaryClient) markBatchUnsupported() {
+	if c.subs == nil {
+		return
+	}
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+	c.batchUnsupportedAt = c.subs.clock()
+}
+
+// GetSubstitutionGroup fetches the substitution group the given ingredient
+// belongs to. Returns nil without error if the ingredient has no group or the
+// endpoint is not yet available (404/405).
+func (c *DictionaryClient) GetSubstitutionGroup(ctx context.Context, ingredientID string) (*SubstitutionGroup, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		c.baseURL+"/ingredients/"+ingredientID+"/group",
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.breaker.do(c.http, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, nil //nolint:nilnil // absent group is not an error
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dictionary service returned %d", resp.StatusCode)
+	}
+
+	var group SubstitutionGroup
+	if err := decodeJSON(resp.Body, &group, c.maxResponseBytes); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// Ping checks that the Ingredient Dictionary is reachable by calling its GET /healthz.
+func (c *DictionaryClient) Ping(ctx context.Context) error {
+	return ping(ctx, c.http, c.baseURL)
+}
@@ -2,9 +2,14 @@ package clients
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -115,3 +120,385 @@ func TestGetSubstitutes_ServerError(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "500")
 }
+
+func TestGetSubstitutionGroup_Success(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ingredients/spinach/group", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"g1","name":"leafy greens","member_ids":["spinach","kale","chard"]}`))
+	}))
+	defer server.Close()
+
+	client := &DictionaryClient{baseURL: server.URL, http: server.Client()}
+	group, err := client.GetSubstitutionGroup(context.Background(), "spinach")
+
+	require.NoError(t, err)
+	require.NotNil(t, group)
+	assert.Equal(t, "leafy greens", group.Name)
+	assert.Equal(t, []string{"spinach", "kale", "chard"}, group.MemberIDs)
+}
+
+func TestGetSubstitutionGroup_Unavailable(t *testing.T) {
+	t.Parallel()
+	for _, status := range []int{http.StatusNotFound, http.StatusMethodNotAllowed} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+
+		client := &DictionaryClient{baseURL: server.URL, http: server.Client()}
+		group, err := client.GetSubstitutionGroup(context.Background(), "abc")
+
+		require.NoError(t, err)
+		assert.Nil(t, group)
+		server.Close()
+	}
+}
+
+func TestGetSubstitutionGroup_ServerError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := &DictionaryClient{baseURL: server.URL, http: server.Client()}
+	_, err := client.GetSubstitutionGroup(context.Background(), "abc")
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "500")
+}
+
+func TestGetSubstitutes_ResponseTooLarge(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[` + strings.Repeat(`{"ingredient_id":"a","substitute_id":"b","ratio":1},`, 100) + `{}]`))
+	}))
+	defer server.Close()
+
+	client := &DictionaryClient{baseURL: server.URL, http: server.Client(), maxResponseBytes: 256}
+	_, err := client.GetSubstitutes(context.Background(), "a")
+
+	require.ErrorIs(t, err, ErrResponseTooLarge)
+}
+
+func TestNewDictionaryClient_SendsToken(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer dictionary-secret", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/ingredients/ing1":
+			w.Write([]byte(`{"id":"ing1","name":"garlic"}`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL, WithToken("dictionary-secret"))
+	_, err := client.GetIngredient(context.Background(), "ing1")
+	require.NoError(t, err)
+	_, err = client.GetSubstitutes(context.Background(), "ing1")
+	require.NoError(t, err)
+}
+
+func TestGetIngredient_NameCacheHit(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"ID":"abc-123","Name":"garlic"}`))
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL, WithNameCacheTTL(time.Minute))
+	_, err := client.GetIngredient(context.Background(), "abc-123")
+	require.NoError(t, err)
+	detail, err := client.GetIngredient(context.Background(), "abc-123")
+
+	require.NoError(t, err)
+	assert.Equal(t, "garlic", detail.Name)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestGetIngredient_NameCacheExpires(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"ID":"abc-123","Name":"garlic"}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := NewDictionaryClient(server.URL, WithNameCacheTTL(time.Minute))
+	client.names.now = func() time.Time { return now }
+	_, err := client.GetIngredient(context.Background(), "abc-123")
+	require.NoError(t, err)
+	now = now.Add(2 * time.Minute)
+	_, err = client.GetIngredient(context.Background(), "abc-123")
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestGetIngredient_NameCacheSkipsErrors(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL, WithNameCacheTTL(time.Minute))
+	_, err := client.GetIngredient(context.Background(), "missing")
+	require.ErrorIs(t, err, ErrIngredientNotFound)
+	_, err = client.GetIngredient(context.Background(), "missing")
+
+	require.ErrorIs(t, err, ErrIngredientNotFound)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestGetIngredient_NameCacheDedupesConcurrentFills(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Write([]byte(`{"ID":"abc-123","Name":"garlic"}`))
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL, WithNameCacheTTL(time.Minute))
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			detail, err := client.GetIngredient(context.Background(), "abc-123")
+			assert.NoError(t, err)
+			assert.Equal(t, "garlic", detail.Name)
+		})
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDictionaryClient_ClearNameCache(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"ID":"abc-123","Name":"garlic"}`))
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL, WithNameCacheTTL(time.Minute))
+	_, err := client.GetIngredient(context.Background(), "abc-123")
+	require.NoError(t, err)
+	client.ClearNameCache()
+	_, err = client.GetIngredient(context.Background(), "abc-123")
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestGetSubstitutes_CacheReusesList(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`[{"ingredient_id":"butter","substitute_id":"oil","ratio":0.8}]`))
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL, WithSubstituteCacheTTL(time.Minute))
+	first, err := client.GetSubstitutes(context.Background(), "butter")
+	require.NoError(t, err)
+	first[0].SubstituteID = "mutated"
+	second, err := client.GetSubstitutes(context.Background(), "butter")
+
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.Equal(t, "oil", second[0].SubstituteID)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1}, client.SubstituteCacheStats())
+}
+
+func TestGetSubstitutes_CachesMissingEndpointPerTTL(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	client := NewDictionaryClient(server.URL, WithSubstituteCacheTTL(time.Minute))
+	client.subs.now = func() time.Time { return now }
+	for range 3 {
+		subs, err := client.GetSubstitutes(context.Background(), "butter")
+		require.NoError(t, err)
+		assert.NotNil(t, subs)
+		assert.Empty(t, subs)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+
+	now = now.Add(2 * time.Minute)
+	_, err := client.GetSubstitutes(context.Background(), "butter")
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 2}, client.SubstituteCacheStats())
+}
+
+func TestGetSubstitutes_CacheSkipsErrors(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL, WithSubstituteCacheTTL(time.Minute))
+	_, err := client.GetSubstitutes(context.Background(), "butter")
+	require.Error(t, err)
+	_, err = client.GetSubstitutes(context.Background(), "butter")
+
+	require.Error(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestDictionaryClient_CacheStatsDisabled(t *testing.T) {
+	t.Parallel()
+	client := NewDictionaryClient("http://unused")
+
+	assert.Equal(t, CacheStats{}, client.NameCacheStats())
+	assert.Equal(t, CacheStats{}, client.SubstituteCacheStats())
+}
+
+func TestGetSubstitutesBatch_Available(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/ingredients/substitutes/batch", r.URL.Path)
+		var body struct {
+			IngredientIDs []string `json:"ingredient_ids"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, []string{"butter", "milk"}, body.IngredientIDs)
+		w.Write([]byte(`{"butter":[{"ingredient_id":"butter","substitute_id":"oil","ratio":0.8}],"milk":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL)
+	subs, err := client.GetSubstitutesBatch(context.Background(), []string{"butter", "milk"})
+
+	require.NoError(t, err)
+	require.Len(t, subs["butter"], 1)
+	assert.Equal(t, "oil", subs["butter"][0].SubstituteID)
+	assert.Empty(t, subs["milk"])
+}
+
+func TestGetSubstitutesBatch_Unsupported(t *testing.T) {
+	t.Parallel()
+	for _, status := range []int{http.StatusNotFound, http.StatusMethodNotAllowed} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			t.Parallel()
+			var posts, gets atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					posts.Add(1)
+					w.WriteHeader(status)
+					return
+				}
+				gets.Add(1)
+				w.Write([]byte(`[{"ingredient_id":"butter","substitute_id":"oil","ratio":0.8}]`))
+			}))
+			defer server.Close()
+
+			client := NewDictionaryClient(server.URL, WithSubstituteCacheTTL(time.Minute))
+			now := time.Now()
+			client.subs.now = func() time.Time { return now }
+			_, err := client.GetSubstitutes(context.Background(), "butter")
+			require.NoError(t, err)
+
+			// Cached IDs are still served; the caller fetches the rest per ID.
+			subs, err := client.GetSubstitutesBatch(context.Background(), []string{"butter", "milk"})
+			require.ErrorIs(t, err, ErrSubstitutesBatchUnsupported)
+			require.Len(t, subs["butter"], 1)
+			assert.NotContains(t, subs, "milk")
+
+			// The unsupported endpoint is remembered for the cache TTL.
+			_, err = client.GetSubstitutesBatch(context.Background(), []string{"milk"})
+			require.ErrorIs(t, err, ErrSubstitutesBatchUnsupported)
+			assert.Equal(t, int32(1), posts.Load())
+
+			now = now.Add(2 * time.Minute)
+			_, err = client.GetSubstitutesBatch(context.Background(), []string{"milk"})
+			require.ErrorIs(t, err, ErrSubstitutesBatchUnsupported)
+			assert.Equal(t, int32(2), posts.Load())
+			assert.Equal(t, int32(1), gets.Load())
+		})
+	}
+}
+
+func TestGetSubstitutesBatch_UsesSubstituteCache(t *testing.T) {
+	t.Parallel()
+	var posted [][]string
+	var gets atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			gets.Add(1)
+			w.Write([]byte(`[]`))
+			return
+		}
+		var body struct {
+			IngredientIDs []string `json:"ingredient_ids"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		posted = append(posted, body.IngredientIDs)
+		w.Write([]byte(`{"butter":[{"ingredient_id":"butter","substitute_id":"oil","ratio":0.8}]}`))
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL, WithSubstituteCacheTTL(time.Minute))
+	_, err := client.GetSubstitutes(context.Background(), "eggs")
+	require.NoError(t, err)
+
+	subs, err := client.GetSubstitutesBatch(context.Background(), []string{"butter", "eggs", "milk"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"butter", "milk"}}, posted)
+	require.Len(t, subs["butter"], 1)
+	assert.Empty(t, subs["milk"])
+
+	// The batch filled the per-ID cache, absent IDs included.
+	subs, err = client.GetSubstitutesBatch(context.Background(), []string{"butter", "milk"})
+	require.NoError(t, err)
+	require.Len(t, subs["butter"], 1)
+	butter, err := client.GetSubstitutes(context.Background(), "butter")
+	require.NoError(t, err)
+	assert.Equal(t, "oil", butter[0].SubstituteID)
+	assert.Len(t, posted, 1)
+	assert.Equal(t, int32(1), gets.Load())
+	assert.Equal(t, CacheStats{Hits: 4, Misses: 3}, client.SubstituteCacheStats())
+}
+
+func TestGetSubstitutesBatch_ServerError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewDictionaryClient(server.URL)
+	_, err := client.GetSubstitutesBatch(context.Background(), []string{"butter"})
+
+	require.Error(t, err)
+}
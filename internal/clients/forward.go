@@ -0,0 +1,53 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+)
+
+// ForwardedHeaders are the correlation and trace headers upstream requests
+// carry over from the incoming request, so a trace spans every service.
+var ForwardedHeaders = []string{"X-Request-ID", "traceparent", "tracestate"}
+
+type forwardedKey struct{}
+
+// WithForwardedHeaders returns a copy of ctx carrying the [ForwardedHeaders]
+// present in h. Every client request made with the returned context sends
+// them upstream.
+func WithForwardedHeaders(ctx context.Context, h http.Header) context.Context {
+	fwd := make(http.Header, len(ForwardedHeaders))
+	for _, name := range ForwardedHeaders {
+		if v := h.Get(name); v != "" {
+			fwd.Set(name, v)
+		}
+	}
+	if len(fwd) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, forwardedKey{}, fwd)
+}
+
+// ForwardedHeadersFrom returns the headers stored by [WithForwardedHeaders],
+// or nil if there are none.
+func ForwardedHeadersFrom(ctx context.Context) http.Header {
+	h, _ := ctx.Value(forwardedKey{}).(http.Header)
+	return h
+}
+
+// forwardTransport sets the request context's forwarded headers on each
+// request.
+type forwardTransport struct {
+	base http.RoundTripper
+}
+
+func (t *forwardTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fwd := ForwardedHeadersFrom(req.Context())
+	if len(fwd) == 0 {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	for name, values := range fwd {
+		req.Header[name] = values
+	}
+	return t.base.RoundTrip(req)
+}
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"sync"
+	"time"
 )
 
 type PantryItem struct {
@@ -12,38 +15,203 @@ type PantryItem struct {
 	IngredientID string  `json:"ingredient_id"`
 	Quantity     float64 `json:"quantity"`
 	Unit         string  `json:"unit"`
+	// QuantityReported records that the pantry service sent a quantity, so
+	// an explicit 0 (used up) can be told apart from no quantity at all.
+	QuantityReported bool `json:"-"`
 }
 
+// UnmarshalJSON decodes an item, setting QuantityReported when the JSON
+// carries a non-null quantity.
+func (p *PantryItem) UnmarshalJSON(data []byte) error {
+	type plain PantryItem
+	var raw struct {
+		plain
+		Quantity *float64 `json:"quantity"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*p = PantryItem(raw.plain)
+	if raw.Quantity != nil {
+		p.Quantity = *raw.Quantity
+		p.QuantityReported = true
+	}
+	return nil
+}
+
+// HasQuantity reports whether the item carries a quantity: one was sent, or
+// it is non-zero.
+func (p PantryItem) HasQuantity() bool {
+	return p.QuantityReported || p.Quantity != 0
+}
+
+// PantryFetchStrategy trades pantry freshness against upstream latency.
+type PantryFetchStrategy string
+
+const (
+	// PantryFetchFresh fetches the full pantry on every call (default).
+	PantryFetchFresh PantryFetchStrategy = "fresh"
+	// PantryFetchShortCache reuses the last pantry for a short TTL.
+	PantryFetchShortCache PantryFetchStrategy = "short-cache"
+	// PantryFetchConditional revalidates the last pantry with
+	// If-Modified-Since and reuses it when the service answers 304.
+	PantryFetchConditional PantryFetchStrategy = "conditional"
+)
+
+// DefaultPantryCacheTTL is how long [PantryFetchShortCache] reuses a pantry
+// when no TTL is configured.
+const DefaultPantryCacheTTL = 5 * time.Second
+
 type PantryClient struct {
 	baseURL string
 	http    *http.Client
+
+	maxResponseBytes int64
+	strategy         PantryFetchStrategy
+	cacheTTL         time.Duration
+	now              func() time.Time
+
+	// fills deduplicates concurrent fetches under the caching strategies
+	// and, for short-cache, holds the pantry for cacheTTL.
+	fills *ttlCache[[]PantryItem]
+
+	// mu guards the pantry last seen by the conditional strategy.
+	mu           sync.Mutex
+	cached       []PantryItem
+	haveCached   bool
+	lastModified string
 }
 
-func NewPantryClient(baseURL string) *PantryClient {
-	return &PantryClient{baseURL: baseURL, http: &http.Client{}}
+func NewPantryClient(baseURL string, opts ...ClientOption) *PantryClient {
+	cfg := newClientConfig(opts)
+	c := &PantryClient{
+		baseURL:          baseURL,
+		http:             newHTTPClient(cfg),
+		maxResponseBytes: cfg.maxResponseBytes,
+		strategy:         cfg.pantryStrategy,
+		cacheTTL:         cfg.pantryCacheTTL,
+	}
+	// Conditional fetches always revalidate, so their fills are never reused.
+	var ttl time.Duration
+	if c.strategy == PantryFetchShortCache {
+		ttl = c.cacheTTL
+		if ttl <= 0 {
+			ttl = DefaultPantryCacheTTL
+		}
+	}
+	c.fills = newTTLCache[[]PantryItem](ttl)
+	c.fills.now = c.clock
+	return c
 }
 
+// GetPantry returns the pantry items according to the client's fetch
+// strategy. Cached items are cloned so callers may modify the result.
 func (c *PantryClient) GetPantry(ctx context.Context) ([]PantryItem, error) {
+	switch c.strategy {
+	case PantryFetchShortCache:
+		return c.getCached(ctx)
+	case PantryFetchConditional:
+		return c.getConditional(ctx)
+	default:
+		items, _, _, err := c.fetch(ctx, "")
+		return items, err
+	}
+}
+
+// pantryCacheKey is the single key pantry fetches are cached under.
+const pantryCacheKey = "pantry"
+
+// getCached serves the pantry from fills for the cache TTL. Concurrent
+// callers share one upstream fetch, and an empty pantry is cached like any
+// other.
+func (c *PantryClient) getCached(ctx context.Context) ([]PantryItem, error) {
+	items, err := c.fills.get(ctx, pantryCacheKey, func(ctx context.Context, _ string) ([]PantryItem, error) {
+		items, _, _, err := c.fetch(ctx, "")
+		return items, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(items), nil
+}
+
+// getConditional revalidates the last pantry seen, sharing one upstream
+// request among concurrent callers.
+func (c *PantryClient) getConditional(ctx context.Context) ([]PantryItem, error) {
+	items, err := c.fills.get(ctx, pantryCacheKey, c.revalidate)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(items), nil
+}
+
+// revalidate fetches the pantry with If-Modified-Since set from the last
+// response, returning the last pantry when the service answers 304. The
+// mutex is not held across the request.
+func (c *PantryClient) revalidate(ctx context.Context, _ string) ([]PantryItem, error) {
+	c.mu.Lock()
+	var since string
+	if c.haveCached {
+		since = c.lastModified
+	}
+	c.mu.Unlock()
+
+	items, lastModified, notModified, err := c.fetch(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if notModified {
+		return c.cached, nil
+	}
+	c.cached, c.lastModified, c.haveCached = items, lastModified, true
+	return items, nil
+}
+
+func (c *PantryClient) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// fetch requests the pantry, sending If-Modified-Since when since is set.
+// It reports the response's Last-Modified header and whether the service
+// answered 304 Not Modified.
+func (c *PantryClient) fetch(ctx context.Context, since string) ([]PantryItem, string, bool, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/pantry", nil)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, "", false, fmt.Errorf("create request: %w", err)
+	}
+	if since != "" {
+		req.Header.Set("If-Modified-Since", since)
 	}
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
+		return nil, "", false, fmt.Errorf("do request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if since != "" && resp.StatusCode == http.StatusNotModified {
+		return nil, since, true, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("pantry service returned %d", resp.StatusCode)
+		return nil, "", false, fmt.Errorf("pantry service returned %d", resp.StatusCode)
 	}
 
 	var wrapper struct {
 		Items []PantryItem `json:"items"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&wrapper); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+	if err := decodeJSON(resp.Body, &wrapper, c.maxResponseBytes); err != nil {
+		return nil, "", false, err
 	}
-	return wrapper.Items, nil
+	return wrapper.Items, resp.Header.Get("Last-Modified"), false, nil
+}
+
+// Ping checks that the Pantry Service is reachable by calling its GET /healthz.
+func (c *PantryClient) Ping(ctx context.Context) error {
+	return ping(ctx, c.http, c.baseURL)
 }
@@ -2,9 +2,14 @@ package clients
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,6 +35,26 @@ func TestGetPantry_Success(t *testing.T) {
 	assert.Equal(t, "cup", items[0].Unit)
 }
 
+func TestPantryItem_QuantityReported(t *testing.T) {
+	t.Parallel()
+	var items []PantryItem
+	err := json.Unmarshal([]byte(`[
+		{"id":"p1","ingredient_id":"milk","quantity":0,"unit":"ml"},
+		{"id":"p2","ingredient_id":"salt"},
+		{"id":"p3","ingredient_id":"pepper","quantity":null},
+		{"id":"p4","ingredient_id":"eggs","quantity":6}
+	]`), &items)
+
+	require.NoError(t, err)
+	require.Len(t, items, 4)
+	assert.Equal(t, PantryItem{ID: "p1", IngredientID: "milk", Unit: "ml", QuantityReported: true}, items[0])
+	assert.True(t, items[0].HasQuantity())
+	assert.False(t, items[1].HasQuantity())
+	assert.False(t, items[2].HasQuantity())
+	assert.InDelta(t, 6.0, items[3].Quantity, 0.0001)
+	assert.True(t, items[3].HasQuantity())
+}
+
 func TestGetPantry_ServerError(t *testing.T) {
 	t.Parallel()
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -58,3 +83,174 @@ func TestGetPantry_InvalidJSON(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "decode")
 }
+
+func TestGetPantry_ResponseTooLarge(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"items":[` + strings.Repeat(`{"id":"p1","ingredient_id":"ing1"},`, 100) + `{}]}`))
+	}))
+	defer server.Close()
+
+	client := &PantryClient{baseURL: server.URL, http: server.Client(), maxResponseBytes: 256}
+	_, err := client.GetPantry(context.Background())
+
+	require.ErrorIs(t, err, ErrResponseTooLarge)
+}
+
+func TestNewPantryClient_SendsToken(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer pantry-secret", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewPantryClient(server.URL, WithToken("pantry-secret"))
+	_, err := client.GetPantry(context.Background())
+
+	require.NoError(t, err)
+}
+
+func TestNewPantryClient_NoTokenByDefault(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewPantryClient(server.URL)
+	_, err := client.GetPantry(context.Background())
+
+	require.NoError(t, err)
+}
+
+// countingPantryServer serves a pantry whose item count is the request number,
+// stamping responses with lastModified and honouring If-Modified-Since.
+func countingPantryServer(t *testing.T, lastModified string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if lastModified != "" && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if lastModified != "" {
+			w.Header().Set("Last-Modified", lastModified)
+		}
+		items := make([]string, 0, n)
+		for range n {
+			items = append(items, `{"id":"p","ingredient_id":"ing"}`)
+		}
+		w.Write([]byte(`{"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestGetPantry_FreshStrategy(t *testing.T) {
+	t.Parallel()
+	server, requests := countingPantryServer(t, "")
+
+	client := NewPantryClient(server.URL)
+	for range 3 {
+		_, err := client.GetPantry(context.Background())
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestGetPantry_ShortCacheStrategy(t *testing.T) {
+	t.Parallel()
+	server, requests := countingPantryServer(t, "")
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	client := NewPantryClient(server.URL, WithPantryFetchStrategy(PantryFetchShortCache, 10*time.Second))
+	client.now = func() time.Time { return now }
+
+	first, err := client.GetPantry(context.Background())
+	require.NoError(t, err)
+	now = now.Add(5 * time.Second)
+	cached, err := client.GetPantry(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), requests.Load(), "within TTL")
+	assert.Equal(t, first, cached)
+
+	now = now.Add(6 * time.Second)
+	refreshed, err := client.GetPantry(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load(), "after TTL")
+	assert.Len(t, refreshed, 2)
+}
+
+func TestGetPantry_ShortCacheKeepsEmptyPantry(t *testing.T) {
+	t.Parallel()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`{"items":null}`))
+	}))
+	defer server.Close()
+
+	client := NewPantryClient(server.URL, WithPantryFetchStrategy(PantryFetchShortCache, time.Minute))
+	for range 3 {
+		items, err := client.GetPantry(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, items)
+	}
+
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestGetPantry_CachingStrategiesDedupeConcurrentFetches(t *testing.T) {
+	t.Parallel()
+	for _, strategy := range []PantryFetchStrategy{PantryFetchShortCache, PantryFetchConditional} {
+		t.Run(string(strategy), func(t *testing.T) {
+			t.Parallel()
+			var requests atomic.Int32
+			release := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				<-release
+				w.Write([]byte(`{"items":[{"id":"p1","ingredient_id":"eggs"}]}`))
+			}))
+			defer server.Close()
+
+			client := NewPantryClient(server.URL, WithPantryFetchStrategy(strategy, time.Minute))
+			var wg sync.WaitGroup
+			for range 5 {
+				wg.Go(func() {
+					items, err := client.GetPantry(context.Background())
+					assert.NoError(t, err)
+					assert.Len(t, items, 1)
+				})
+			}
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			assert.Equal(t, int32(1), requests.Load())
+		})
+	}
+}
+
+func TestGetPantry_ConditionalStrategy(t *testing.T) {
+	t.Parallel()
+	lastModified := "Wed, 01 Jan 2026 12:00:00 GMT"
+	server, requests := countingPantryServer(t, lastModified)
+
+	client := NewPantryClient(server.URL, WithPantryFetchStrategy(PantryFetchConditional, 0))
+
+	first, err := client.GetPantry(context.Background())
+	require.NoError(t, err)
+	again, err := client.GetPantry(context.Background())
+	require.NoError(t, err)
+
+	// Every call revalidates; a 304 reuses the last pantry.
+	assert.Equal(t, int32(2), requests.Load())
+	require.Len(t, first, 1)
+	assert.Equal(t, first, again)
+}
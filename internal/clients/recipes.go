@@ -2,7 +2,6 @@ package clients
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 )
@@ -13,24 +12,48 @@ type RecipeIngredient struct {
 	Quantity     float64 `json:"quantity"`
 	Unit         string  `json:"unit"`
 	IsOptional   bool    `json:"is_optional"`
+	// Weight is the ingredient's importance to coverage. Zero or absent
+	// weighs as 1.
+	Weight float64 `json:"weight,omitempty"`
+	// Substitutes are swaps this recipe accepts for the ingredient, tried
+	// before the dictionary's substitutes. IngredientID may be omitted.
+	Substitutes []IngredientSubstitute `json:"substitutes,omitempty"`
+	// Name is filled in from the Ingredient Dictionary by the matching
+	// service on request; the recipe service does not send it.
+	Name string `json:"name,omitempty"`
+}
+
+// Nutrition is optional per-serving nutrition data for a recipe.
+type Nutrition struct {
+	Calories     float64 `json:"calories"`
+	ProteinGrams float64 `json:"protein_g"`
+	CarbsGrams   float64 `json:"carbs_g"`
+	FatGrams     float64 `json:"fat_g"`
 }
 
 type Recipe struct {
-	ID          string             `json:"id"`
-	Title       string             `json:"title"`
-	Tags        []string           `json:"tags"`
-	PrepMinutes int                `json:"prep_minutes"`
-	CookMinutes int                `json:"cook_minutes"`
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Tags        []string   `json:"tags"`
+	PrepMinutes int        `json:"prep_minutes"`
+	CookMinutes int        `json:"cook_minutes"`
+	Nutrition   *Nutrition `json:"nutrition,omitempty"`
+	// Servings is how many servings the ingredient quantities make; zero
+	// when the recipe does not say.
+	Servings    int                `json:"servings,omitempty"`
 	Ingredients []RecipeIngredient `json:"ingredients"`
 }
 
 type RecipeClient struct {
 	baseURL string
 	http    *http.Client
+
+	maxResponseBytes int64
 }
 
-func NewRecipeClient(baseURL string) *RecipeClient {
-	return &RecipeClient{baseURL: baseURL, http: &http.Client{}}
+func NewRecipeClient(baseURL string, opts ...ClientOption) *RecipeClient {
+	cfg := newClientConfig(opts)
+	return &RecipeClient{baseURL: baseURL, http: newHTTPClient(cfg), maxResponseBytes: cfg.maxResponseBytes}
 }
 
 func (c *RecipeClient) GetRecipes(ctx context.Context) ([]Recipe, error) {
@@ -50,8 +73,16 @@ func (c *RecipeClient) GetRecipes(ctx context.Context) ([]Recipe, error) {
 	}
 
 	var recipes []Recipe
-	if err := json.NewDecoder(resp.Body).Decode(&recipes); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+	if err := decodeJSON(resp.Body, &recipes, c.maxResponseBytes); err != nil {
+		return nil, err
+	}
+	if recipes == nil {
+		return nil, fmt.Errorf("recipe service: %w", ErrNullResponse)
 	}
 	return recipes, nil
 }
+
+// Ping checks that the Recipe Service is reachable by calling its GET /healthz.
+func (c *RecipeClient) Ping(ctx context.Context) error {
+	return ping(ctx, c.http, c.baseURL)
+}
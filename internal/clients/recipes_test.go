@@ -4,7 +4,9 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -49,6 +51,35 @@ func TestGetRecipes_ServerError(t *testing.T) {
 	assert.Contains(t, err.Error(), "500")
 }
 
+func TestGetRecipes_NullResponse(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`null`))
+	}))
+	defer server.Close()
+
+	client := &RecipeClient{baseURL: server.URL, http: server.Client()}
+	_, err := client.GetRecipes(context.Background())
+
+	require.ErrorIs(t, err, ErrNullResponse)
+}
+
+func TestGetRecipes_EmptyList(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := &RecipeClient{baseURL: server.URL, http: server.Client()}
+	recipes, err := client.GetRecipes(context.Background())
+
+	require.NoError(t, err)
+	assert.Empty(t, recipes)
+}
+
 func TestGetRecipes_InvalidJSON(t *testing.T) {
 	t.Parallel()
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -63,3 +94,73 @@ func TestGetRecipes_InvalidJSON(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "decode")
 }
+
+func TestGetRecipes_ResponseTooLarge(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[` + strings.Repeat(`{"id":"r1","title":"Pasta"},`, 100) + `{}]`))
+	}))
+	defer server.Close()
+
+	client := NewRecipeClient(server.URL, WithMaxResponseBytes(256))
+	_, err := client.GetRecipes(context.Background())
+
+	require.ErrorIs(t, err, ErrResponseTooLarge)
+	assert.Contains(t, err.Error(), "256 bytes")
+}
+
+func TestGetRecipes_WithinSizeLimit(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"r1","title":"Pasta"}]`))
+	}))
+	defer server.Close()
+
+	client := NewRecipeClient(server.URL, WithMaxResponseBytes(256))
+	recipes, err := client.GetRecipes(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, recipes, 1)
+}
+
+func TestNewRecipeClient_SendsToken(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer recipe-secret", r.Header.Get("Authorization"))
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewRecipeClient(server.URL, WithToken("recipe-secret"))
+	_, err := client.GetRecipes(context.Background())
+
+	require.NoError(t, err)
+}
+
+func TestNewRecipeClient_Timeout(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-time.After(time.Second):
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewRecipeClient(server.URL, WithTimeout(20*time.Millisecond))
+	_, err := client.GetRecipes(context.Background())
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNewRecipeClient_DefaultTimeout(t *testing.T) {
+	t.Parallel()
+
+	client := NewRecipeClient("http://recipes")
+	assert.Equal(t, DefaultTimeout, client.http.Timeout)
+}
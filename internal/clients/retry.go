@@ -0,0 +1,98 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// Default retry delays used when a RetryConfig leaves them unset.
+const (
+	DefaultRetryBaseDelay = 100 * time.Millisecond
+	DefaultRetryMaxDelay  = 2 * time.Second
+)
+
+// RetryConfig controls how clients retry idempotent GET requests that fail
+// with a connection error or a 5xx response. 4xx responses are never
+// retried.
+type RetryConfig struct {
+	// MaxRetries is how many times a failed request is retried. Zero
+	// disables retries.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles for each
+	// further retry, up to MaxDelay. Each delay is jittered down by up to
+	// half.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// WithRetry retries transient upstream failures as described by cfg.
+func WithRetry(cfg RetryConfig) ClientOption {
+	return func(c *clientConfig) {
+		c.retry = cfg
+	}
+}
+
+// backoff returns the jittered delay before retry number attempt (from 0).
+func (cfg RetryConfig) backoff(attempt int) time.Duration {
+	base := cfg.BaseDelay
+	if base <= 0 {
+		base = DefaultRetryBaseDelay
+	}
+	limit := cfg.MaxDelay
+	if limit <= 0 {
+		limit = DefaultRetryMaxDelay
+	}
+	d := base
+	for range attempt {
+		if d >= limit/2 {
+			d = limit
+			break
+		}
+		d *= 2
+	}
+	d = min(d, limit)
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryTransport retries GET requests on connection errors and 5xx
+// responses, honouring the request context between attempts.
+type retryTransport struct {
+	cfg  RetryConfig
+	base http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.cfg.MaxRetries || !retryable(req.Context(), resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body) //nolint:errcheck
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(t.cfg.backoff(attempt))
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
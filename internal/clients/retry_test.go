@@ -0,0 +1,96 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastRetry = RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestRetry_FailsTwiceThenSucceeds(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"id":"r1","title":"Soup"}]`))
+	}))
+	defer server.Close()
+
+	client := NewRecipeClient(server.URL, WithRetry(fastRetry))
+	recipes, err := client.GetRecipes(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, recipes, 1)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRetry_GivesUpAfterMaxRetries(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewRecipeClient(server.URL, WithRetry(fastRetry))
+	_, err := client.GetRecipes(context.Background())
+
+	require.Error(t, err)
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestRetry_ClientErrorFailsFast(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewRecipeClient(server.URL, WithRetry(fastRetry))
+	_, err := client.GetRecipes(context.Background())
+
+	require.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetry_ContextCancelStopsRetries(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewRecipeClient(server.URL, WithRetry(RetryConfig{MaxRetries: 5, BaseDelay: time.Second}))
+	_, err := client.GetRecipes(ctx)
+
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetryConfig_BackoffCapped(t *testing.T) {
+	t.Parallel()
+	cfg := RetryConfig{BaseDelay: 10 * time.Millisecond, MaxDelay: 40 * time.Millisecond}
+
+	for attempt := range 6 {
+		d := cfg.backoff(attempt)
+		assert.LessOrEqual(t, d, 40*time.Millisecond)
+		assert.GreaterOrEqual(t, d, 5*time.Millisecond)
+	}
+}
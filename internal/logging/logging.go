@@ -6,6 +6,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 const (
@@ -42,34 +44,54 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// Middleware is a chi-compatible HTTP request logger.
-// It skips /healthz to avoid Kubernetes probe noise.
+// Unwrap lets [http.ResponseController] reach the underlying writer, so
+// streaming handlers can flush through the logger.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Middleware is a chi-compatible HTTP request logger that logs successful
+// requests at info level. See [RequestLogger].
 func Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/healthz" {
-			next.ServeHTTP(w, r)
-			return
-		}
+	return RequestLogger(slog.LevelInfo)(next)
+}
+
+// RequestLogger returns a chi-compatible HTTP request logger. Each request is
+// logged with its method, path, status, duration and, when chi's
+// [middleware.RequestID] runs first, its request_id. Successful requests are
+// logged at level, 4xx responses at warn or above, and 5xx at error.
+// It skips /healthz to avoid Kubernetes probe noise.
+func RequestLogger(level slog.Level) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/healthz" {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-		start := time.Now()
-		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
-		next.ServeHTTP(rw, r)
-		duration := time.Since(start)
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			duration := time.Since(start)
 
-		attrs := []slog.Attr{
-			slog.String("method", r.Method),
-			slog.String("path", r.URL.Path),
-			slog.Int("status", rw.status),
-			slog.Duration("duration", duration),
-		}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rw.status),
+				slog.Duration("duration", duration),
+			}
+			if id := middleware.GetReqID(r.Context()); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
 
-		switch {
-		case rw.status >= statusErrorMin:
-			slog.LogAttrs(r.Context(), slog.LevelError, "request", attrs...)
-		case rw.status >= statusWarnMin:
-			slog.LogAttrs(r.Context(), slog.LevelWarn, "request", attrs...)
-		default:
-			slog.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
-		}
-	})
+			switch {
+			case rw.status >= statusErrorMin:
+				slog.LogAttrs(r.Context(), slog.LevelError, "request", attrs...)
+			case rw.status >= statusWarnMin:
+				slog.LogAttrs(r.Context(), max(level, slog.LevelWarn), "request", attrs...)
+			default:
+				slog.LogAttrs(r.Context(), level, "request", attrs...)
+			}
+		})
+	}
 }
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs routes the default slog logger to a buffer for the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func serve(t *testing.T, h http.Handler, req *http.Request) map[string]any {
+	t.Helper()
+	buf := captureLogs(t)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	return entry
+}
+
+func TestRequestLogger_Fields(t *testing.T) {
+	h := middleware.RequestID(RequestLogger(slog.LevelInfo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/matches?allow_subs=true", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+
+	entry := serve(t, h, req)
+
+	assert.Equal(t, "request", entry["msg"])
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, http.MethodGet, entry["method"])
+	assert.Equal(t, "/matches", entry["path"])
+	assert.InDelta(t, http.StatusCreated, entry["status"], 0)
+	assert.Contains(t, entry, "duration")
+	assert.Equal(t, "req-123", entry["request_id"])
+}
+
+func TestRequestLogger_Levels(t *testing.T) {
+	for name, tc := range map[string]struct {
+		level  slog.Level
+		status int
+		want   string
+	}{
+		"success at configured level": {level: slog.LevelDebug, status: http.StatusOK, want: "DEBUG"},
+		"4xx at least warn":           {level: slog.LevelDebug, status: http.StatusBadRequest, want: "WARN"},
+		"5xx at error":                {level: slog.LevelInfo, status: http.StatusBadGateway, want: "ERROR"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h := RequestLogger(tc.level)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+
+			entry := serve(t, h, httptest.NewRequest(http.MethodGet, "/matches", nil))
+
+			assert.Equal(t, tc.want, entry["level"])
+			assert.NotContains(t, entry, "request_id")
+		})
+	}
+}
+
+func TestRequestLogger_SkipsHealthz(t *testing.T) {
+	buf := captureLogs(t)
+	h := RequestLogger(slog.LevelInfo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Empty(t, buf.String())
+}
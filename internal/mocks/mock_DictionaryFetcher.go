@@ -140,6 +140,65 @@ func (_c *MockDictionaryFetcher_GetSubstitutes_Call) RunAndReturn(run func(conte
 	return _c
 }
 
+// GetSubstitutionGroup provides a mock function with given fields: ctx, ingredientID
+func (_m *MockDictionaryFetcher) GetSubstitutionGroup(ctx context.Context, ingredientID string) (*clients.SubstitutionGroup, error) {
+	ret := _m.Called(ctx, ingredientID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSubstitutionGroup")
+	}
+
+	var r0 *clients.SubstitutionGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*clients.SubstitutionGroup, error)); ok {
+		return rf(ctx, ingredientID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *clients.SubstitutionGroup); ok {
+		r0 = rf(ctx, ingredientID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*clients.SubstitutionGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, ingredientID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockDictionaryFetcher_GetSubstitutionGroup_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSubstitutionGroup'
+type MockDictionaryFetcher_GetSubstitutionGroup_Call struct {
+	*mock.Call
+}
+
+// GetSubstitutionGroup is a helper method to define mock.On call
+//   - ctx context.Context
+//   - ingredientID string
+func (_e *MockDictionaryFetcher_Expecter) GetSubstitutionGroup(ctx interface{}, ingredientID interface{}) *MockDictionaryFetcher_GetSubstitutionGroup_Call {
+	return &MockDictionaryFetcher_GetSubstitutionGroup_Call{Call: _e.mock.On("GetSubstitutionGroup", ctx, ingredientID)}
+}
+
+func (_c *MockDictionaryFetcher_GetSubstitutionGroup_Call) Run(run func(ctx context.Context, ingredientID string)) *MockDictionaryFetcher_GetSubstitutionGroup_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockDictionaryFetcher_GetSubstitutionGroup_Call) Return(_a0 *clients.SubstitutionGroup, _a1 error) *MockDictionaryFetcher_GetSubstitutionGroup_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockDictionaryFetcher_GetSubstitutionGroup_Call) RunAndReturn(run func(context.Context, string) (*clients.SubstitutionGroup, error)) *MockDictionaryFetcher_GetSubstitutionGroup_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockDictionaryFetcher creates a new instance of MockDictionaryFetcher. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockDictionaryFetcher(t interface {
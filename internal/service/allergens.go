@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// excludeAllergens drops recipes with any ingredient, optional ones
+// included, the dictionary tags with any of the excluded allergens, compared
+// case-insensitively. Each distinct ingredient is looked up once; the
+// dictionary client's name cache serves repeats across requests. The filter
+// fails closed: an ingredient whose lookup fails, or every ingredient once
+// the score budget is exhausted, counts as containing the allergen, and the
+// returned warning says so.
+func (s *Service) excludeAllergens(
+	ctx context.Context,
+	recipes []clients.Recipe,
+	excluded []string,
+) ([]clients.Recipe, []string, string) {
+	ids := make(map[string]bool)
+	for _, recipe := range recipes {
+		for _, ing := range recipe.Ingredients {
+			ids[ing.IngredientID] = true
+		}
+	}
+
+	var warning string
+	unsafe := make(map[string]bool)
+	if budgetRemains(ctx) {
+		var (
+			mu     sync.Mutex
+			failed int
+		)
+		s.fanOut(ctx, "allergens", ids, func(ctx context.Context, ingredientID string) {
+			detail, err := s.dictionary.GetIngredient(ctx, ingredientID)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil || detail == nil {
+				failed++
+				unsafe[ingredientID] = true
+				return
+			}
+			if hasAllergen(detail.Allergens, excluded) {
+				unsafe[ingredientID] = true
+			}
+		})
+		if failed > 0 {
+			warning = fmt.Sprintf("allergen lookup failed for %d ingredients; recipes using them were excluded", failed)
+		}
+	} else {
+		unsafe = ids
+		warning = "allergen lookup skipped: score budget exhausted; every recipe with ingredients was excluded"
+	}
+	if len(unsafe) == 0 {
+		return recipes, nil, warning
+	}
+
+	kept := make([]clients.Recipe, 0, len(recipes))
+	var dropped []string
+	for _, recipe := range recipes {
+		if slices.ContainsFunc(recipe.Ingredients, func(ing clients.RecipeIngredient) bool {
+			return unsafe[ing.IngredientID]
+		}) {
+			dropped = append(dropped, recipe.ID)
+			continue
+		}
+		kept = append(kept, recipe)
+	}
+	return kept, dropped, warning
+}
+
+// hasAllergen reports whether any of allergens is in excluded, ignoring case.
+func hasAllergen(allergens, excluded []string) bool {
+	for _, a := range allergens {
+		for _, e := range excluded {
+			if strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(e)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestScore_ExcludeAllergens(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "noodles"},
+		{ID: "p2", IngredientID: "peanut-butter"},
+		{ID: "p3", IngredientID: "soy"},
+		{ID: "p4", IngredientID: "peanuts"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "satay-noodles", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "noodles"}, {IngredientID: "peanut-butter"},
+		}},
+		{ID: "soy-noodles", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "noodles"}, {IngredientID: "soy"},
+		}},
+		{ID: "crunchy-noodles", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "noodles"}, {IngredientID: "soy"},
+			// Optional garnishes still end up on the plate.
+			{IngredientID: "peanuts", IsOptional: true},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "noodles").Return(
+		&clients.IngredientDetail{ID: "noodles", Allergens: []string{"gluten"}}, nil).Once()
+	dictMock.EXPECT().GetIngredient(mock.Anything, "peanut-butter").Return(
+		&clients.IngredientDetail{ID: "peanut-butter", Allergens: []string{"Peanut"}}, nil).Once()
+	dictMock.EXPECT().GetIngredient(mock.Anything, "soy").Return(
+		&clients.IngredientDetail{ID: "soy", Allergens: []string{"soy"}}, nil).Once()
+	dictMock.EXPECT().GetIngredient(mock.Anything, "peanuts").Return(
+		&clients.IngredientDetail{ID: "peanuts", Allergens: []string{"peanut"}}, nil).Once()
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{ExcludeAllergens: []string{"peanut"}})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "soy-noodles", res.Results[0].Recipe.ID)
+	assert.Empty(t, res.Warnings)
+}
+
+func TestScore_ExcludeAllergensLookupFailure(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "mystery", Quantity: 1},
+		{ID: "p2", IngredientID: "rice", Quantity: 1},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "mystery"}}},
+		{ID: "r2", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice"}}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "mystery").Return(nil, errors.New("dictionary down"))
+	dictMock.EXPECT().GetIngredient(mock.Anything, "rice").Return(&clients.IngredientDetail{ID: "rice"}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{ExcludeAllergens: []string{"peanut"}})
+	require.NoError(t, err)
+
+	// An ingredient that cannot be checked may contain the allergen.
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "r2", res.Results[0].Recipe.ID)
+	assert.Equal(t, []string{"allergen lookup failed for 1 ingredients; recipes using them were excluded"}, res.Warnings)
+}
+
+func TestScore_ExcludeAllergensBudgetExhausted(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "rice", Quantity: 1},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice"}}},
+	}, nil)
+	// No dictionary expectations: nothing can be looked up.
+
+	svc := New(pantryMock, recipeMock, dictMock, WithScoreBudget(bestEffortReserve/2))
+	res, err := svc.Score(context.Background(), ScoreOptions{ExcludeAllergens: []string{"peanut"}})
+	require.NoError(t, err)
+
+	assert.Empty(t, res.Results)
+	assert.Equal(t, []string{
+		"allergen lookup skipped: score budget exhausted; every recipe with ingredients was excluded",
+		"name resolution skipped: score budget exhausted",
+	}, res.Warnings)
+}
+
+func TestScore_NoAllergensNoLookups(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "noodles"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "noodles"}}},
+	}, nil)
+
+	// No dictionary expectations: blank allergens are ignored.
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{ExcludeAllergens: []string{" "}})
+	require.NoError(t, err)
+	assert.Len(t, res.Results, 1)
+}
+
+func TestHasAllergen(t *testing.T) {
+	t.Parallel()
+	assert.True(t, hasAllergen([]string{"gluten", "Peanut"}, []string{"peanut"}))
+	assert.True(t, hasAllergen([]string{" tree nut "}, []string{"Tree Nut"}))
+	assert.False(t, hasAllergen([]string{"gluten"}, []string{"peanut"}))
+	assert.False(t, hasAllergen(nil, []string{"peanut"}))
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// defaultBandMins are the lower bounds of the coverage bands used when a
+// request does not define its own.
+var defaultBandMins = []float64{100, 80, 50, 0}
+
+// DefaultBandSamples is the number of sample recipes listed per band.
+const DefaultBandSamples = 3
+
+// BandSample is a recipe listed as an example of a coverage band.
+type BandSample struct {
+	RecipeID    string  `json:"recipe_id"`
+	Title       string  `json:"title"`
+	CoveragePct float64 `json:"coverage_pct"`
+}
+
+// CoverageBand counts recipes whose coverage falls in [MinPct, MaxPct]. The
+// top band includes 100%; every other band excludes its MaxPct, which is the
+// next band's MinPct.
+type CoverageBand struct {
+	Label   string       `json:"label"`
+	MinPct  float64      `json:"min_pct"`
+	MaxPct  float64      `json:"max_pct"`
+	Count   int          `json:"count"`
+	Samples []BandSample `json:"samples"`
+}
+
+// CoverageBands groups the catalog into coverage bands.
+type CoverageBands struct {
+	TotalRecipes int            `json:"total_recipes"`
+	Bands        []CoverageBand `json:"bands"`
+}
+
+// Bands scores the whole catalog, including unmakeable recipes, and groups it
+// into bands starting at each of mins (0-100, in any order; nil uses
+// 100, 80, 50, and 0). Each recipe is
+// counted in the highest band it reaches; recipes below the lowest bound are
+// left out. Up to samples of the best-covered recipes are listed per band.
+func (s *Service) Bands(ctx context.Context, opts ScoreOptions, mins []float64, samples int) (*CoverageBands, error) {
+	opts.IncludeUnmakeable = true
+	opts.skipNames = true
+	opts.skipUsage = true
+	if len(mins) == 0 {
+		mins = defaultBandMins
+	}
+
+	res, err := s.scoreRaw(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	bands := buildCoverageBands(res.Results, mins, samples)
+	for i := range bands.Bands {
+		for j := range bands.Bands[i].Samples {
+			bands.Bands[i].Samples[j].CoveragePct = s.roundPct(bands.Bands[i].Samples[j].CoveragePct)
+		}
+	}
+	return bands, nil
+}
+
+// buildCoverageBands assigns results, already sorted by coverage descending,
+// to bands ordered from the highest lower bound down.
+func buildCoverageBands(results []MatchResult, mins []float64, samples int) *CoverageBands {
+	mins = slices.Clone(mins)
+	slices.Sort(mins)
+	slices.Reverse(mins)
+	mins = slices.Compact(mins)
+
+	bands := make([]CoverageBand, 0, len(mins))
+	high := coveragePercentScale
+	for _, low := range mins {
+		label := fmt.Sprintf("%.0f-%.0f%%", low, high)
+		if low == high {
+			label = fmt.Sprintf("%.0f%%", low)
+		}
+		bands = append(bands, CoverageBand{Label: label, MinPct: low, MaxPct: high, Samples: []BandSample{}})
+		high = low
+	}
+
+	for _, r := range results {
+		i := slices.IndexFunc(bands, func(b CoverageBand) bool { return r.CoveragePct >= b.MinPct })
+		if i < 0 {
+			continue
+		}
+		bands[i].Count++
+		if len(bands[i].Samples) < samples {
+			bands[i].Samples = append(bands[i].Samples, BandSample{
+				RecipeID:    r.Recipe.ID,
+				Title:       r.Recipe.Title,
+				CoveragePct: r.CoveragePct,
+			})
+		}
+	}
+
+	return &CoverageBands{TotalRecipes: len(results), Bands: bands}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func bandsCatalog() []clients.Recipe {
+	return []clients.Recipe{
+		recipeWithCoverage("full", 2, 2),    // 100%
+		recipeWithCoverage("full2", 1, 1),   // 100%
+		recipeWithCoverage("nearly", 9, 10), // 90%
+		recipeWithCoverage("most", 4, 5),    // 80%
+		recipeWithCoverage("half", 1, 2),    // 50%
+		recipeWithCoverage("third", 1, 3),   // 33.3%
+		recipeWithCoverage("zero", 0, 2),    // 0%
+	}
+}
+
+func TestBands_DefaultBands(t *testing.T) {
+	t.Parallel()
+
+	recipes := bandsCatalog()
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return(statsPantry(recipes), nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+
+	svc := New(pantryMock, recipeMock, nil)
+	bands, err := svc.Bands(context.Background(), ScoreOptions{}, nil, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, 7, bands.TotalRecipes)
+	require.Len(t, bands.Bands, 4)
+
+	counts := map[string]int{}
+	for _, b := range bands.Bands {
+		counts[b.Label] = b.Count
+		assert.LessOrEqual(t, len(b.Samples), 1)
+	}
+	assert.Equal(t, map[string]int{"100%": 2, "80-100%": 2, "50-80%": 1, "0-50%": 2}, counts)
+	assert.Equal(t, []BandSample{{RecipeID: "nearly", Title: "nearly", CoveragePct: 90}}, bands.Bands[1].Samples)
+}
+
+func TestBuildCoverageBands_CustomBounds(t *testing.T) {
+	t.Parallel()
+
+	results := []MatchResult{
+		{Recipe: clients.Recipe{ID: "a"}, CoveragePct: 100},
+		{Recipe: clients.Recipe{ID: "b"}, CoveragePct: 95},
+		{Recipe: clients.Recipe{ID: "c"}, CoveragePct: 75},
+		{Recipe: clients.Recipe{ID: "d"}, CoveragePct: 60},
+		{Recipe: clients.Recipe{ID: "e"}, CoveragePct: 10},
+	}
+
+	// Unordered and duplicated bounds are normalised; recipes under the
+	// lowest bound are not counted.
+	bands := buildCoverageBands(results, []float64{75, 90, 75}, 5)
+
+	require.Len(t, bands.Bands, 2)
+	assert.Equal(t, "90-100%", bands.Bands[0].Label)
+	assert.Equal(t, 2, bands.Bands[0].Count)
+	assert.Equal(t, "75-90%", bands.Bands[1].Label)
+	assert.Equal(t, 1, bands.Bands[1].Count)
+	assert.Equal(t, "c", bands.Bands[1].Samples[0].RecipeID)
+	assert.Equal(t, 5, bands.TotalRecipes)
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// ScoreBatch scores the live recipe catalog against each of pantries, inline
+// pantry snapshots that are not fetched from the pantry service, under the
+// same opts. The catalog is fetched once for the whole batch. Results are in
+// the order of pantries, ranked and rounded as [Service.Score] returns them;
+// usage is not counted.
+func (s *Service) ScoreBatch(
+	ctx context.Context,
+	pantries [][]clients.PantryItem,
+	opts ScoreOptions,
+) ([]*ScoreResult, error) {
+	if _, err := s.scorer(opts.Strategy); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+
+	recipes, warning, err := s.fetchRecipes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recipes, warnings := prepareRecipes(ctx, recipes, warning)
+
+	opts.skipUsage = true
+	results := make([]*ScoreResult, 0, len(pantries))
+	for _, pantry := range pantries {
+		res := s.scoreCatalog(ctx, pantry, recipes, opts, slices.Clone(warnings))
+		s.roundCoverage(res.Results)
+		results = append(results, res)
+	}
+	return results, nil
+}
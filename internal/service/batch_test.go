@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestScoreBatch(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "omelette", Ingredients: []clients.RecipeIngredient{{IngredientID: "eggs"}, {IngredientID: "butter"}}},
+		{ID: "toast", Ingredients: []clients.RecipeIngredient{{IngredientID: "bread"}, {IngredientID: "butter"}}},
+	}, nil).Once()
+
+	svc := New(pantryMock, recipeMock, nil)
+	results, err := svc.ScoreBatch(context.Background(), [][]clients.PantryItem{
+		{{IngredientID: "eggs", Quantity: 6}, {IngredientID: "butter", Quantity: 1}},
+		{{IngredientID: "bread", Quantity: 1}, {IngredientID: "butter", Quantity: 1}},
+		{},
+	}, ScoreOptions{skipNames: true})
+	require.NoError(t, err)
+
+	require.Len(t, results, 3)
+	ids := func(res *ScoreResult) []string {
+		out := []string{}
+		for _, r := range res.Results {
+			out = append(out, r.Recipe.ID)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"omelette"}, ids(results[0]))
+	assert.Equal(t, []string{"toast"}, ids(results[1]))
+	assert.Empty(t, ids(results[2]))
+	assert.Equal(t, 2, results[0].Meta.PantryItems)
+	pantryMock.AssertNotCalled(t, "GetPantry", mock.Anything)
+}
+
+func TestScoreBatch_RecipeFetchError(t *testing.T) {
+	t.Parallel()
+
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return(nil, errors.New("down"))
+
+	svc := New(mocks.NewMockPantryFetcher(t), recipeMock, nil)
+	_, err := svc.ScoreBatch(context.Background(), [][]clients.PantryItem{{}}, ScoreOptions{})
+	require.ErrorIs(t, err, ErrRecipesUnavailable)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// CatalogDiff compares makeability between the live recipe catalog and an
+// alternate one, scored against the same pantry.
+type CatalogDiff struct {
+	CurrentMakeable   int                 `json:"current_makeable"`
+	AlternateMakeable int                 `json:"alternate_makeable"`
+	Changes           []MakeabilityChange `json:"changes"`
+	Warnings          []string            `json:"-"`
+}
+
+// MakeabilityChange is a recipe whose CanMake differs between the catalogs.
+// A recipe missing from one catalog counts as not makeable there.
+type MakeabilityChange struct {
+	RecipeID         string `json:"recipe_id"`
+	Title            string `json:"title"`
+	InCurrent        bool   `json:"in_current"`
+	InAlternate      bool   `json:"in_alternate"`
+	CurrentCanMake   bool   `json:"current_can_make"`
+	AlternateCanMake bool   `json:"alternate_can_make"`
+}
+
+// DiffCatalog scores the live catalog and alternate against the live pantry
+// with the same options and reports the recipes whose makeability changed,
+// ordered by recipe ID. alternate is normalized like the live catalog, and
+// its warnings are reported prefixed with "alternate catalog: ". Names are not
+// resolved and usage is not counted.
+func (s *Service) DiffCatalog(ctx context.Context, opts ScoreOptions, alternate []clients.Recipe) (*CatalogDiff, error) {
+	if _, err := s.scorer(opts.Strategy); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+
+	pantryItems, recipes, warnings, err := s.fetchCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.IncludeUnmakeable = true
+	opts.SubstitutionSummary = false
+	opts.skipNames = true
+	opts.skipUsage = true
+
+	alternate, altWarnings := prepareRecipes(ctx, alternate, "")
+	current := s.scoreCatalog(ctx, pantryItems, recipes, opts, warnings)
+	alt := s.scoreCatalog(ctx, pantryItems, alternate, opts, altWarnings)
+
+	diff := &CatalogDiff{Changes: make([]MakeabilityChange, 0), Warnings: current.Warnings}
+	for _, w := range alt.Warnings {
+		diff.Warnings = append(diff.Warnings, "alternate catalog: "+w)
+	}
+	changes := make(map[string]*MakeabilityChange)
+	change := func(recipe clients.Recipe) *MakeabilityChange {
+		c, ok := changes[recipe.ID]
+		if !ok {
+			c = &MakeabilityChange{RecipeID: recipe.ID, Title: recipe.Title}
+			changes[recipe.ID] = c
+		}
+		return c
+	}
+	for _, r := range current.Results {
+		c := change(r.Recipe)
+		c.InCurrent = true
+		c.CurrentCanMake = r.CanMake
+		if r.CanMake {
+			diff.CurrentMakeable++
+		}
+	}
+	for _, r := range alt.Results {
+		c := change(r.Recipe)
+		c.InAlternate = true
+		c.AlternateCanMake = r.CanMake
+		c.Title = r.Recipe.Title
+		if r.CanMake {
+			diff.AlternateMakeable++
+		}
+	}
+
+	for _, c := range changes {
+		if c.CurrentCanMake != c.AlternateCanMake {
+			diff.Changes = append(diff.Changes, *c)
+		}
+	}
+	slices.SortFunc(diff.Changes, func(a, b MakeabilityChange) int {
+		return strings.Compare(a.RecipeID, b.RecipeID)
+	})
+	return diff, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestDiffCatalog(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour"}, {IngredientID: "milk"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "pancakes", Title: "Pancakes", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "milk"}, {IngredientID: "eggs"},
+		}},
+		{ID: "roux", Title: "Roux", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "milk"},
+		}},
+	}, nil)
+
+	alternate := []clients.Recipe{
+		// eggs became optional, so pancakes are now makeable.
+		{ID: "pancakes", Title: "Pancakes", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "milk"}, {IngredientID: "eggs", IsOptional: true},
+		}},
+		{ID: "roux", Title: "Roux", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "milk"},
+		}},
+	}
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	diff, err := svc.DiffCatalog(context.Background(), ScoreOptions{}, alternate)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, diff.CurrentMakeable)
+	assert.Equal(t, 2, diff.AlternateMakeable)
+	assert.Equal(t, []MakeabilityChange{{
+		RecipeID:         "pancakes",
+		Title:            "Pancakes",
+		InCurrent:        true,
+		InAlternate:      true,
+		CurrentCanMake:   false,
+		AlternateCanMake: true,
+	}}, diff.Changes)
+}
+
+func TestDiffCatalog_AddedAndRemovedRecipes(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "rice"}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "plain-rice", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice"}}},
+		{ID: "risotto", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice"}, {IngredientID: "stock"}}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	diff, err := svc.DiffCatalog(context.Background(), ScoreOptions{}, []clients.Recipe{
+		{ID: "fried-rice", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice"}}},
+	})
+	require.NoError(t, err)
+
+	// risotto was unmakeable in both, so it is not a change.
+	require.Len(t, diff.Changes, 2)
+	assert.Equal(t, "fried-rice", diff.Changes[0].RecipeID)
+	assert.False(t, diff.Changes[0].InCurrent)
+	assert.True(t, diff.Changes[0].AlternateCanMake)
+	assert.Equal(t, "plain-rice", diff.Changes[1].RecipeID)
+	assert.False(t, diff.Changes[1].InAlternate)
+	assert.True(t, diff.Changes[1].CurrentCanMake)
+}
+
+func TestDiffCatalog_NormalizesAlternate(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour", Quantity: 220, Unit: "g"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "bread", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour", Quantity: 400, Unit: "g"}}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	diff, err := svc.DiffCatalog(context.Background(), ScoreOptions{Strategy: StrategyQuantity}, []clients.Recipe{
+		// The repeated flour is collapsed into 250 g, more than the pantry holds,
+		// although each entry alone would be covered.
+		{ID: "bread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Quantity: 200, Unit: "g"},
+			{IngredientID: "flour", Quantity: 50, Unit: "g"},
+		}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, diff.CurrentMakeable)
+	assert.Equal(t, 0, diff.AlternateMakeable)
+	assert.Empty(t, diff.Changes)
+	assert.Equal(t, []string{"alternate catalog: collapsed duplicate ingredients in 1 recipes"}, diff.Warnings)
+}
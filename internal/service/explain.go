@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// ExplainMissing is the [ExplainedIngredient] status of an ingredient the
+// pantry does not satisfy. Satisfied ingredients report their match source,
+// [MatchSourceDirect] or [MatchSourceSubstitute].
+const ExplainMissing = "missing"
+
+// Explanation breaks one recipe's score down ingredient by ingredient.
+type Explanation struct {
+	RecipeID      string                `json:"recipe_id"`
+	Title         string                `json:"title"`
+	CoveragePct   float64               `json:"coverage_pct"`
+	CanMake       bool                  `json:"can_make"`
+	RequiredCount int                   `json:"required_count"`
+	MatchedCount  int                   `json:"matched_count"`
+	Ingredients   []ExplainedIngredient `json:"ingredients"`
+	Warnings      []string              `json:"-"`
+}
+
+// ExplainedIngredient is one required ingredient in recipe order, with the
+// running coverage after it is counted: MatchedSoFar of the recipe's
+// RequiredCount ingredients, CoverageSoFar as a percentage of the recipe's
+// total ingredient weight.
+type ExplainedIngredient struct {
+	IngredientID   string  `json:"ingredient_id"`
+	Name           string  `json:"name,omitempty"`
+	Quantity       float64 `json:"quantity"`
+	Unit           string  `json:"unit"`
+	Weight         float64 `json:"weight"`
+	Status         string  `json:"status"`
+	SubstituteID   string  `json:"substitute_id,omitempty"`
+	SubstituteName string  `json:"substitute_name,omitempty"`
+	Shortfall      float64 `json:"shortfall,omitempty"`
+	Unconvertible  bool    `json:"unconvertible,omitempty"`
+	MatchedSoFar   int     `json:"matched_so_far"`
+	CoverageSoFar  float64 `json:"coverage_so_far"`
+}
+
+// Explain scores a single catalog recipe under opts and explains the result.
+// Recipe filters such as tags, max_calories, min_coverage and allergens are
+// ignored so any recipe can be explained; usage is not counted. Returns [ErrRecipeNotFound] if the
+// recipe is not in the catalog.
+func (s *Service) Explain(ctx context.Context, recipeID string, opts ScoreOptions) (*Explanation, error) {
+	if _, err := s.scorer(opts.Strategy); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+
+	pantryItems, recipes, warnings, err := s.fetchCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var recipe *clients.Recipe
+	for i := range recipes {
+		if recipes[i].ID == recipeID {
+			recipe = &recipes[i]
+			break
+		}
+	}
+	if recipe == nil {
+		return nil, fmt.Errorf("%w: %s", ErrRecipeNotFound, recipeID)
+	}
+
+	opts.IncludeUnmakeable = true
+	opts.IncludeMatched = true
+	opts.SubstitutionSummary = false
+	opts.MealPlan = false
+	opts.MaxCalories = 0
+	opts.MinCoverage = 0
+	opts.MaxTotalMinutes = nil
+	opts.Tags = nil
+	opts.ExcludeAllergens = nil
+	opts.skipUsage = true
+
+	res := s.scoreCatalog(ctx, pantryItems, []clients.Recipe{*recipe}, opts, warnings)
+	if len(res.Results) == 0 {
+		// With the result filters reset only the empty-recipe policy can drop
+		// the recipe; explain it as empty.
+		return &Explanation{
+			RecipeID:    recipe.ID,
+			Title:       recipe.Title,
+			Ingredients: []ExplainedIngredient{},
+			Warnings:    res.Warnings,
+		}, nil
+	}
+	exp := explainResult(res.Results[0], opts, res.Warnings)
+	exp.CoveragePct = s.roundPct(exp.CoveragePct)
+	for i := range exp.Ingredients {
+		exp.Ingredients[i].CoverageSoFar = s.roundPct(exp.Ingredients[i].CoverageSoFar)
+	}
+	return exp, nil
+}
+
+// explainResult lists r's required ingredients in recipe order with how each
+// was satisfied and the running coverage.
+func explainResult(r MatchResult, opts ScoreOptions, warnings []string) *Explanation {
+	rules := scoreRules{optionalAsRequired: opts.TreatOptionalAsRequired}
+	matched := make(map[string]MatchedIngredient, len(r.MatchedIngredients))
+	for _, m := range r.MatchedIngredients {
+		matched[m.IngredientID] = m
+	}
+	missing := make(map[string]MissingIngredient, len(r.MissingIngredients))
+	for _, m := range r.MissingIngredients {
+		missing[m.IngredientID] = m
+	}
+
+	exp := &Explanation{
+		RecipeID:    r.Recipe.ID,
+		Title:       r.Recipe.Title,
+		CoveragePct: r.CoveragePct,
+		CanMake:     r.CanMake,
+		Ingredients: make([]ExplainedIngredient, 0, len(r.Recipe.Ingredients)),
+		Warnings:    warnings,
+	}
+	totalWeight, matchedWeight := 0.0, 0.0
+	for _, ing := range r.Recipe.Ingredients {
+		if rules.isRequired(ing) {
+			exp.RequiredCount++
+			totalWeight += ingredientWeight(ing)
+		}
+	}
+
+	for _, ing := range r.Recipe.Ingredients {
+		if !rules.isRequired(ing) {
+			continue
+		}
+		e := ExplainedIngredient{
+			IngredientID: ing.IngredientID,
+			Quantity:     ing.Quantity,
+			Unit:         ing.Unit,
+			Weight:       ingredientWeight(ing),
+		}
+		if m, ok := missing[ing.IngredientID]; ok {
+			e.Name = m.Name
+			e.Status = ExplainMissing
+			e.Shortfall = m.Shortfall
+			e.Unconvertible = m.Unconvertible
+		} else {
+			m := matched[ing.IngredientID]
+			exp.MatchedCount++
+			matchedWeight += e.Weight
+			e.Name = m.Name
+			e.Status = m.Source
+			e.SubstituteID = m.SubstituteID
+			e.SubstituteName = m.SubstituteName
+		}
+		e.MatchedSoFar = exp.MatchedCount
+		e.CoverageSoFar = matchedWeight / totalWeight * coveragePercentScale
+		exp.Ingredients = append(exp.Ingredients, e)
+	}
+	return exp
+}
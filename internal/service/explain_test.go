@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour"}, {IngredientID: "oil"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "other", Title: "Other", Tags: []string{"dessert"}},
+		{ID: "bread", Title: "Bread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Quantity: 500, Unit: "g"},
+			{IngredientID: "butter", Quantity: 50, Unit: "g"},
+			{IngredientID: "seeds", IsOptional: true},
+			{IngredientID: "yeast", Quantity: 7, Unit: "g"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, mock.Anything).RunAndReturn(
+		func(_ context.Context, id string) ([]clients.IngredientSubstitute, error) {
+			if id == "butter" {
+				return []clients.IngredientSubstitute{{IngredientID: "butter", SubstituteID: "oil", Ratio: 1}}, nil
+			}
+			return nil, nil
+		})
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).RunAndReturn(
+		func(_ context.Context, id string) (*clients.IngredientDetail, error) {
+			return &clients.IngredientDetail{ID: id, Name: id + " name"}, nil
+		})
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	exp, err := svc.Explain(context.Background(), "bread", ScoreOptions{AllowSubs: true, Tags: []string{"dessert"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "bread", exp.RecipeID)
+	assert.Equal(t, 3, exp.RequiredCount)
+	assert.Equal(t, 2, exp.MatchedCount)
+	assert.False(t, exp.CanMake)
+	assert.Equal(t, 66.7, exp.CoveragePct)
+
+	// Every required ingredient appears exactly once, in recipe order.
+	ids := make([]string, 0, len(exp.Ingredients))
+	for _, e := range exp.Ingredients {
+		ids = append(ids, e.IngredientID)
+	}
+	assert.Equal(t, []string{"flour", "butter", "yeast"}, ids)
+
+	flour, butter, yeast := exp.Ingredients[0], exp.Ingredients[1], exp.Ingredients[2]
+	assert.Equal(t, MatchSourceDirect, flour.Status)
+	assert.Equal(t, "flour name", flour.Name)
+	assert.Equal(t, 1, flour.MatchedSoFar)
+	assert.Equal(t, 33.3, flour.CoverageSoFar)
+
+	assert.Equal(t, MatchSourceSubstitute, butter.Status)
+	assert.Equal(t, "oil", butter.SubstituteID)
+	assert.Equal(t, "oil name", butter.SubstituteName)
+	assert.Equal(t, 2, butter.MatchedSoFar)
+
+	assert.Equal(t, ExplainMissing, yeast.Status)
+	assert.Equal(t, "yeast name", yeast.Name)
+	assert.Equal(t, 2, yeast.MatchedSoFar)
+	assert.InDelta(t, exp.CoveragePct, yeast.CoverageSoFar, 0.0001)
+}
+
+func TestExplain_IgnoresMinCoverage(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "flour"}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "bread", Title: "Bread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "yeast"}, {IngredientID: "salt"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(nil, clients.ErrIngredientNotFound).Maybe()
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	exp, err := svc.Explain(context.Background(), "bread", ScoreOptions{MinCoverage: 50})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, exp.RequiredCount)
+	assert.Equal(t, 1, exp.MatchedCount)
+	assert.Equal(t, 33.3, exp.CoveragePct)
+	require.Len(t, exp.Ingredients, 3)
+	assert.Equal(t, MatchSourceDirect, exp.Ingredients[0].Status)
+}
+
+func TestExplain_RecipeNotFound(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{{ID: "bread"}}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	_, err := svc.Explain(context.Background(), "cake", ScoreOptions{})
+	require.ErrorIs(t, err, ErrRecipeNotFound)
+}
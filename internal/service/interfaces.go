@@ -20,4 +20,14 @@ type RecipeFetcher interface {
 type DictionaryFetcher interface {
 	GetIngredient(ctx context.Context, id string) (*clients.IngredientDetail, error)
 	GetSubstitutes(ctx context.Context, ingredientID string) ([]clients.IngredientSubstitute, error)
+	GetSubstitutionGroup(ctx context.Context, ingredientID string) (*clients.SubstitutionGroup, error)
+}
+
+// SubstituteBatchFetcher is optionally implemented by a [DictionaryFetcher]
+// that can fetch substitutes for many ingredients in one round-trip. When
+// present, scoring prefers it over per-ingredient GetSubstitutes calls. On
+// error, the IDs missing from the returned map are retried one at a time
+// within the dictionary concurrency bound.
+type SubstituteBatchFetcher interface {
+	GetSubstitutesBatch(ctx context.Context, ids []string) (map[string][]clients.IngredientSubstitute, error)
 }
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+var ErrRecipeNotFound = errors.New("recipe not found")
+
+// MealResult reports whether a set of recipes can be cooked together from one
+// pantry, with required quantities drawn down as each recipe is allocated.
+type MealResult struct {
+	Recipes   []clients.Recipe    `json:"recipes"`
+	CanMake   bool                `json:"can_make"`
+	Shortfall []MissingIngredient `json:"shortfall"`
+	PrepOrder []MealStep          `json:"prep_order"`
+}
+
+// MealStep is one recipe in the suggested preparation order. StartAtMinute is
+// when to start it so that every recipe finishes together with the longest.
+type MealStep struct {
+	RecipeID      string `json:"recipe_id"`
+	Title         string `json:"title"`
+	TotalMinutes  int    `json:"total_minutes"`
+	StartAtMinute int    `json:"start_at_minute"`
+}
+
+// ScoreMeal checks whether the pantry can cover every required ingredient of
+// the given recipes at once. Recipes are allocated in the order given; each
+// consumes pantry quantity so later recipes see only what remains. The pantry
+// is read as under the quantity strategy: items count as present as for
+// [Service.Score], pantry staples are stocked in unlimited quantity, and
+// pantry quantities are converted to each recipe's unit, an unconvertible
+// ingredient being short its full quantity.
+// Returns [ErrRecipeNotFound] if any ID is not in the catalog.
+func (s *Service) ScoreMeal(ctx context.Context, recipeIDs []string) (*MealResult, error) {
+	pantryItems, recipes, warnings, err := s.fetchCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		slog.Default().WarnContext(ctx, w)
+	}
+
+	byID := make(map[string]clients.Recipe, len(recipes))
+	for _, recipe := range recipes {
+		byID[recipe.ID] = recipe
+	}
+
+	selected := make([]clients.Recipe, 0, len(recipeIDs))
+	for _, id := range recipeIDs {
+		recipe, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrRecipeNotFound, id)
+		}
+		selected = append(selected, recipe)
+	}
+
+	shortfall := allocateMeal(selected, scoreInput{
+		pantrySet: buildPantrySet(pantryItems, s.quantityFloor),
+		stock:     buildPantryStock(pantryItems),
+		staples:   s.staplesFor(ScoreOptions{}),
+	})
+
+	if len(shortfall) > 0 {
+		ids := make(map[string]bool, len(shortfall))
+		for _, m := range shortfall {
+			ids[m.IngredientID] = true
+		}
+		names := s.fetchNames(ctx, ids)
+		for i := range shortfall {
+			shortfall[i].Name = names[shortfall[i].IngredientID]
+		}
+	}
+
+	slog.Default().DebugContext(ctx, "meal scored", "recipes", len(selected), "shortfall", len(shortfall))
+
+	return &MealResult{
+		Recipes:   selected,
+		CanMake:   len(shortfall) == 0,
+		Shortfall: shortfall,
+		PrepOrder: prepOrder(selected),
+	}, nil
+}
+
+// prepOrder suggests what to start first: longest total time first, keeping
+// the requested order for ties. Recipes without times count as zero minutes.
+func prepOrder(recipes []clients.Recipe) []MealStep {
+	steps := make([]MealStep, 0, len(recipes))
+	for _, recipe := range recipes {
+		steps = append(steps, MealStep{
+			RecipeID:     recipe.ID,
+			Title:        recipe.Title,
+			TotalMinutes: totalMinutes(recipe),
+		})
+	}
+	slices.SortStableFunc(steps, func(a, b MealStep) int {
+		return cmp.Compare(b.TotalMinutes, a.TotalMinutes)
+	})
+	for i := range steps {
+		steps[i].StartAtMinute = steps[0].TotalMinutes - steps[i].TotalMinutes
+	}
+	return steps
+}
+
+// allocateMeal draws each recipe's required quantities from in.stock in
+// order and returns the combined shortfall, one entry per ingredient and unit.
+// An ingredient with no quantity only needs to be in in.pantrySet, and staples
+// are never short. in.stock is modified in place.
+func allocateMeal(recipes []clients.Recipe, in scoreInput) []MissingIngredient {
+	shortfall := make([]MissingIngredient, 0)
+	index := make(map[string]int)
+
+	for _, recipe := range recipes {
+		for _, ing := range recipe.Ingredients {
+			id := ing.IngredientID
+			if ing.IsOptional || in.staples[id] {
+				continue
+			}
+
+			short := ing.Quantity
+			unconvertible := false
+			switch {
+			case !in.pantrySet[id]:
+			case ing.Quantity <= 0:
+				continue
+			default:
+				have, err := stockIn(in.stock[id], ing.Unit)
+				if err != nil {
+					unconvertible = true
+					break
+				}
+				used := min(have, ing.Quantity)
+				drawStock(in.stock, id, used, ing.Unit)
+				short = ing.Quantity - used
+				if short <= 0 {
+					continue
+				}
+			}
+
+			key := id + "|" + ing.Unit
+			if i, ok := index[key]; ok {
+				shortfall[i].Quantity += short
+				shortfall[i].Unconvertible = shortfall[i].Unconvertible || unconvertible
+				continue
+			}
+			index[key] = len(shortfall)
+			shortfall = append(shortfall, MissingIngredient{
+				IngredientID:  id,
+				Quantity:      short,
+				Unit:          ing.Unit,
+				Unconvertible: unconvertible,
+			})
+		}
+	}
+
+	return shortfall
+}
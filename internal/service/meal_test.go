@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func mealRecipes() []clients.Recipe {
+	return []clients.Recipe{
+		{
+			ID:    "omelette",
+			Title: "Omelette",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri1", IngredientID: "eggs", Quantity: 2, Unit: "whole"},
+				{ID: "ri2", IngredientID: "salt", Unit: "pinch"},
+			},
+		},
+		{
+			ID:    "cake",
+			Title: "Cake",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri3", IngredientID: "eggs", Quantity: 2, Unit: "whole"},
+				{ID: "ri4", IngredientID: "flour", Quantity: 200, Unit: "g"},
+				{ID: "ri5", IngredientID: "vanilla", Quantity: 1, Unit: "tsp", IsOptional: true},
+			},
+		},
+	}
+}
+
+func TestAllocateMeal_IndividuallyMakeableButNotTogether(t *testing.T) {
+	t.Parallel()
+	recipes := mealRecipes()
+	pantry := []clients.PantryItem{
+		{IngredientID: "eggs", Quantity: 3, Unit: "whole"},
+		{IngredientID: "salt", Quantity: 1, Unit: "pinch"},
+		{IngredientID: "flour", Quantity: 500, Unit: "g"},
+	}
+
+	assert.Empty(t, allocateMeal(recipes[:1], mealInput(pantry)))
+	assert.Empty(t, allocateMeal(recipes[1:], mealInput(pantry)))
+
+	in := mealInput(pantry)
+	shortfall := allocateMeal(recipes, in)
+	require.Len(t, shortfall, 1)
+	assert.Equal(t, "eggs", shortfall[0].IngredientID)
+	assert.InDelta(t, 1.0, shortfall[0].Quantity, 0.0001)
+	assert.Equal(t, "whole", shortfall[0].Unit)
+	assert.InDelta(t, 0.0, in.stock["eggs"].Quantity, 0.0001)
+	assert.InDelta(t, 300.0, in.stock["flour"].Quantity, 0.0001)
+}
+
+// mealInput reads pantry the way ScoreMeal does with the default options.
+func mealInput(pantry []clients.PantryItem) scoreInput {
+	return scoreInput{
+		pantrySet: buildPantrySet(pantry, nil),
+		stock:     buildPantryStock(pantry),
+		staples:   map[string]bool{},
+	}
+}
+
+func TestAllocateMeal_CombinesShortfallAcrossRecipes(t *testing.T) {
+	t.Parallel()
+
+	shortfall := allocateMeal(mealRecipes(), mealInput([]clients.PantryItem{{IngredientID: "salt", Quantity: 1}}))
+
+	require.Len(t, shortfall, 2)
+	assert.Equal(t, "eggs", shortfall[0].IngredientID)
+	assert.InDelta(t, 4.0, shortfall[0].Quantity, 0.0001)
+	assert.Equal(t, "flour", shortfall[1].IngredientID)
+	assert.InDelta(t, 200.0, shortfall[1].Quantity, 0.0001)
+}
+
+func TestScoreMeal(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "eggs", Quantity: 3, Unit: "whole"},
+		{ID: "p2", IngredientID: "salt", Quantity: 1, Unit: "pinch"},
+		{ID: "p3", IngredientID: "flour", Quantity: 500, Unit: "g"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return(mealRecipes(), nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "eggs").Return(&clients.IngredientDetail{ID: "eggs", Name: "egg"}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	result, err := svc.ScoreMeal(context.Background(), []string{"omelette", "cake"})
+	require.NoError(t, err)
+
+	assert.False(t, result.CanMake)
+	require.Len(t, result.Recipes, 2)
+	require.Len(t, result.Shortfall, 1)
+	assert.Equal(t, "egg", result.Shortfall[0].Name)
+	assert.InDelta(t, 1.0, result.Shortfall[0].Quantity, 0.0001)
+	require.Len(t, result.PrepOrder, 2)
+}
+
+func TestScoreMeal_ReadsPantryLikeQuantityStrategy(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	// Flour is stocked in kilograms, eggs sit below the floor, salt is a
+	// staple, and the butter cannot be converted to grams.
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "eggs", Quantity: 0.5, Unit: "whole"},
+		{ID: "p2", IngredientID: "flour", Quantity: 1, Unit: "kg"},
+		{ID: "p3", IngredientID: "butter", Quantity: 1, Unit: "stick"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "cake", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Quantity: 200, Unit: "g"},
+			{IngredientID: "salt", Quantity: 1, Unit: "pinch"},
+			{IngredientID: "eggs", Quantity: 2, Unit: "whole"},
+		}},
+		{ID: "shortbread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Quantity: 200, Unit: "g"},
+			{IngredientID: "butter", Quantity: 100, Unit: "g"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(nil, clients.ErrIngredientNotFound).Maybe()
+
+	svc := New(pantryMock, recipeMock, dictMock, WithPantryQuantityFloor(0.5), WithPantryStaples([]string{"salt"}))
+	result, err := svc.ScoreMeal(context.Background(), []string{"cake", "shortbread"})
+	require.NoError(t, err)
+
+	require.Len(t, result.Shortfall, 2)
+	assert.Equal(t, "eggs", result.Shortfall[0].IngredientID)
+	assert.InDelta(t, 2.0, result.Shortfall[0].Quantity, 0.0001)
+	assert.Equal(t, "butter", result.Shortfall[1].IngredientID)
+	assert.InDelta(t, 100.0, result.Shortfall[1].Quantity, 0.0001)
+	assert.True(t, result.Shortfall[1].Unconvertible)
+}
+
+func TestPrepOrder(t *testing.T) {
+	t.Parallel()
+
+	order := prepOrder([]clients.Recipe{
+		{ID: "salad", Title: "Salad", PrepMinutes: 10},
+		{ID: "roast", Title: "Roast", PrepMinutes: 15, CookMinutes: 90},
+		{ID: "bread", Title: "Bread"},
+		{ID: "soup", Title: "Soup", PrepMinutes: 5, CookMinutes: 5},
+		{ID: "rice", Title: "Rice", CookMinutes: 20},
+	})
+
+	ids := make([]string, 0, len(order))
+	for _, step := range order {
+		ids = append(ids, step.RecipeID)
+	}
+	assert.Equal(t, []string{"roast", "rice", "salad", "soup", "bread"}, ids)
+	assert.Equal(t, MealStep{RecipeID: "roast", Title: "Roast", TotalMinutes: 105}, order[0])
+	assert.Equal(t, 85, order[1].StartAtMinute)
+	assert.Equal(t, 95, order[2].StartAtMinute)
+	assert.Equal(t, 95, order[3].StartAtMinute)
+	assert.Equal(t, 105, order[4].StartAtMinute)
+}
+
+func TestScoreMeal_UnknownRecipe(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return(mealRecipes(), nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	_, err := svc.ScoreMeal(context.Background(), []string{"omelette", "nope"})
+	require.ErrorIs(t, err, ErrRecipeNotFound)
+}
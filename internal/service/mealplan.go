@@ -0,0 +1,127 @@
+package service
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// PantryRemainder is the stock of one pantry ingredient left once meal
+// planning has allocated the ranked recipes.
+type PantryRemainder struct {
+	IngredientID string  `json:"ingredient_id"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit,omitempty"`
+}
+
+// planMeals walks results in rank order and rescores each recipe,
+// quantity-aware, against the stock the recipes ranked above it left behind.
+// A recipe that can still be made draws its required quantities, including
+// substitutes scaled by their ratio, from the remaining stock. It returns the
+// rescored results in the same order and the stock left at the end.
+func planMeals(
+	results []MatchResult,
+	in scoreInput,
+	rulesFor func(clients.Recipe) scoreRules,
+	subsFor func(clients.Recipe) map[string][]clients.IngredientSubstitute,
+) ([]MatchResult, []PantryRemainder) {
+	remaining := maps.Clone(in.stock)
+	in.stock = remaining
+
+	planned := make([]MatchResult, 0, len(results))
+	for _, r := range results {
+		in.rules = rulesFor(r.Recipe)
+		in.subsMap = subsFor(r.Recipe)
+		result := quantityScorer{}.score(r.Recipe, in)
+		if result.CanMake {
+			consumeRecipe(remaining, result, in.subsMap, in.rules)
+		}
+		planned = append(planned, result)
+	}
+	return planned, pantryRemainders(remaining)
+}
+
+// consumeRecipe draws the quantities result's recipe needs from remaining.
+// Missing ingredients and ingredients without a quantity draw nothing.
+func consumeRecipe(
+	remaining map[string]pantryStock,
+	result MatchResult,
+	subsMap map[string][]clients.IngredientSubstitute,
+	rules scoreRules,
+) {
+	missing := make(map[string]bool, len(result.MissingIngredients))
+	for _, m := range result.MissingIngredients {
+		missing[m.IngredientID] = true
+	}
+
+	for _, ing := range result.Recipe.Ingredients {
+		if !rules.isRequired(ing) || ing.Quantity <= 0 || missing[ing.IngredientID] {
+			continue
+		}
+		subID, ok := result.SubstitutedWith[ing.IngredientID]
+		if !ok {
+			drawStock(remaining, ing.IngredientID, ing.Quantity, ing.Unit)
+			continue
+		}
+		ratio := 1.0
+		for _, sub := range subsMap[ing.IngredientID] {
+			if sub.SubstituteID == subID && sub.Ratio > 0 {
+				ratio = sub.Ratio
+				break
+			}
+		}
+		drawStock(remaining, subID, ing.Quantity*ratio, ing.Unit)
+	}
+}
+
+// drawStock removes qty of unit from the stock of id, drawing on the entries
+// that convert to unit in order and never going below zero. Stock that
+// cannot be converted to unit is left untouched.
+func drawStock(remaining map[string]pantryStock, id string, qty float64, unit string) {
+	st, ok := remaining[id]
+	if !ok {
+		return
+	}
+	qty = st.draw(qty, unit)
+	for i := range st.incompatible {
+		qty = st.incompatible[i].draw(qty, unit)
+	}
+	remaining[id] = st
+}
+
+// draw removes up to qty of unit from s's own quantity, ignoring its
+// incompatible entries, and returns what is still to be drawn.
+func (s *pantryStock) draw(qty float64, unit string) float64 {
+	if qty <= 0 {
+		return 0
+	}
+	if s.Unit == "" || unit == "" {
+		used := min(s.Quantity, qty)
+		s.Quantity -= used
+		return qty - used
+	}
+	have, err := ConvertQuantity(s.Quantity, s.Unit, unit)
+	if err != nil || have <= 0 {
+		return qty
+	}
+	used := min(have, qty)
+	s.Quantity = max(s.Quantity-used*s.Quantity/have, 0)
+	return qty - used
+}
+
+// pantryRemainders lists remaining stock ordered by ingredient ID.
+func pantryRemainders(remaining map[string]pantryStock) []PantryRemainder {
+	out := make([]PantryRemainder, 0, len(remaining))
+	for id, st := range remaining {
+		out = append(out, PantryRemainder{IngredientID: id, Quantity: st.Quantity, Unit: st.Unit})
+		for _, other := range st.incompatible {
+			out = append(out, PantryRemainder{IngredientID: id, Quantity: other.Quantity, Unit: other.Unit})
+		}
+	}
+	slices.SortStableFunc(out, func(a, b PantryRemainder) int {
+		return strings.Compare(a.IngredientID, b.IngredientID)
+	})
+	return out
+}
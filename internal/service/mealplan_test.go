@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func scoreMealPlan(
+	t *testing.T,
+	pantry []clients.PantryItem,
+	recipes []clients.Recipe,
+	subs map[string][]clients.IngredientSubstitute,
+	opts ScoreOptions,
+) *ScoreResult {
+	t.Helper()
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return(pantry, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, mock.Anything).RunAndReturn(
+		func(_ context.Context, id string) ([]clients.IngredientSubstitute, error) {
+			return subs[id], nil
+		}).Maybe()
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).RunAndReturn(
+		func(_ context.Context, id string) (*clients.IngredientDetail, error) {
+			return &clients.IngredientDetail{ID: id, Name: id}, nil
+		}).Maybe()
+
+	opts.MealPlan = true
+	opts.IncludeUnmakeable = true
+	res, err := New(pantryMock, recipeMock, dictMock).Score(context.Background(), opts)
+	require.NoError(t, err)
+	return res
+}
+
+func TestScore_MealPlanDepletesInRankOrder(t *testing.T) {
+	t.Parallel()
+	res := scoreMealPlan(t,
+		[]clients.PantryItem{{IngredientID: "egg", Quantity: 3}, {IngredientID: "flour", Quantity: 500, Unit: "g"}},
+		[]clients.Recipe{
+			{ID: "r2-omelette", Title: "Omelette", Ingredients: []clients.RecipeIngredient{
+				{IngredientID: "egg", Quantity: 2},
+			}},
+			{ID: "r1-cake", Title: "Cake", Ingredients: []clients.RecipeIngredient{
+				{IngredientID: "egg", Quantity: 2}, {IngredientID: "flour", Quantity: 200, Unit: "g"},
+			}},
+		},
+		nil, ScoreOptions{},
+	)
+
+	require.Len(t, res.Results, 2)
+	// Both rank at 100%; the ID tiebreak puts the cake first, so it gets the eggs.
+	cake, omelette := res.Results[0], res.Results[1]
+	assert.Equal(t, "r1-cake", cake.Recipe.ID)
+	assert.True(t, cake.CanMake)
+	assert.False(t, omelette.CanMake)
+	require.Len(t, omelette.MissingIngredients, 1)
+	assert.Equal(t, "egg", omelette.MissingIngredients[0].IngredientID)
+	assert.InDelta(t, 1.0, omelette.MissingIngredients[0].Shortfall, 0.0001)
+
+	assert.Equal(t, []PantryRemainder{
+		{IngredientID: "egg", Quantity: 1},
+		{IngredientID: "flour", Quantity: 300, Unit: "g"},
+	}, res.RemainingPantry)
+}
+
+func TestScore_MealPlanRunsOutMidList(t *testing.T) {
+	t.Parallel()
+	latte := func(id string) clients.Recipe {
+		return clients.Recipe{ID: id, Title: id, Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "milk", Quantity: 400, Unit: "ml"},
+		}}
+	}
+	res := scoreMealPlan(t,
+		[]clients.PantryItem{{IngredientID: "milk", Quantity: 1, Unit: "l"}},
+		[]clients.Recipe{latte("a"), latte("b"), latte("c")},
+		nil, ScoreOptions{},
+	)
+
+	require.Len(t, res.Results, 3)
+	assert.True(t, res.Results[0].CanMake)
+	assert.True(t, res.Results[1].CanMake)
+	assert.False(t, res.Results[2].CanMake)
+	require.Len(t, res.Results[2].MissingIngredients, 1)
+	assert.InDelta(t, 200.0, res.Results[2].MissingIngredients[0].Shortfall, 0.0001)
+	require.Len(t, res.RemainingPantry, 1)
+	// An unmakeable recipe consumes nothing, so 0.2 l is left.
+	assert.InDelta(t, 0.2, res.RemainingPantry[0].Quantity, 0.0001)
+	assert.Equal(t, "l", res.RemainingPantry[0].Unit)
+}
+
+func TestScore_MealPlanConsumesSubstitutes(t *testing.T) {
+	t.Parallel()
+	cookie := func(id string) clients.Recipe {
+		return clients.Recipe{ID: id, Title: id, Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "butter", Quantity: 100, Unit: "g"},
+		}}
+	}
+	res := scoreMealPlan(t,
+		[]clients.PantryItem{{IngredientID: "oil", Quantity: 120, Unit: "g"}},
+		[]clients.Recipe{cookie("a"), cookie("b")},
+		map[string][]clients.IngredientSubstitute{
+			"butter": {{IngredientID: "butter", SubstituteID: "oil", Ratio: 0.8}},
+		},
+		ScoreOptions{AllowSubs: true},
+	)
+
+	require.Len(t, res.Results, 2)
+	assert.True(t, res.Results[0].CanMake)
+	assert.Equal(t, map[string]string{"butter": "oil"}, res.Results[0].SubstitutedWith)
+	assert.False(t, res.Results[1].CanMake)
+	assert.Equal(t, []PantryRemainder{{IngredientID: "oil", Quantity: 40, Unit: "g"}}, res.RemainingPantry)
+}
+
+func TestScore_WithoutMealPlanPantryIsShared(t *testing.T) {
+	t.Parallel()
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "egg", Quantity: 2}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "a", Ingredients: []clients.RecipeIngredient{{IngredientID: "egg", Quantity: 2}}},
+		{ID: "b", Ingredients: []clients.RecipeIngredient{{IngredientID: "egg", Quantity: 2}}},
+	}, nil)
+
+	res, err := New(pantryMock, recipeMock, dictMock).Score(context.Background(), ScoreOptions{})
+	require.NoError(t, err)
+
+	assert.Len(t, res.Results, 2)
+	assert.Nil(t, res.RemainingPantry)
+}
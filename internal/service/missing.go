@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// RecipeMissing is what one recipe still needs from the current pantry.
+type RecipeMissing struct {
+	RecipeID           string              `json:"recipe_id"`
+	Title              string              `json:"title"`
+	MissingIngredients []MissingIngredient `json:"missing_ingredients"`
+	Warnings           []string            `json:"-"`
+}
+
+// Missing lists the required ingredients of a single catalog recipe that the
+// pantry lacks, with names resolved. Only direct pantry matches and pantry
+// staples count; substitutes are not consulted. Returns [ErrRecipeNotFound]
+// if the recipe is not in the catalog.
+func (s *Service) Missing(ctx context.Context, recipeID string) (*RecipeMissing, error) {
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+
+	pantryItems, recipes, warnings, err := s.fetchCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(recipes, func(r clients.Recipe) bool { return r.ID == recipeID })
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRecipeNotFound, recipeID)
+	}
+	recipe := recipes[i]
+
+	pantrySet := buildPantrySet(pantryItems, s.quantityFloor)
+	for id := range s.staplesFor(ScoreOptions{}) {
+		pantrySet[id] = true
+	}
+	result := scoreRecipe(recipe, pantrySet, nil, scoreRules{})
+	result.MatchedIngredients = nil
+
+	results := []MatchResult{result}
+	if budgetRemains(ctx) {
+		if n := s.resolveNames(ctx, results, false); n > 0 {
+			warnings = append(warnings, cappedNamesWarning(n))
+		}
+	} else {
+		warnings = append(warnings, "name resolution skipped: score budget exhausted")
+	}
+
+	return &RecipeMissing{
+		RecipeID:           recipe.ID,
+		Title:              recipe.Title,
+		MissingIngredients: results[0].MissingIngredients,
+		Warnings:           warnings,
+	}, nil
+}
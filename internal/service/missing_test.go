@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestMissing(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "flour", Quantity: 1}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "cake", Ingredients: []clients.RecipeIngredient{{IngredientID: "sugar"}}},
+		{ID: "bread", Title: "Bread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"},
+			{IngredientID: "salt"},
+			{IngredientID: "yeast", Quantity: 7, Unit: "g"},
+			{IngredientID: "seeds", IsOptional: true},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "yeast").
+		Return(&clients.IngredientDetail{ID: "yeast", Name: "dried yeast"}, nil).Once()
+
+	svc := New(pantryMock, recipeMock, dictMock, WithPantryStaples([]string{"salt"}))
+	got, err := svc.Missing(context.Background(), "bread")
+	require.NoError(t, err)
+
+	assert.Equal(t, "bread", got.RecipeID)
+	assert.Equal(t, "Bread", got.Title)
+	assert.Equal(t, []MissingIngredient{
+		{IngredientID: "yeast", Name: "dried yeast", NameSource: NameSourceExact, Quantity: 7, Unit: "g"},
+	}, got.MissingIngredients)
+}
+
+func TestMissing_RecipeNotFound(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{{ID: "bread"}}, nil)
+
+	svc := New(pantryMock, recipeMock, nil)
+	_, err := svc.Missing(context.Background(), "cake")
+	require.ErrorIs(t, err, ErrRecipeNotFound)
+}
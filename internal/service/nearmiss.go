@@ -0,0 +1,21 @@
+package service
+
+// markNearMisses flags recipes that cannot be made but come close: at most
+// maxMissing required ingredients missing, or at least minCoverage percent
+// covered. A non-positive threshold is disabled; with both disabled nothing
+// is flagged.
+func markNearMisses(results []MatchResult, maxMissing int, minCoverage float64) {
+	if maxMissing <= 0 && minCoverage <= 0 {
+		return
+	}
+
+	for i := range results {
+		r := &results[i]
+		if r.CanMake {
+			continue
+		}
+		withinMissing := maxMissing > 0 && len(r.MissingIngredients) <= maxMissing
+		aboveCoverage := minCoverage > 0 && r.CoveragePct >= minCoverage
+		r.NearMiss = withinMissing || aboveCoverage
+	}
+}
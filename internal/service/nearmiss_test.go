@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func nearMissResults() []MatchResult {
+	missing := func(n int) []MissingIngredient {
+		return make([]MissingIngredient, n)
+	}
+	return []MatchResult{
+		{Recipe: clients.Recipe{ID: "makeable"}, CoveragePct: 100, CanMake: true},
+		{Recipe: clients.Recipe{ID: "one-of-two"}, CoveragePct: 50, MissingIngredients: missing(1)},
+		{Recipe: clients.Recipe{ID: "two-of-ten"}, CoveragePct: 80, MissingIngredients: missing(2)},
+		{Recipe: clients.Recipe{ID: "far"}, CoveragePct: 25, MissingIngredients: missing(3)},
+	}
+}
+
+func nearMissIDs(results []MatchResult) []string {
+	ids := make([]string, 0)
+	for _, r := range results {
+		if r.NearMiss {
+			ids = append(ids, r.Recipe.ID)
+		}
+	}
+	return ids
+}
+
+func TestMarkNearMisses(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		maxMissing  int
+		minCoverage float64
+		want        []string
+	}{
+		{name: "disabled", want: []string{}},
+		{name: "missing only", maxMissing: 1, want: []string{"one-of-two"}},
+		{name: "coverage only", minCoverage: 75, want: []string{"two-of-ten"}},
+		{name: "either threshold", maxMissing: 1, minCoverage: 75, want: []string{"one-of-two", "two-of-ten"}},
+		{name: "coverage boundary inclusive", minCoverage: 80, want: []string{"two-of-ten"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			results := nearMissResults()
+			markNearMisses(results, tt.maxMissing, tt.minCoverage)
+			assert.Equal(t, tt.want, nearMissIDs(results))
+		})
+	}
+}
+
+func TestScore_NearMissesIncluded(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "a"}, {IngredientID: "b"}, {IngredientID: "c"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "close", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "a"}, {IngredientID: "b"}, {IngredientID: "c"}, {IngredientID: "x"},
+		}},
+		{ID: "far", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "a"}, {IngredientID: "x"}, {IngredientID: "y"},
+		}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, nil)
+	res, err := svc.Score(context.Background(), ScoreOptions{NearMissMinCoverage: 70, skipNames: true})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "close", res.Results[0].Recipe.ID)
+	assert.False(t, res.Results[0].CanMake)
+	assert.True(t, res.Results[0].NearMiss)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// ingredientConflict is a duplicated recipe ingredient whose copies use
+// units that cannot be converted into each other.
+type ingredientConflict struct {
+	RecipeID     string `json:"recipe_id"`
+	IngredientID string `json:"ingredient_id"`
+}
+
+// normalizeRecipes collapses ingredients listed more than once in a recipe
+// into a single entry, so coverage counts each ingredient once. It returns
+// the normalized recipes, the IDs of recipes that had duplicates, and the
+// duplicates whose units conflicted. Recipes without duplicates are returned
+// unchanged; the input is never modified.
+func normalizeRecipes(recipes []clients.Recipe) ([]clients.Recipe, []string, []ingredientConflict) {
+	var (
+		out       []clients.Recipe
+		collapsed []string
+		conflicts []ingredientConflict
+	)
+	for i, recipe := range recipes {
+		ingredients, merged, conflicting := mergeDuplicateIngredients(recipe.Ingredients)
+		if !merged {
+			continue
+		}
+		if out == nil {
+			out = append([]clients.Recipe(nil), recipes...)
+		}
+		out[i].Ingredients = ingredients
+		collapsed = append(collapsed, recipe.ID)
+		for _, id := range conflicting {
+			conflicts = append(conflicts, ingredientConflict{RecipeID: recipe.ID, IngredientID: id})
+		}
+	}
+	if out == nil {
+		return recipes, nil, nil
+	}
+	return out, collapsed, conflicts
+}
+
+// mergeDuplicateIngredients merges repeated IngredientIDs into the first
+// occurrence, keeping its position. Quantities are summed in the first
+// occurrence's unit, converting where possible; a copy whose unit cannot be
+// converted adds nothing and its ingredient ID is reported as conflicting.
+// The merged ingredient is required if any copy is, and carries the largest
+// weight. merged is false when there were no duplicates.
+func mergeDuplicateIngredients(
+	ingredients []clients.RecipeIngredient,
+) (out []clients.RecipeIngredient, merged bool, conflicting []string) {
+	index := make(map[string]int, len(ingredients))
+	out = make([]clients.RecipeIngredient, 0, len(ingredients))
+	for _, ing := range ingredients {
+		i, seen := index[ing.IngredientID]
+		if !seen {
+			index[ing.IngredientID] = len(out)
+			out = append(out, ing)
+			continue
+		}
+		merged = true
+		first := &out[i]
+		first.IsOptional = first.IsOptional && ing.IsOptional
+		first.Weight = max(first.Weight, ing.Weight)
+		qty, err := ConvertQuantity(ing.Quantity, ing.Unit, first.Unit)
+		if err != nil {
+			conflicting = append(conflicting, ing.IngredientID)
+			continue
+		}
+		first.Quantity += qty
+	}
+	if !merged {
+		return ingredients, false, nil
+	}
+	return out, true, conflicting
+}
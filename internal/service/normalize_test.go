@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestNormalizeRecipes_CollapsesDuplicates(t *testing.T) {
+	t.Parallel()
+	recipes := []clients.Recipe{
+		{ID: "clean", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Quantity: 200, Unit: "g"},
+		}},
+		{ID: "dupes", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "butter", Quantity: 100, Unit: "g", IsOptional: true},
+			{IngredientID: "sugar", Quantity: 50, Unit: "g"},
+			{IngredientID: "butter", Quantity: 0.5, Unit: "kg", Weight: 2},
+			{IngredientID: "salt", Quantity: 1, Unit: "tsp"},
+			{IngredientID: "salt", Quantity: 1, Unit: "pinch"},
+		}},
+	}
+
+	out, collapsed, conflicts := normalizeRecipes(recipes)
+
+	assert.Equal(t, []string{"dupes"}, collapsed)
+	assert.Equal(t, []ingredientConflict{{RecipeID: "dupes", IngredientID: "salt"}}, conflicts)
+	assert.Equal(t, recipes[0], out[0])
+	// Summed in the first unit, required because one copy is, heaviest
+	// weight kept; the unconvertible salt keeps its first quantity.
+	assert.Equal(t, []clients.RecipeIngredient{
+		{IngredientID: "butter", Quantity: 600, Unit: "g", Weight: 2},
+		{IngredientID: "sugar", Quantity: 50, Unit: "g"},
+		{IngredientID: "salt", Quantity: 1, Unit: "tsp"},
+	}, out[1].Ingredients)
+	// The caller's catalog is untouched.
+	assert.Len(t, recipes[1].Ingredients, 5)
+}
+
+func TestNormalizeRecipes_NoDuplicates(t *testing.T) {
+	t.Parallel()
+	recipes := []clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "a"}, {IngredientID: "b"}}},
+	}
+
+	out, collapsed, conflicts := normalizeRecipes(recipes)
+
+	assert.Equal(t, recipes, out)
+	assert.Empty(t, collapsed)
+	assert.Empty(t, conflicts)
+}
+
+func TestScore_DuplicateIngredientsCountOnce(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "garlic"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "garlic", Quantity: 2, Unit: "clove"},
+			{IngredientID: "pasta", Quantity: 200, Unit: "g"},
+			{IngredientID: "garlic", Quantity: 1, Unit: "tbsp"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "pasta").Return(nil, clients.ErrIngredientNotFound)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{MaxMissing: 1})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.InDelta(t, 50.0, res.Results[0].CoveragePct, 0.0001)
+	assert.Contains(t, res.Warnings, "collapsed duplicate ingredients in 1 recipes")
+	assert.Contains(t, res.Warnings, "1 duplicate recipe ingredients had incompatible units; kept the first quantity")
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"maps"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// ScoreRecipes scores recipes against pantry entirely in memory, for batch
+// jobs and tests that have the data at hand rather than behind the pantry,
+// recipe and dictionary services. It ranks and filters exactly as
+// [Service.Score] does for the same opts.
+//
+// subs maps an ingredient ID to its substitutes, as the dictionary would
+// return them; it is used when opts.AllowSubs or opts.SuggestSubs is set.
+// Options that need the dictionary (UseGroups, ExcludeAllergens, names) have
+// no effect, and only the results are returned: no warnings, substitution
+// summary or meal-plan remainder. serviceOpts apply the same configuration as
+// in [New], e.g. [WithTagMaxMissing].
+func ScoreRecipes(
+	pantry []clients.PantryItem,
+	recipes []clients.Recipe,
+	subs map[string][]clients.IngredientSubstitute,
+	opts ScoreOptions,
+	serviceOpts ...Option,
+) ([]MatchResult, error) {
+	s := New(nil, nil, nil, serviceOpts...)
+	if _, err := s.scorer(opts.Strategy); err != nil {
+		return nil, err
+	}
+	if opts.PantryConstrained {
+		opts = opts.constrained()
+	}
+
+	ctx := context.Background()
+	recipes, _ = prepareRecipes(ctx, recipes, "")
+	recipes, pantrySet, _ := s.prepareCatalog(ctx, pantry, recipes, opts)
+
+	var subsMap, suggestionSubs map[string][]clients.IngredientSubstitute
+	if opts.AllowSubs {
+		subsMap = limitedSubstitutes(subs, s.maxSubs)
+		if opts.SubDepth > 1 {
+			subsMap = expandSubstituteChains(subsMap, opts.SubDepth)
+		}
+		removeExcludedSubstitutes(subsMap, opts.ExcludeSubs)
+	}
+	if opts.SuggestSubs {
+		suggestionSubs = limitedSubstitutes(subs, s.maxSubs)
+		removeExcludedSubstitutes(suggestionSubs, opts.ExcludeSubs)
+	}
+	// Keep only the ingredients the dictionary prefetch would have looked up.
+	candidates := s.substituteCandidates(recipes, pantrySet, opts)
+	notCandidate := func(id string, _ []clients.IngredientSubstitute) bool { return !candidates[id] }
+	maps.DeleteFunc(subsMap, notCandidate)
+	maps.DeleteFunc(suggestionSubs, notCandidate)
+
+	results, _ := s.rankRecipes(recipes, pantry, pantrySet, subsMap, suggestionSubs, opts)
+	if !opts.IncludeMatched {
+		clearMatched(results)
+	}
+	if opts.IncludeSummary {
+		attachSummaries(results)
+	}
+	s.roundCoverage(results)
+	return results, nil
+}
+
+// limitedSubstitutes copies subs, keeping at most maxSubs substitutes per
+// ingredient as the dictionary prefetch does.
+func limitedSubstitutes(
+	subs map[string][]clients.IngredientSubstitute,
+	maxSubs int,
+) map[string][]clients.IngredientSubstitute {
+	out := make(map[string][]clients.IngredientSubstitute, len(subs))
+	for id, list := range subs {
+		if len(list) > 0 {
+			out[id] = limitSubstitutes(list, maxSubs)
+		}
+	}
+	return cloneSubstitutes(out)
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func offlineCatalog() ([]clients.PantryItem, []clients.Recipe) {
+	pantry := []clients.PantryItem{
+		{ID: "p1", IngredientID: "pasta"},
+		{ID: "p2", IngredientID: "garlic"},
+		{ID: "p3", IngredientID: "oil"},
+	}
+	recipes := []clients.Recipe{
+		{ID: "aglio", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "pasta"}, {IngredientID: "garlic"}, {IngredientID: "oil"},
+		}},
+		{ID: "carbonara", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "pasta"}, {IngredientID: "egg"}, {IngredientID: "guanciale"},
+		}},
+		{ID: "buttered", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "pasta"}, {IngredientID: "butter"},
+		}},
+	}
+	return pantry, recipes
+}
+
+func TestScoreRecipes(t *testing.T) {
+	t.Parallel()
+	pantry, recipes := offlineCatalog()
+
+	results, err := ScoreRecipes(pantry, recipes, nil, ScoreOptions{MaxMissing: 1})
+	require.NoError(t, err)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, "aglio", results[0].Recipe.ID)
+	assert.True(t, results[0].CanMake)
+	assert.Equal(t, "buttered", results[1].Recipe.ID)
+	assert.InDelta(t, 50.0, results[1].CoveragePct, 0.0001)
+	assert.Equal(t, []MissingIngredient{{IngredientID: "butter"}}, results[1].MissingIngredients)
+	assert.Nil(t, results[0].MatchedIngredients)
+}
+
+func TestScoreRecipes_Substitutes(t *testing.T) {
+	t.Parallel()
+	pantry, recipes := offlineCatalog()
+	subs := map[string][]clients.IngredientSubstitute{
+		"butter": {{SubstituteID: "oil", Ratio: 1}},
+	}
+
+	results, err := ScoreRecipes(pantry, recipes, subs, ScoreOptions{AllowSubs: true})
+	require.NoError(t, err)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, "buttered", results[1].Recipe.ID)
+	assert.True(t, results[1].CanMake)
+	assert.Equal(t, map[string]string{"butter": "oil"}, results[1].SubstitutedWith)
+
+	// Without allow_subs the same substitutes are ignored.
+	results, err = ScoreRecipes(pantry, recipes, subs, ScoreOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "aglio", results[0].Recipe.ID)
+}
+
+func TestScoreRecipes_ServiceOptions(t *testing.T) {
+	t.Parallel()
+	pantry, recipes := offlineCatalog()
+	recipes[1].Tags = []string{"flexible"}
+
+	results, err := ScoreRecipes(pantry, recipes, nil, ScoreOptions{},
+		WithTagMaxMissing(map[string]int{"flexible": 2}))
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Recipe.ID)
+	}
+	assert.Equal(t, []string{"aglio", "carbonara"}, ids)
+}
+
+func TestScoreRecipes_UnknownStrategy(t *testing.T) {
+	t.Parallel()
+	_, err := ScoreRecipes(nil, nil, nil, ScoreOptions{Strategy: "vibes"})
+	require.ErrorIs(t, err, ErrUnknownStrategy)
+}
+
+func TestScoreRecipes_MatchesScore(t *testing.T) {
+	t.Parallel()
+	pantry, recipes := offlineCatalog()
+	opts := ScoreOptions{MaxMissing: 1, IncludeUnmakeable: true, IncludeMatched: true}
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return(pantry, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(nil, clients.ErrIngredientNotFound)
+
+	res, err := New(pantryMock, recipeMock, dictMock).Score(context.Background(), opts)
+	require.NoError(t, err)
+	offline, err := ScoreRecipes(pantry, recipes, nil, opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, res.Results, offline)
+}
+
+func TestScoreRecipes_Servings(t *testing.T) {
+	t.Parallel()
+	pantry := []clients.PantryItem{{ID: "p1", IngredientID: "flour", Quantity: 200, Unit: "g"}}
+	recipes := []clients.Recipe{
+		{ID: "bread", Servings: 2, Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Quantity: 100, Unit: "g"},
+		}},
+	}
+
+	results, err := ScoreRecipes(pantry, recipes, nil, ScoreOptions{
+		Strategy:          StrategyQuantity,
+		Servings:          6,
+		IncludeUnmakeable: true,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, results, 1)
+	assert.False(t, results[0].CanMake)
+	require.Len(t, results[0].MissingIngredients, 1)
+	assert.InDelta(t, 300.0, results[0].MissingIngredients[0].Quantity, 0.0001)
+	assert.InDelta(t, 100.0, results[0].MissingIngredients[0].Shortfall, 0.0001)
+}
+
+func TestScoreRecipes_SubstituteMinCoverage(t *testing.T) {
+	t.Parallel()
+	pantry, recipes := offlineCatalog()
+	subs := map[string][]clients.IngredientSubstitute{
+		"butter": {{SubstituteID: "oil", Ratio: 1}},
+		"egg":    {{SubstituteID: "oil", Ratio: 1}},
+	}
+
+	// carbonara covers a third of its ingredients directly, below the 50%
+	// needed for its substitutes to be looked up, so the egg substitute does
+	// not bring it within max_missing.
+	results, err := ScoreRecipes(pantry, recipes, subs, ScoreOptions{AllowSubs: true, MaxMissing: 1},
+		WithSubstituteMinCoverage(50))
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Recipe.ID)
+	}
+	assert.Equal(t, []string{"aglio", "buttered"}, ids)
+}
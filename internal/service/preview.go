@@ -0,0 +1,65 @@
+package service
+
+import "context"
+
+// Preview answers "if I buy these ingredients, what new recipes can I make?".
+// It scores the catalog against the current pantry and against the pantry
+// plus buy, and returns only the recipes that become makeable, ranked as
+// [Service.Score] ranks them. Scoring options apply to both passes.
+func (s *Service) Preview(ctx context.Context, opts ScoreOptions, buy []string) (*ScoreResult, error) {
+	if _, err := s.scorer(opts.Strategy); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+
+	pantryItems, recipes, warnings, err := s.fetchCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Previews are hypothetical: they never count toward substitute usage,
+	// and names are only resolved for the recipes returned.
+	opts.IncludeUnmakeable = false
+	opts.SubstitutionSummary = false
+	opts.skipUsage = true
+	skipNames := opts.skipNames
+	opts.skipNames = true
+
+	before := s.scoreCatalog(ctx, pantryItems, recipes, opts, nil)
+	makeable := make(map[string]bool, len(before.Results))
+	for _, r := range before.Results {
+		if r.CanMake {
+			makeable[r.Recipe.ID] = true
+		}
+	}
+
+	opts.extraPantry = buy
+	after := s.scoreCatalog(ctx, pantryItems, recipes, opts, warnings)
+
+	unlocked := make([]MatchResult, 0)
+	for _, r := range after.Results {
+		if r.CanMake && !makeable[r.Recipe.ID] {
+			unlocked = append(unlocked, r)
+		}
+	}
+
+	warnings = after.Warnings
+	switch {
+	case skipNames:
+	case budgetRemains(ctx):
+		if n := s.resolveNames(ctx, unlocked, opts.IncludeNames); n > 0 {
+			warnings = append(warnings, cappedNamesWarning(n))
+		}
+	default:
+		warnings = append(warnings, "name resolution skipped: score budget exhausted")
+	}
+
+	s.roundCoverage(unlocked)
+	if opts.IncludeSummary {
+		attachSummaries(unlocked)
+	}
+
+	return &ScoreResult{Results: unlocked, Warnings: warnings}, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func previewService(t *testing.T) (*Service, *mocks.MockDictionaryFetcher) {
+	t.Helper()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour"}, {IngredientID: "milk"},
+	}, nil).Once()
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "pancakes", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "milk"}, {IngredientID: "eggs"},
+		}},
+		{ID: "omelette", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "eggs"}, {IngredientID: "cheese"},
+		}},
+		{ID: "crepes", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "eggs"}, {IngredientID: "butter"},
+		}},
+		{ID: "roux", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "milk"},
+		}},
+	}, nil).Once()
+
+	return New(pantryMock, recipeMock, dictMock), dictMock
+}
+
+func previewIDs(res *ScoreResult) []string {
+	ids := make([]string, 0, len(res.Results))
+	for _, r := range res.Results {
+		ids = append(ids, r.Recipe.ID)
+	}
+	return ids
+}
+
+func TestPreview_SinglePurchase(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := previewService(t)
+	res, err := svc.Preview(context.Background(), ScoreOptions{skipNames: true}, []string{"eggs"})
+	require.NoError(t, err)
+
+	// roux was already makeable; omelette and crepes still miss something.
+	assert.Equal(t, []string{"pancakes"}, previewIDs(res))
+}
+
+func TestPreview_MultiplePurchases(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := previewService(t)
+	res, err := svc.Preview(context.Background(), ScoreOptions{skipNames: true}, []string{"eggs", "cheese"})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"pancakes", "omelette"}, previewIDs(res))
+}
+
+func TestPreview_RespectsMaxMissing(t *testing.T) {
+	t.Parallel()
+
+	svc, dictMock := previewService(t)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "butter").Return(&clients.IngredientDetail{ID: "butter", Name: "butter"}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "cheese").Return(&clients.IngredientDetail{ID: "cheese", Name: "cheese"}, nil)
+
+	res, err := svc.Preview(context.Background(), ScoreOptions{MaxMissing: 1}, []string{"eggs"})
+	require.NoError(t, err)
+
+	// pancakes was already makeable missing one; crepes and omelette now
+	// miss at most one.
+	assert.ElementsMatch(t, []string{"crepes", "omelette"}, previewIDs(res))
+	for _, r := range res.Results {
+		if r.Recipe.ID == "crepes" {
+			require.Len(t, r.MissingIngredients, 1)
+			assert.Equal(t, "butter", r.MissingIngredients[0].Name)
+		}
+	}
+}
+
+func TestPreview_QuantityStrategy(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour", Quantity: 500, Unit: "g"},
+	}, nil).Once()
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "pancakes", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Quantity: 200, Unit: "g"},
+			{IngredientID: "eggs", Quantity: 2},
+		}},
+		{ID: "bread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Quantity: 1, Unit: "kg"},
+			{IngredientID: "eggs", Quantity: 1},
+		}},
+	}, nil).Once()
+
+	svc := New(pantryMock, recipeMock, nil)
+	res, err := svc.Preview(context.Background(), ScoreOptions{Strategy: StrategyQuantity, skipNames: true},
+		[]string{"eggs"})
+	require.NoError(t, err)
+
+	// Bought eggs cover any quantity; bread still needs more flour than stocked.
+	assert.Equal(t, []string{"pancakes"}, previewIDs(res))
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// minPromptTermLen drops short filler words ("a", "of", "me") from prompts.
+const minPromptTermLen = 3
+
+// promptTerms splits a natural language prompt into lowercase keyword terms.
+// An empty or whitespace-only prompt yields no terms, which callers treat as
+// "no prompt": the full candidate set is returned in coverage order.
+func promptTerms(prompt string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if len(f) >= minPromptTermLen {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
+
+// rerankByPrompt stably reorders results so recipes whose title or tags match
+// more prompt terms come first. It never adds or removes candidates, so the
+// prompt can only re-rank what deterministic scoring produced.
+func rerankByPrompt(results []MatchResult, terms []string) {
+	if len(terms) == 0 {
+		return
+	}
+
+	relevance := make(map[string]int, len(results))
+	for _, r := range results {
+		relevance[r.Recipe.ID] = promptRelevance(r, terms)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return relevance[results[i].Recipe.ID] > relevance[results[j].Recipe.ID]
+	})
+}
+
+func promptRelevance(r MatchResult, terms []string) int {
+	text := strings.ToLower(r.Recipe.Title + " " + strings.Join(r.Recipe.Tags, " "))
+	n := 0
+	for _, term := range terms {
+		if strings.Contains(text, term) {
+			n++
+		}
+	}
+	return n
+}
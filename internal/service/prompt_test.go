@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+func TestPromptTerms(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, promptTerms(""))
+	assert.Empty(t, promptTerms("   \t\n "))
+	assert.Equal(t, []string{"something", "spicy", "and", "quick", "maybe", "asian"},
+		promptTerms("something spicy and quick, maybe Asian"))
+}
+
+func promptCandidates() []MatchResult {
+	return []MatchResult{
+		{Recipe: clients.Recipe{ID: "r1", Title: "Garlic Pasta", Tags: []string{"italian"}}, CoveragePct: 100},
+		{Recipe: clients.Recipe{ID: "r2", Title: "Chicken Stir Fry", Tags: []string{"asian", "quick"}}, CoveragePct: 80},
+		{Recipe: clients.Recipe{ID: "r3", Title: "Beef Stew"}, CoveragePct: 50},
+	}
+}
+
+func resultIDs(results []MatchResult) []string {
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Recipe.ID)
+	}
+	return ids
+}
+
+func TestRerankByPrompt_EmptyPromptKeepsCoverageOrder(t *testing.T) {
+	t.Parallel()
+
+	for _, prompt := range []string{"", "   "} {
+		results := promptCandidates()
+		rerankByPrompt(results, promptTerms(prompt))
+		assert.Equal(t, []string{"r1", "r2", "r3"}, resultIDs(results), "prompt %q", prompt)
+	}
+}
+
+func TestRerankByPrompt_MeaningfulPrompt(t *testing.T) {
+	t.Parallel()
+
+	results := promptCandidates()
+	rerankByPrompt(results, promptTerms("something quick, maybe Asian"))
+
+	// Re-ranked, never filtered.
+	assert.Equal(t, []string{"r2", "r1", "r3"}, resultIDs(results))
+}
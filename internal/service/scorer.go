@@ -0,0 +1,189 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// Built-in scoring strategies, selected per request by ScoreOptions.Strategy.
+const (
+	// StrategyPresence counts an ingredient as stocked when the pantry holds
+	// any amount of it (default).
+	StrategyPresence = "presence"
+	// StrategyQuantity additionally requires the pantry to hold at least the
+	// recipe quantity when both use the same unit.
+	StrategyQuantity = "quantity"
+)
+
+// ErrUnknownStrategy is returned when a request names a scoring strategy the
+// service has not registered.
+var ErrUnknownStrategy = errors.New("unknown scoring strategy")
+
+// Scorer is a recipe scoring strategy. The service registers each strategy
+// under a name; implementations live in this package.
+type Scorer interface {
+	score(recipe clients.Recipe, in scoreInput) MatchResult
+}
+
+// scoreInput is the pantry state and rules a Scorer applies to one recipe.
+type scoreInput struct {
+	pantrySet map[string]bool
+	stock     map[string]pantryStock
+	// staples are in pantrySet with unlimited stock.
+	staples map[string]bool
+	subsMap map[string][]clients.IngredientSubstitute
+	rules   scoreRules
+}
+
+func defaultScorers() map[string]Scorer {
+	return map[string]Scorer{
+		StrategyPresence: presenceScorer{},
+		StrategyQuantity: quantityScorer{},
+	}
+}
+
+// Strategies returns the names of the registered scoring strategies, sorted.
+func (s *Service) Strategies() []string {
+	names := make([]string, 0, len(s.scorers))
+	for name := range s.scorers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// HasStrategy reports whether name is a registered scoring strategy. The empty
+// name selects the default strategy and is always valid.
+func (s *Service) HasStrategy(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, ok := s.scorers[name]
+	return ok
+}
+
+// scorer returns the Scorer registered under name, or the default strategy
+// when name is empty.
+func (s *Service) scorer(name string) (Scorer, error) {
+	if name == "" {
+		name = StrategyPresence
+	}
+	sc, ok := s.scorers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStrategy, name)
+	}
+	return sc, nil
+}
+
+// presenceScorer is the original presence-only scoring.
+type presenceScorer struct{}
+
+func (presenceScorer) score(recipe clients.Recipe, in scoreInput) MatchResult {
+	return scoreRecipe(recipe, in.pantrySet, in.subsMap, in.rules)
+}
+
+// quantityScorer treats a directly stocked ingredient as missing when the
+// pantry holds less than the recipe calls for, reporting the difference as
+// its Shortfall. Pantry quantities are converted to the recipe's unit first;
+// an ingredient whose units cannot be converted is missing and flagged
+// Unconvertible. Substitutes must likewise be stocked in the recipe quantity
+// scaled by their ratio. Pantry staples are always stocked.
+type quantityScorer struct{}
+
+func (quantityScorer) score(recipe clients.Recipe, in scoreInput) MatchResult {
+	// A substitute can cover one ingredient while falling short as a direct
+	// ingredient of its own, so the two are tracked apart.
+	subStocked := make(map[string]bool)
+	subsMap := make(map[string][]clients.IngredientSubstitute)
+	for _, ing := range recipe.Ingredients {
+		for _, sub := range in.subsMap[ing.IngredientID] {
+			if in.staples[sub.SubstituteID] ||
+				in.pantrySet[sub.SubstituteID] && hasSubstituteStock(in.stock[sub.SubstituteID], ing, sub) {
+				subStocked[sub.SubstituteID] = true
+				subsMap[ing.IngredientID] = append(subsMap[ing.IngredientID], sub)
+			}
+		}
+	}
+	stocked := make(map[string]bool, len(recipe.Ingredients))
+	have := make(map[string]float64)
+	unconvertible := make(map[string]bool)
+	for _, ing := range recipe.Ingredients {
+		id := ing.IngredientID
+		if in.staples[id] {
+			stocked[id] = true
+			continue
+		}
+		if !in.pantrySet[id] || ing.Quantity <= 0 {
+			stocked[id] = in.pantrySet[id]
+			continue
+		}
+		qty, err := stockIn(in.stock[id], ing.Unit)
+		if err != nil {
+			unconvertible[id] = true
+			stocked[id] = false
+			continue
+		}
+		have[id] = qty
+		stocked[id] = qty >= ing.Quantity
+	}
+
+	result := scoreRecipeWith(recipe, stocked, subStocked, subsMap, in.rules)
+	for i := range result.MissingIngredients {
+		m := &result.MissingIngredients[i]
+		switch {
+		case unconvertible[m.IngredientID]:
+			m.Unconvertible = true
+		case in.pantrySet[m.IngredientID]:
+			m.Shortfall = m.Quantity - have[m.IngredientID]
+		}
+	}
+	return result
+}
+
+// hasSubstituteStock reports whether stock of sub covers ing once scaled by
+// the substitute ratio: 100 g of butter with an oil ratio of 0.8 needs 80 g
+// of oil. A non-positive ratio counts as 1:1; stock that cannot be converted
+// to the recipe unit does not cover it.
+func hasSubstituteStock(stock pantryStock, ing clients.RecipeIngredient, sub clients.IngredientSubstitute) bool {
+	if ing.Quantity <= 0 {
+		return true
+	}
+	ratio := sub.Ratio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	qty, err := stockIn(stock, ing.Unit)
+	return err == nil && qty >= ing.Quantity*ratio
+}
+
+// stockIn returns the pantry stock expressed in unit, summing every entry
+// that converts to it. A recipe without a unit compares the first entry as a
+// plain count, and an entry without a unit counts in any unit. It fails when
+// no entry converts.
+func stockIn(stock pantryStock, unit string) (float64, error) {
+	if unit == "" {
+		return stock.Quantity, nil
+	}
+	var (
+		total float64
+		found bool
+	)
+	for _, st := range slices.Concat([]pantryStock{stock}, stock.incompatible) {
+		qty := st.Quantity
+		if st.Unit != "" {
+			var err error
+			if qty, err = ConvertQuantity(st.Quantity, st.Unit, unit); err != nil {
+				continue
+			}
+		}
+		total += qty
+		found = true
+	}
+	if !found {
+		return 0, fmt.Errorf("%w: %q to %q", ErrIncompatibleUnits, stock.Unit, unit)
+	}
+	return total, nil
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestQuantityScorer(t *testing.T) {
+	t.Parallel()
+
+	recipe := clients.Recipe{
+		ID: "r1",
+		Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Quantity: 2, Unit: "cup"},
+			{IngredientID: "milk", Quantity: 1, Unit: "cup"},
+			{IngredientID: "eggs", Quantity: 3, Unit: ""},
+			{IngredientID: "salt"},
+		},
+	}
+	in := scoreInput{
+		pantrySet: map[string]bool{"flour": true, "milk": true, "eggs": true, "salt": true},
+		stock: map[string]pantryStock{
+			"flour": {Quantity: 1, Unit: "cup"},
+			"milk":  {Quantity: 500, Unit: "ml"},
+			"eggs":  {Quantity: 6},
+			"salt":  {Quantity: 0},
+		},
+		rules: scoreRules{maxMissing: 1},
+	}
+
+	result := quantityScorer{}.score(recipe, in)
+	require.Len(t, result.MissingIngredients, 1)
+	assert.Equal(t, "flour", result.MissingIngredients[0].IngredientID)
+	assert.Equal(t, 75.0, result.CoveragePct)
+	assert.True(t, result.CanMake)
+
+	presence := presenceScorer{}.score(recipe, in)
+	assert.Empty(t, presence.MissingIngredients)
+}
+
+func TestQuantityScorer_Coverage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		have          float64
+		wantCovered   bool
+		wantShortfall float64
+	}{
+		{name: "exact match", have: 500, wantCovered: true},
+		{name: "surplus", have: 750, wantCovered: true},
+		{name: "shortfall", have: 10, wantShortfall: 490},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recipe := clients.Recipe{
+				ID:          "bread",
+				Ingredients: []clients.RecipeIngredient{{IngredientID: "flour", Quantity: 500, Unit: "g"}},
+			}
+			result := quantityScorer{}.score(recipe, scoreInput{
+				pantrySet: map[string]bool{"flour": true},
+				stock:     map[string]pantryStock{"flour": {Quantity: tt.have, Unit: "g"}},
+			})
+
+			assert.Equal(t, tt.wantCovered, result.CanMake)
+			if tt.wantCovered {
+				assert.Empty(t, result.MissingIngredients)
+				return
+			}
+			require.Len(t, result.MissingIngredients, 1)
+			assert.InDelta(t, 500.0, result.MissingIngredients[0].Quantity, 0.0001)
+			assert.InDelta(t, tt.wantShortfall, result.MissingIngredients[0].Shortfall, 0.0001)
+		})
+	}
+}
+
+func TestQuantityScorer_ConvertsUnits(t *testing.T) {
+	t.Parallel()
+
+	recipe := clients.Recipe{
+		ID: "bread",
+		Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Quantity: 500, Unit: "g"},
+			{IngredientID: "milk", Quantity: 2, Unit: "cup"},
+			{IngredientID: "sugar", Quantity: 1, Unit: "cup"},
+		},
+	}
+	result := quantityScorer{}.score(recipe, scoreInput{
+		pantrySet: map[string]bool{"flour": true, "milk": true, "sugar": true},
+		stock: map[string]pantryStock{
+			"flour": {Quantity: 1, Unit: "kg"},
+			"milk":  {Quantity: 250, Unit: "ml"},
+			"sugar": {Quantity: 200, Unit: "g"},
+		},
+		rules: scoreRules{maxMissing: 2},
+	})
+
+	require.Len(t, result.MissingIngredients, 2)
+	milk, sugar := result.MissingIngredients[0], result.MissingIngredients[1]
+	assert.Equal(t, "milk", milk.IngredientID)
+	assert.InDelta(t, 2-250/236.5882365, milk.Shortfall, 0.0001)
+	assert.False(t, milk.Unconvertible)
+	assert.Equal(t, "sugar", sugar.IngredientID)
+	assert.True(t, sugar.Unconvertible)
+	assert.Zero(t, sugar.Shortfall)
+}
+
+func TestBuildPantryStock_ConvertsUnits(t *testing.T) {
+	t.Parallel()
+
+	stock := buildPantryStock([]clients.PantryItem{
+		{IngredientID: "flour", Quantity: 1, Unit: "kg"},
+		{IngredientID: "flour", Quantity: 250, Unit: "g"},
+	})
+	assert.Equal(t, pantryStock{Quantity: 1.25, Unit: "kg"}, stock["flour"])
+}
+
+func TestBuildPantryStock_KeepsIncompatibleUnitsApart(t *testing.T) {
+	t.Parallel()
+
+	stock := buildPantryStock([]clients.PantryItem{
+		{IngredientID: "butter", Quantity: 200, Unit: "g"},
+		{IngredientID: "butter", Quantity: 2, Unit: "stick"},
+		{IngredientID: "butter", Quantity: 1, Unit: "stick"},
+		{IngredientID: "butter", Quantity: 2, Unit: "tbsp"},
+	})
+	assert.Equal(t, pantryStock{Quantity: 200, Unit: "g", incompatible: []pantryStock{
+		{Quantity: 3, Unit: "stick"},
+		{Quantity: 2, Unit: "tbsp"},
+	}}, stock["butter"])
+	assert.Equal(t, []string{"butter"}, mixedUnitIngredients(stock))
+
+	grams, err := stockIn(stock["butter"], "g")
+	require.NoError(t, err)
+	assert.InDelta(t, 200.0, grams, 0.0001)
+	tsp, err := stockIn(stock["butter"], "tsp")
+	require.NoError(t, err)
+	assert.InDelta(t, 6.0, tsp, 0.0001)
+	_, err = stockIn(stock["butter"], "cup")
+	require.NoError(t, err)
+	_, err = stockIn(stock["butter"], "pinch")
+	require.ErrorIs(t, err, ErrIncompatibleUnits)
+
+	drawStock(stock, "butter", 2, "stick")
+	assert.InDelta(t, 200.0, stock["butter"].Quantity, 0.0001)
+	assert.InDelta(t, 1.0, stock["butter"].incompatible[0].Quantity, 0.0001)
+}
+
+func TestQuantityScorer_AbsentIngredientHasNoShortfall(t *testing.T) {
+	t.Parallel()
+
+	recipe := clients.Recipe{
+		ID:          "bread",
+		Ingredients: []clients.RecipeIngredient{{IngredientID: "yeast", Quantity: 7, Unit: "g"}},
+	}
+	result := quantityScorer{}.score(recipe, scoreInput{pantrySet: map[string]bool{}})
+
+	require.Len(t, result.MissingIngredients, 1)
+	assert.Zero(t, result.MissingIngredients[0].Shortfall)
+}
+
+func TestQuantityScorer_SubstituteCoversShortfall(t *testing.T) {
+	t.Parallel()
+
+	recipe := clients.Recipe{
+		ID:          "r1",
+		Ingredients: []clients.RecipeIngredient{{IngredientID: "butter", Quantity: 2, Unit: "tbsp"}},
+	}
+	result := quantityScorer{}.score(recipe, scoreInput{
+		pantrySet: map[string]bool{"butter": true, "oil": true},
+		stock: map[string]pantryStock{
+			"butter": {Quantity: 1, Unit: "tbsp"},
+			"oil":    {Quantity: 100, Unit: "ml"},
+		},
+		subsMap: map[string][]clients.IngredientSubstitute{"butter": {{SubstituteID: "oil"}}},
+	})
+
+	assert.True(t, result.CanMake)
+	require.Len(t, result.MatchedIngredients, 1)
+	assert.Equal(t, MatchSourceSubstitute, result.MatchedIngredients[0].Source)
+	assert.Equal(t, map[string]string{"butter": "oil"}, result.SubstitutedWith)
+}
+
+func TestQuantityScorer_SubstituteAlsoNeededDirectly(t *testing.T) {
+	t.Parallel()
+
+	// The oil covers the butter but not the recipe's own, larger oil.
+	recipe := clients.Recipe{
+		ID: "r1",
+		Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "butter", Quantity: 2, Unit: "tbsp"},
+			{IngredientID: "oil", Quantity: 200, Unit: "ml"},
+		},
+	}
+	result := quantityScorer{}.score(recipe, scoreInput{
+		pantrySet: map[string]bool{"oil": true},
+		stock:     map[string]pantryStock{"oil": {Quantity: 100, Unit: "ml"}},
+		subsMap:   map[string][]clients.IngredientSubstitute{"butter": {{SubstituteID: "oil"}}},
+		rules:     scoreRules{maxMissing: 1},
+	})
+
+	require.Len(t, result.MatchedIngredients, 1)
+	assert.Equal(t, "butter", result.MatchedIngredients[0].IngredientID)
+	assert.Equal(t, MatchSourceSubstitute, result.MatchedIngredients[0].Source)
+	require.Len(t, result.MissingIngredients, 1)
+	assert.Equal(t, "oil", result.MissingIngredients[0].IngredientID)
+	assert.InDelta(t, 100.0, result.MissingIngredients[0].Shortfall, 0.0001)
+	assert.InDelta(t, 50.0, result.CoveragePct, 0.0001)
+}
+
+func TestQuantityScorer_SubstituteRatio(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		oil     float64
+		canMake bool
+	}{
+		{name: "enough after ratio", oil: 80, canMake: true},
+		{name: "short after ratio", oil: 70},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recipe := clients.Recipe{
+				ID:          "cake",
+				Ingredients: []clients.RecipeIngredient{{IngredientID: "butter", Quantity: 100, Unit: "g"}},
+			}
+			result := quantityScorer{}.score(recipe, scoreInput{
+				pantrySet: map[string]bool{"oil": true},
+				stock:     map[string]pantryStock{"oil": {Quantity: tt.oil, Unit: "g"}},
+				subsMap: map[string][]clients.IngredientSubstitute{
+					"butter": {{IngredientID: "butter", SubstituteID: "oil", Ratio: 0.8}},
+				},
+			})
+
+			assert.Equal(t, tt.canMake, result.CanMake)
+			if tt.canMake {
+				assert.Equal(t, map[string]string{"butter": "oil"}, result.SubstitutedWith)
+			} else {
+				assert.Nil(t, result.SubstitutedWith)
+			}
+		})
+	}
+}
+
+func TestQuantityScorer_SubstituteUnitsUnconvertible(t *testing.T) {
+	t.Parallel()
+
+	recipe := clients.Recipe{
+		ID:          "cake",
+		Ingredients: []clients.RecipeIngredient{{IngredientID: "butter", Quantity: 100, Unit: "g"}},
+	}
+	result := quantityScorer{}.score(recipe, scoreInput{
+		pantrySet: map[string]bool{"oil": true},
+		stock:     map[string]pantryStock{"oil": {Quantity: 1, Unit: "cup"}},
+		subsMap:   map[string][]clients.IngredientSubstitute{"butter": {{SubstituteID: "oil", Ratio: 1}}},
+	})
+
+	assert.False(t, result.CanMake)
+}
+
+func TestScore_UnknownStrategy(t *testing.T) {
+	t.Parallel()
+
+	svc := New(mocks.NewMockPantryFetcher(t), mocks.NewMockRecipeFetcher(t), mocks.NewMockDictionaryFetcher(t))
+	_, err := svc.Score(context.Background(), ScoreOptions{Strategy: "magic"})
+	require.ErrorIs(t, err, ErrUnknownStrategy)
+}
+
+func TestScore_QuantityStrategy(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "rice", Quantity: 100, Unit: "g"},
+		{ID: "p2", IngredientID: "rice", Quantity: 150, Unit: "g"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "small", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice", Quantity: 200, Unit: "g"}}},
+		{ID: "large", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice", Quantity: 500, Unit: "g"}}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{Strategy: StrategyQuantity})
+	require.NoError(t, err)
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "small", res.Results[0].Recipe.ID)
+}
+
+func TestScore_QuantityStrategyWarnsOnIncompatibleUnits(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "rice", Quantity: 100, Unit: "g"},
+		{ID: "p2", IngredientID: "rice", Quantity: 300, Unit: "ml"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "grams", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice", Quantity: 200, Unit: "g"}}},
+		{ID: "cups", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice", Quantity: 1, Unit: "cup"}}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{Strategy: StrategyQuantity})
+	require.NoError(t, err)
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "cups", res.Results[0].Recipe.ID)
+	assert.Contains(t, res.Warnings,
+		"1 pantry ingredients are stocked in incompatible units; only quantities convertible to the recipe unit count")
+}
+
+func TestService_Strategies(t *testing.T) {
+	t.Parallel()
+
+	svc := New(nil, nil, nil)
+	assert.Equal(t, []string{StrategyPresence, StrategyQuantity}, svc.Strategies())
+	assert.True(t, svc.HasStrategy(""))
+	assert.True(t, svc.HasStrategy(StrategyQuantity))
+	assert.False(t, svc.HasStrategy("magic"))
+}
@@ -1,148 +1,1713 @@
 package service
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log/slog"
+	"maps"
+	"math"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/mwhite7112/woodpantry-matching/internal/clients"
 )
 
-const coveragePercentScale = 100.0
+const (
+	coveragePercentScale = 100.0
+
+	// bestEffortReserve is the minimum time that must remain in the score
+	// budget to start a best-effort dictionary fan-out.
+	bestEffortReserve = 100 * time.Millisecond
+)
+
+// Match sources reported on [MatchedIngredient].
+const (
+	MatchSourceDirect     = "direct"
+	MatchSourceSubstitute = "substitute"
+)
+
+type MissingIngredient struct {
+	IngredientID string  `json:"ingredient_id"`
+	Name         string  `json:"name,omitempty"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	HaveQuantity float64 `json:"have_quantity,omitempty"`
+	HaveUnit     string  `json:"have_unit,omitempty"`
+	// Shortfall is how much more is needed when the pantry holds some, but
+	// not enough, of the ingredient. Only the quantity strategy sets it.
+	Shortfall float64 `json:"shortfall,omitempty"`
+	// Unconvertible marks an ingredient the quantity strategy could not
+	// check because the pantry and recipe units are incompatible.
+	Unconvertible bool `json:"unconvertible,omitempty"`
+
+	// NameSource says how Name was obtained; empty when there is no name.
+	NameSource NameSource `json:"name_source,omitempty"`
+
+	Suggestions []SubstituteSuggestion `json:"suggestions,omitempty"`
+}
+
+// NameSource says how reliable a reported ingredient name is.
+type NameSource string
+
+const (
+	// NameSourceExact is a name the dictionary returned for the ID.
+	NameSourceExact NameSource = "exact"
+	// NameSourceFallback is a stand-in from the [NameFallbackPolicy] for an
+	// ingredient the dictionary could not resolve.
+	NameSourceFallback NameSource = "fallback"
+)
+
+// MatchedIngredient is a required ingredient the pantry satisfies, either
+// directly or via a substitute.
+type MatchedIngredient struct {
+	IngredientID   string  `json:"ingredient_id"`
+	Name           string  `json:"name,omitempty"`
+	Quantity       float64 `json:"quantity"`
+	Unit           string  `json:"unit"`
+	Source         string  `json:"source"`
+	SubstituteID   string  `json:"substitute_id,omitempty"`
+	SubstituteName string  `json:"substitute_name,omitempty"`
+}
+
+// UsedIngredient is pantry stock a recipe would consume: the recipe quantity
+// of a directly stocked ingredient, or of a substitute scaled by its ratio.
+type UsedIngredient struct {
+	IngredientID string  `json:"ingredient_id"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	// ForIngredientID is the recipe ingredient a substitute stands in for.
+	ForIngredientID string `json:"for_ingredient_id,omitempty"`
+}
+
+type MatchResult struct {
+	Recipe      clients.Recipe `json:"recipe"`
+	CoveragePct float64        `json:"coverage_pct"`
+	// OptionalCoveragePct is the weighted percentage of the recipe's optional
+	// ingredients stocked directly in the pantry; 0 when it has none. It only
+	// breaks ranking ties and never affects CanMake.
+	OptionalCoveragePct float64             `json:"optional_coverage_pct"`
+	TotalMinutes        int                 `json:"total_minutes"`
+	MissingIngredients  []MissingIngredient `json:"missing_ingredients"`
+	MatchedIngredients  []MatchedIngredient `json:"matched_ingredients,omitempty"`
+	// UsedIngredients lists the pantry stock each satisfied required
+	// ingredient draws on, set when ScoreOptions.IncludeUsed is.
+	UsedIngredients []UsedIngredient `json:"used_ingredients,omitempty"`
+	CanMake         bool             `json:"can_make"`
+	NearMiss        bool             `json:"near_miss,omitempty"`
+	// SubstitutedWith maps each ingredient a substitute satisfied to the
+	// substitute ID used.
+	SubstitutedWith map[string]string `json:"substituted_with,omitempty"`
+	// SubstituteNotes maps each ingredient in SubstitutedWith whose
+	// substitute came with usage notes, such as "use half as much", to
+	// those notes.
+	SubstituteNotes map[string]string `json:"substitute_notes,omitempty"`
+	// Summary is a one-line description such as "Ready to cook", set when
+	// ScoreOptions.IncludeSummary is.
+	Summary string `json:"summary,omitempty"`
+}
+
+// EmptyRecipePolicy decides how recipes with no ingredients at all are scored.
+type EmptyRecipePolicy string
+
+const (
+	// EmptyRecipeMakeable scores empty recipes as 100% covered (default).
+	EmptyRecipeMakeable EmptyRecipePolicy = "makeable"
+	// EmptyRecipeExclude drops empty recipes as likely malformed and warns.
+	EmptyRecipeExclude EmptyRecipePolicy = "exclude"
+)
+
+// DuplicateRecipePolicy decides which copy of a recipe ID returned more than
+// once by the recipe service is scored.
+type DuplicateRecipePolicy string
+
+const (
+	// DuplicateRecipeKeepFirst scores the first copy of a duplicated recipe
+	// (default).
+	DuplicateRecipeKeepFirst DuplicateRecipePolicy = "first"
+	// DuplicateRecipeKeepLast scores the last copy of a duplicated recipe.
+	DuplicateRecipeKeepLast DuplicateRecipePolicy = "last"
+)
+
+// NullRecipePolicy decides how a recipe service answering with JSON null,
+// rather than a list, is handled.
+type NullRecipePolicy string
+
+const (
+	// NullRecipeEmpty treats null as an empty catalog and warns (default).
+	NullRecipeEmpty NullRecipePolicy = "empty"
+	// NullRecipeError fails the request as an upstream error.
+	NullRecipeError NullRecipePolicy = "error"
+)
+
+// NameFallbackPolicy decides the name reported for an ingredient the
+// dictionary cannot resolve.
+type NameFallbackPolicy string
+
+const (
+	// NameFallbackEmpty leaves the name empty (default).
+	NameFallbackEmpty NameFallbackPolicy = "empty"
+	// NameFallbackID uses the ingredient ID as its name.
+	NameFallbackID NameFallbackPolicy = "id"
+	// NameFallbackPlaceholder uses [UnknownIngredientName].
+	NameFallbackPlaceholder NameFallbackPolicy = "placeholder"
+)
+
+// UnknownIngredientName is the name [NameFallbackPlaceholder] reports.
+const UnknownIngredientName = "Unknown ingredient"
+
+// tracerName names the OpenTelemetry tracer scoring spans come from.
+const tracerName = "github.com/mwhite7112/woodpantry-matching/internal/service"
+
+func (p NameFallbackPolicy) name(ingredientID string) string {
+	switch p {
+	case NameFallbackID:
+		return ingredientID
+	case NameFallbackPlaceholder:
+		return UnknownIngredientName
+	default:
+		return ""
+	}
+}
+
+// ScoreResult is the output of [Service.Score]. Warnings describe best-effort
+// stages that were skipped; Results are still correct for what was computed.
+type ScoreResult struct {
+	Results  []MatchResult
+	Warnings []string
+	// SubstitutionSummary is set when ScoreOptions.SubstitutionSummary is.
+	SubstitutionSummary []SubstitutionUnlock
+	// RemainingPantry is set when ScoreOptions.MealPlan is.
+	RemainingPantry []PantryRemainder
+	// Meta counts the inputs scoring saw, so callers can tell an empty
+	// catalog or pantry apart from a catalog where nothing matched.
+	Meta ScoreMeta
+	// InputsHash fingerprints the fetched pantry and recipe catalog: it
+	// changes whenever either does. Dictionary data is not included.
+	InputsHash string
+}
+
+// ScoreMeta counts the inputs behind a [ScoreResult].
+type ScoreMeta struct {
+	// RecipesConsidered is the number of recipes the recipe service
+	// returned, duplicates included.
+	RecipesConsidered int `json:"recipes_considered"`
+	// RecipesScored is the number of recipes scored, after duplicates and
+	// recipes excluded by the request's filters were dropped.
+	RecipesScored int `json:"recipes_scored"`
+	// PantryItems is the number of items the pantry service returned.
+	PantryItems int `json:"pantry_items"`
+}
+
+// ScoreOptions holds the per-request parameters for [Service.Score].
+type ScoreOptions struct {
+	// AllowSubs counts substitute ingredients in the pantry as available.
+	AllowSubs bool
+	// SubDepth is how many substitute hops AllowSubs follows: 1 (also 0) uses
+	// only direct substitutes, 2 also substitutes of substitutes, up to
+	// MaxSubDepth. Ratios multiply along a chain.
+	SubDepth int
+	// MaxMissing is the number of required ingredients a recipe may miss and
+	// still be returned.
+	MaxMissing int
+	// CanMakeMinCoverage additionally requires this coverage percentage
+	// (0-100) for CanMake, independent of the missing count.
+	CanMakeMinCoverage float64
+	// MinCoverage drops every recipe whose CoveragePct is below this
+	// percentage (0-100), whether or not it can be made or is a near miss.
+	MinCoverage float64
+	// TreatOptionalAsRequired counts optional ingredients toward coverage and
+	// CanMake like required ones.
+	TreatOptionalAsRequired bool
+	// ExcludeAllergens drops recipes with any ingredient, optional or not,
+	// the dictionary tags with any of these allergens, or whose allergens
+	// could not be looked up. Costs one dictionary lookup per distinct
+	// ingredient.
+	ExcludeAllergens []string
+	// IncludeUnmakeable returns every scored recipe, including those with
+	// CanMake false.
+	IncludeUnmakeable bool
+	// MealPlan allocates pantry quantities to recipes in rank order: each
+	// recipe is scored, quantity-aware, against what the recipes ranked above
+	// it left, and consumes its required quantities if it can be made.
+	// ScoreResult.RemainingPantry reports the stock left over.
+	MealPlan bool
+	// PantryConstrained returns only recipes the pantry covers completely. It
+	// takes precedence over MaxMissing, tag thresholds, IncludeUnmakeable and
+	// near-miss options, scoring as if max_missing were 0.
+	PantryConstrained bool
+	// NearMissMaxMissing, when positive, returns recipes that cannot be made
+	// but miss at most this many required ingredients, flagged NearMiss.
+	NearMissMaxMissing int
+	// NearMissMinCoverage, when positive, returns recipes that cannot be made
+	// but reach this coverage percentage (0-100), flagged NearMiss. A recipe
+	// meeting either near-miss threshold is included.
+	NearMissMinCoverage float64
+	// IncludeMatched populates MatchResult.MatchedIngredients.
+	IncludeMatched bool
+	// IncludeSummary sets a human-readable MatchResult.Summary.
+	IncludeSummary bool
+	// IncludeUsed populates MatchResult.UsedIngredients.
+	IncludeUsed bool
+	// IncludeNames names every ingredient of each returned recipe, not just
+	// the missing and matched ones.
+	IncludeNames bool
+	// IncludeHave reports how much of each missing ingredient the pantry
+	// already holds (HaveQuantity/HaveUnit).
+	IncludeHave bool
+	// SuggestSubs lists known substitutes on each missing ingredient,
+	// in-pantry substitutes first. Suggestions are informational: they only
+	// affect CanMake when AllowSubs is also set.
+	SuggestSubs bool
+	// SubstitutionSummary aggregates, across the returned recipes, which
+	// ingredients substitutes covered and in how many recipes.
+	SubstitutionSummary bool
+	// UseGroups treats any in-pantry member of a required ingredient's
+	// substitution group as satisfying it.
+	UseGroups bool
+	// MaxCalories, when positive, drops recipes whose nutrition data exceeds
+	// this many calories. Recipes without nutrition data are kept.
+	MaxCalories float64
+	// MaxTotalMinutes, when set, drops recipes whose prep plus cook time
+	// exceeds this many minutes. Recipes without times count as zero.
+	MaxTotalMinutes *int
+	// Tags, when non-empty, keeps only recipes carrying every listed tag, or
+	// any of them when TagsAny is set.
+	Tags    []string
+	TagsAny bool
+	// ExcludeSubs lists substitutes that must never be used. An entry is either
+	// a substitute ID, excluded for every ingredient, or "ingredientID:substituteID",
+	// excluded only when substituting for that ingredient.
+	ExcludeSubs []string
+	// Prompt is the user's free-text request. Matching keywords re-rank the
+	// candidate set; an empty or whitespace prompt leaves ranking unchanged.
+	Prompt string
+	// RankSeed, when non-empty, deterministically shuffles recipes that tie on
+	// coverage and missing count. The same seed always yields the same order.
+	RankSeed string
+	// Strategy names the registered scoring strategy to apply. Empty selects
+	// the default, StrategyPresence.
+	Strategy string
+	// Servings, when positive, scales each recipe's ingredient quantities
+	// from its base servings to this many before scoring, so quantity checks
+	// and shortfalls reflect the amount cooked. Recipes without base servings
+	// are scored unscaled.
+	Servings int
+	// Pantry, when non-nil, is scored instead of the pantry service's
+	// contents, which are then not fetched. An empty slice is an empty
+	// pantry.
+	Pantry []clients.PantryItem
+	// Staples, when non-nil, replaces the service's pantry staples for this
+	// request; an empty slice scores without staples.
+	Staples []string
+	// Sort selects the result ordering: SortCoverage (default, also empty),
+	// SortUseMost, SortTime, or SortMissing.
+	Sort string
+
+	// skipNames disables dictionary name resolution for internal callers that
+	// only aggregate results.
+	skipNames bool
+	// skipUsage keeps internal callers from counting toward substitute usage.
+	skipUsage bool
+	// extraPantry lists ingredient IDs scored as present on top of the
+	// fetched pantry, in whatever quantity a recipe needs.
+	extraPantry []string
+}
+
+type Service struct {
+	pantry     PantryFetcher
+	recipes    RecipeFetcher
+	dictionary DictionaryFetcher
+
+	tagMaxMissing map[string]int
+	// defaultMaxMissing is the max_missing for requests that do not set one.
+	defaultMaxMissing int
+	scoreBudget       time.Duration
+	maxSubs           int
+	emptyRecipes      EmptyRecipePolicy
+	dupRecipes        DuplicateRecipePolicy
+	versatility       *VersatilityWeights
+	optionalSubs      bool
+	nameFallback      NameFallbackPolicy
+	usage             *substituteUsage
+	subPenalty        float64
+	nullRecipes       NullRecipePolicy
+	// quantityFloor, when set, is the pantry quantity an item must exceed to
+	// count as present.
+	quantityFloor *float64
+	// staples are ingredient IDs always scored as present.
+	staples      []string
+	scorers      map[string]Scorer
+	validateSubs bool
+	// statsSkipZeroReq leaves recipes with no required ingredients out of
+	// Stats.
+	statsSkipZeroReq bool
+	// subsMinCoverage, when positive, is the direct coverage a recipe needs
+	// for its missing ingredients' substitutes to be fetched.
+	subsMinCoverage float64
+	subReport       SubstitutionReportPolicy
+	// maxNames, when positive, caps the ingredient names resolved per call.
+	maxNames int
+	// dictConcurrency bounds in-flight dictionary calls per fan-out.
+	dictConcurrency int
+	// coverageDecimals is the precision of emitted coverage percentages;
+	// negative leaves them unrounded.
+	coverageDecimals int
+}
+
+// Option configures optional Service behaviour.
+type Option func(*Service)
+
+// WithTagMaxMissing maps recipe tags to a per-recipe max_missing threshold.
+// A recipe carrying a mapped tag may miss up to that many required ingredients
+// when it is more lenient than the request's max_missing.
+func WithTagMaxMissing(m map[string]int) Option {
+	return func(s *Service) {
+		s.tagMaxMissing = m
+	}
+}
+
+// WithDefaultMaxMissing sets the max_missing callers should use when a
+// request does not specify one, reported by [Service.DefaultMaxMissing].
+// Negative values are treated as 0.
+func WithDefaultMaxMissing(n int) Option {
+	return func(s *Service) {
+		s.defaultMaxMissing = max(n, 0)
+	}
+}
+
+// DefaultMaxMissing returns the max_missing to use when a request does not
+// set one; 0 unless configured with [WithDefaultMaxMissing].
+func (s *Service) DefaultMaxMissing() int {
+	return s.defaultMaxMissing
+}
+
+// WithScoreBudget bounds the total time of a Score call, including upstream
+// fetches, substitute prefetch, and name resolution.
+func WithScoreBudget(d time.Duration) Option {
+	return func(s *Service) {
+		s.scoreBudget = d
+	}
+}
+
+// WithMaxSubstitutes keeps at most n substitutes per ingredient, preferring
+// those whose ratio is closest to 1:1.
+func WithMaxSubstitutes(n int) Option {
+	return func(s *Service) {
+		s.maxSubs = n
+	}
+}
+
+// WithEmptyRecipePolicy sets how recipes with an empty ingredient list are
+// handled. The default is [EmptyRecipeMakeable].
+func WithEmptyRecipePolicy(p EmptyRecipePolicy) Option {
+	return func(s *Service) {
+		s.emptyRecipes = p
+	}
+}
+
+// WithDuplicateRecipePolicy sets which copy of a duplicated recipe ID is
+// scored. The default is [DuplicateRecipeKeepFirst].
+func WithDuplicateRecipePolicy(p DuplicateRecipePolicy) Option {
+	return func(s *Service) {
+		s.dupRecipes = p
+	}
+}
+
+// WithVersatilityWeights sets the weights of makeable and near-miss recipes in
+// the stats versatility score. The default is 1 and 0.5.
+func WithVersatilityWeights(w VersatilityWeights) Option {
+	return func(s *Service) {
+		s.versatility = &w
+	}
+}
+
+// WithOptionalSubstitutes lets substitutes satisfy optional ingredients when
+// a request counts them as required (treat_optional_as_required). By default
+// substitutes only apply to ingredients the recipe marks as required.
+func WithOptionalSubstitutes(enabled bool) Option {
+	return func(s *Service) {
+		s.optionalSubs = enabled
+	}
+}
+
+// WithNameFallback sets the name reported for ingredients the dictionary
+// cannot resolve. The default is [NameFallbackEmpty].
+func WithNameFallback(p NameFallbackPolicy) Option {
+	return func(s *Service) {
+		s.nameFallback = p
+	}
+}
+
+// WithSubstituteUsage enables or disables counting how often each substitute
+// is used in returned matches. Counting is enabled by default.
+func WithSubstituteUsage(enabled bool) Option {
+	return func(s *Service) {
+		if !enabled {
+			s.usage = nil
+		} else if s.usage == nil {
+			s.usage = newSubstituteUsage()
+		}
+	}
+}
+
+// WithSubstitutionPenalty lowers a recipe's ranking by penalty coverage
+// percentage points for each ingredient it matches via a substitute, so
+// fully stocked recipes rank ahead of heavily substituted ones. Reported
+// coverage and CanMake are unaffected. The default is no penalty.
+func WithSubstitutionPenalty(penalty float64) Option {
+	return func(s *Service) {
+		s.subPenalty = penalty
+	}
+}
+
+// WithMaxNameLookups caps how many distinct ingredient names one scoring call
+// resolves, favouring the top-ranked recipes. Names past the cap are left
+// empty with a warning. n <= 0 means no cap, the default.
+func WithMaxNameLookups(n int) Option {
+	return func(s *Service) {
+		s.maxNames = n
+	}
+}
+
+// DefaultDictionaryConcurrency is how many dictionary calls one fan-out may
+// have in flight when WithDictionaryConcurrency is not set.
+const DefaultDictionaryConcurrency = 8
+
+// WithDictionaryConcurrency bounds how many dictionary calls a substitute,
+// group or name fan-out has in flight at once. n < 1 uses
+// [DefaultDictionaryConcurrency].
+func WithDictionaryConcurrency(n int) Option {
+	return func(s *Service) {
+		s.dictConcurrency = n
+	}
+}
+
+// WithSubstitutionReport sets which substitutes the substitution summary
+// reports. The default is [SubstitutionReportNecessary].
+func WithSubstitutionReport(p SubstitutionReportPolicy) Option {
+	return func(s *Service) {
+		s.subReport = p
+	}
+}
+
+// WithSubstituteMinCoverage only fetches substitutes for recipes whose direct
+// coverage is at least pct percent, saving dictionary calls for recipes that
+// miss most of their ingredients anyway. Zero, the default, fetches for all.
+func WithSubstituteMinCoverage(pct float64) Option {
+	return func(s *Service) {
+		s.subsMinCoverage = pct
+	}
+}
+
+// WithStatsExcludeZeroRequired leaves recipes with no required ingredients,
+// which always score 100%, out of [Service.Stats]. Matching still returns
+// them.
+func WithStatsExcludeZeroRequired(enabled bool) Option {
+	return func(s *Service) {
+		s.statsSkipZeroReq = enabled
+	}
+}
+
+// WithSubstituteValidation drops substitutes whose substitute_id the
+// dictionary does not know. It costs one dictionary lookup per distinct
+// substitute, so it is off by default.
+func WithSubstituteValidation(enabled bool) Option {
+	return func(s *Service) {
+		s.validateSubs = enabled
+	}
+}
+
+// WithNullRecipePolicy sets how a null recipe catalog is handled. The
+// default is [NullRecipeEmpty].
+func WithNullRecipePolicy(p NullRecipePolicy) Option {
+	return func(s *Service) {
+		s.nullRecipes = p
+	}
+}
+
+// WithPantryQuantityFloor treats pantry items whose quantity is at or below
+// floor as absent, so used-up items that were never deleted don't produce
+// false matches; items without a quantity count as 0. Without a floor, items
+// reporting a quantity of 0 or less are absent and items without a quantity
+// are present.
+func WithPantryQuantityFloor(floor float64) Option {
+	return func(s *Service) {
+		s.quantityFloor = &floor
+	}
+}
+
+// WithPantryStaples treats ids as always in the pantry, for ingredients such
+// as salt and water that are on hand but rarely tracked. Staples count as
+// present in unlimited quantity. A request's ScoreOptions.Staples replaces
+// the list.
+func WithPantryStaples(ids []string) Option {
+	return func(s *Service) {
+		s.staples = ids
+	}
+}
+
+// DefaultCoverageDecimals is how many decimal places emitted coverage
+// percentages keep when WithCoverageDecimals is not set.
+const DefaultCoverageDecimals = 1
+
+// WithCoverageDecimals rounds the coverage percentages the service returns to
+// n decimal places, e.g. 66.7 rather than 66.66666666666667. Ranking and
+// thresholds always use the unrounded values. A negative n returns them
+// unrounded.
+func WithCoverageDecimals(n int) Option {
+	return func(s *Service) {
+		s.coverageDecimals = n
+	}
+}
+
+func New(pantry PantryFetcher, recipes RecipeFetcher, dictionary DictionaryFetcher, opts ...Option) *Service {
+	s := &Service{
+		pantry:           pantry,
+		recipes:          recipes,
+		dictionary:       dictionary,
+		usage:            newSubstituteUsage(),
+		scorers:          defaultScorers(),
+		dictConcurrency:  DefaultDictionaryConcurrency,
+		coverageDecimals: DefaultCoverageDecimals,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Score fetches live pantry and recipe data, scores each recipe by ingredient
+// coverage, and returns results ranked by coverage descending.
+// Only recipes with missing_count <= opts.MaxMissing are included in the result.
+//
+// Score honours the context deadline and, if configured, the service's overall
+// score budget. When too little of the budget remains, substitute prefetch and
+// name resolution are skipped and a warning is returned instead of an error.
+// Coverage percentages are rounded for output; see [WithCoverageDecimals].
+func (s *Service) Score(ctx context.Context, opts ScoreOptions) (*ScoreResult, error) {
+	res, err := s.scoreRaw(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.roundCoverage(res.Results)
+	return res, nil
+}
+
+// scoreRaw is [Service.Score] without output rounding, for callers that
+// compare or bucket coverage.
+func (s *Service) scoreRaw(ctx context.Context, opts ScoreOptions) (*ScoreResult, error) {
+	if _, err := s.scorer(opts.Strategy); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "matching.Score")
+	defer span.End()
+
+	pantryItems, recipes, warnings, err := s.fetchInputs(ctx, opts.Pantry)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	hash := inputsHash(pantryItems, recipes)
+	res := s.scoreCatalog(ctx, pantryItems, recipes, opts, warnings)
+	res.InputsHash = hash
+
+	missing := 0
+	for _, r := range res.Results {
+		missing += len(r.MissingIngredients)
+	}
+	span.SetAttributes(
+		attribute.Int("matching.recipes", res.Meta.RecipesScored),
+		attribute.Int("matching.results", len(res.Results)),
+		attribute.Int("matching.missing", missing),
+	)
+	return res, nil
+}
+
+// roundCoverage rounds each result's coverage percentages to the service's
+// precision for output. Call it only once results are ranked and filtered.
+func (s *Service) roundCoverage(results []MatchResult) {
+	for i := range results {
+		results[i].CoveragePct = s.roundPct(results[i].CoveragePct)
+		results[i].OptionalCoveragePct = s.roundPct(results[i].OptionalCoveragePct)
+	}
+}
+
+// roundPct rounds pct to the service's coverage precision.
+func (s *Service) roundPct(pct float64) float64 {
+	if s.coverageDecimals < 0 {
+		return pct
+	}
+	scale := math.Pow(10, float64(s.coverageDecimals))
+	return math.Round(pct*scale) / scale
+}
+
+// inputsHash returns a hex FNV-64a hash of the JSON encoding of the pantry
+// and recipes, or "" if they cannot be encoded.
+func inputsHash(pantryItems []clients.PantryItem, recipes []clients.Recipe) string {
+	h := fnv.New64a()
+	enc := json.NewEncoder(h)
+	if enc.Encode(pantryItems) != nil || enc.Encode(recipes) != nil {
+		return ""
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// withBudget bounds ctx by the service's score budget, if configured.
+func (s *Service) withBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.scoreBudget > 0 {
+		return context.WithTimeout(ctx, s.scoreBudget)
+	}
+	return ctx, func() {}
+}
+
+// Upstream failures that abort scoring. Each wraps the underlying cause.
+// Dictionary failures never abort scoring; names and substitutes are
+// best-effort.
+var (
+	ErrPantryUnavailable  = errors.New("pantry service unavailable")
+	ErrRecipesUnavailable = errors.New("recipe service unavailable")
+)
+
+// fetchCatalog fetches the live pantry and recipe catalog concurrently,
+// returning any warnings about the upstream data. Ingredients repeated within
+// a recipe are collapsed into one. The first failure cancels the other fetch
+// and is the error returned.
+func (s *Service) fetchCatalog(ctx context.Context) ([]clients.PantryItem, []clients.Recipe, []string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	var (
+		pantryItems []clients.PantryItem
+		recipes     []clients.Recipe
+		warning     string
+		wg          sync.WaitGroup
+	)
+	wg.Go(func() {
+		var err error
+		if pantryItems, err = s.pantry.GetPantry(ctx); err != nil {
+			fail(fmt.Errorf("%w: %w", ErrPantryUnavailable, err))
+		}
+	})
+	wg.Go(func() {
+		var err error
+		if recipes, warning, err = s.fetchRecipes(ctx); err != nil {
+			fail(err)
+		}
+	})
+	wg.Wait()
+	if firstErr != nil {
+		return nil, nil, nil, firstErr
+	}
+
+	recipes, warnings := prepareRecipes(ctx, recipes, warning)
+	return pantryItems, recipes, warnings, nil
+}
+
+// prepareRecipes normalizes a fetched catalog, returning it with warnings for
+// fetchWarning, if set, and any duplicate ingredients it collapsed.
+func prepareRecipes(ctx context.Context, recipes []clients.Recipe, fetchWarning string) ([]clients.Recipe, []string) {
+	var warnings []string
+	if fetchWarning != "" {
+		warnings = append(warnings, fetchWarning)
+	}
+
+	recipes, collapsed, conflicts := normalizeRecipes(recipes)
+	if len(collapsed) > 0 {
+		slog.Default().DebugContext(ctx, "collapsed duplicate recipe ingredients",
+			"recipe_ids", collapsed, "conflicting_units", conflicts)
+		warnings = append(warnings, fmt.Sprintf("collapsed duplicate ingredients in %d recipes", len(collapsed)))
+	}
+	if len(conflicts) > 0 {
+		warnings = append(warnings, fmt.Sprintf(
+			"%d duplicate recipe ingredients had incompatible units; kept the first quantity", len(conflicts)))
+	}
+	return recipes, warnings
+}
+
+// fetchInputs is fetchCatalog for a request that may bring its own pantry:
+// with inline non-nil only the recipe catalog is fetched, and inline is
+// scored as the pantry.
+func (s *Service) fetchInputs(
+	ctx context.Context,
+	inline []clients.PantryItem,
+) ([]clients.PantryItem, []clients.Recipe, []string, error) {
+	if inline == nil {
+		return s.fetchCatalog(ctx)
+	}
+	recipes, warning, err := s.fetchRecipes(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	recipes, warnings := prepareRecipes(ctx, recipes, warning)
+	return inline, recipes, warnings, nil
+}
+
+// fetchRecipes fetches the recipe catalog. A null catalog is handled by the
+// service's [NullRecipePolicy]; under the default policy it is treated as
+// empty and a warning is returned.
+func (s *Service) fetchRecipes(ctx context.Context) ([]clients.Recipe, string, error) {
+	recipes, err := s.recipes.GetRecipes(ctx)
+	if errors.Is(err, clients.ErrNullResponse) && s.nullRecipes != NullRecipeError {
+		return []clients.Recipe{}, "recipe service returned null; treating as an empty catalog", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w", ErrRecipesUnavailable, err)
+	}
+	return recipes, "", nil
+}
+
+// scoreCatalog scores already-fetched pantry and recipe data under opts.
+func (s *Service) scoreCatalog(
+	ctx context.Context,
+	pantryItems []clients.PantryItem,
+	recipes []clients.Recipe,
+	opts ScoreOptions,
+	warnings []string,
+) *ScoreResult {
+	logger := slog.Default()
+
+	logger.DebugContext(
+		ctx,
+		"scoring started",
+		"pantry_items",
+		len(pantryItems),
+		"recipes",
+		len(recipes),
+		"allow_subs",
+		opts.AllowSubs,
+		"max_missing",
+		opts.MaxMissing,
+	)
+
+	if opts.PantryConstrained {
+		opts = opts.constrained()
+	}
+
+	considered := len(recipes)
+	recipes, pantrySet, prepWarnings := s.prepareCatalog(ctx, pantryItems, recipes, opts)
+	warnings = append(warnings, prepWarnings...)
+
+	var subsMap, suggestionSubs map[string][]clients.IngredientSubstitute
+	needSubs := opts.AllowSubs || opts.UseGroups || opts.SuggestSubs
+	if needSubs && !budgetRemains(ctx) {
+		warnings = append(warnings, "substitute lookup skipped: score budget exhausted")
+	} else if needSubs {
+		subsMap, suggestionSubs = s.loadSubstitutes(ctx, recipes, pantrySet, opts)
+	}
+
+	filtered, remaining := s.rankRecipes(recipes, pantryItems, pantrySet, subsMap, suggestionSubs, opts)
+
+	if s.usage != nil && !opts.skipUsage {
+		s.usage.record(filtered)
+	}
+	if !opts.IncludeMatched && !opts.SubstitutionSummary {
+		clearMatched(filtered)
+	}
+
+	// Best-effort: resolve ingredient names from dictionary for missing and matched ingredients.
+	// Errors are silently ignored — the caller still receives results without names.
+	switch {
+	case opts.skipNames:
+		// Aggregating callers never show names.
+	case budgetRemains(ctx):
+		if n := s.resolveNames(ctx, filtered, opts.IncludeNames); n > 0 {
+			warnings = append(warnings, cappedNamesWarning(n))
+		}
+	default:
+		warnings = append(warnings, "name resolution skipped: score budget exhausted")
+	}
+
+	if opts.IncludeSummary {
+		attachSummaries(filtered)
+	}
+
+	var summary []SubstitutionUnlock
+	if opts.SubstitutionSummary {
+		summary = summarizeSubstitutions(filtered)
+		if s.subReport == SubstitutionReportAvailable && (opts.AllowSubs || opts.UseGroups) {
+			if budgetRemains(ctx) {
+				unused := s.loadUnusedSubstitutes(ctx, filtered, opts)
+				summary = noteUnusedSubstitutes(summary, filtered, unused, pantrySet)
+			} else {
+				warnings = append(warnings, "unused substitute lookup skipped: score budget exhausted")
+			}
+		}
+		if !opts.IncludeMatched {
+			clearMatched(filtered)
+		}
+	}
+
+	for _, w := range warnings {
+		logger.WarnContext(ctx, w)
+	}
+	logger.DebugContext(ctx, "scoring complete", "total_recipes", len(recipes), "matched", len(filtered))
+
+	return &ScoreResult{
+		Results:             filtered,
+		Warnings:            warnings,
+		SubstitutionSummary: summary,
+		RemainingPantry:     remaining,
+		Meta: ScoreMeta{
+			RecipesConsidered: considered,
+			RecipesScored:     len(recipes),
+			PantryItems:       len(pantryItems),
+		},
+	}
+}
+
+// prepareCatalog applies opts to a fetched catalog before scoring: it drops
+// duplicate, filtered, allergen-bearing and (under [EmptyRecipeExclude])
+// empty recipes, scales servings, and builds the pantry set with staples. It
+// returns the recipes to score, the pantry set and any warnings. Allergens are
+// only checked when the service has a dictionary.
+func (s *Service) prepareCatalog(
+	ctx context.Context,
+	pantryItems []clients.PantryItem,
+	recipes []clients.Recipe,
+	opts ScoreOptions,
+) ([]clients.Recipe, map[string]bool, []string) {
+	logger := slog.Default()
+	var warnings []string
+
+	recipes, duplicates := dedupeRecipes(recipes, s.dupRecipes)
+	if len(duplicates) > 0 {
+		logger.DebugContext(ctx, "dropped duplicate recipes", "recipe_ids", duplicates)
+		warnings = append(warnings, fmt.Sprintf("dropped %d duplicate recipes", len(duplicates)))
+	}
+
+	recipes = filterRecipes(recipes, opts)
+	if opts.Servings > 0 {
+		var unscaled int
+		recipes, unscaled = scaleServings(recipes, opts.Servings)
+		if unscaled > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d recipes have no base servings and were not scaled", unscaled))
+		}
+	}
+	allergens := slices.DeleteFunc(slices.Clone(opts.ExcludeAllergens), func(a string) bool {
+		return strings.TrimSpace(a) == ""
+	})
+	if len(allergens) > 0 && s.dictionary != nil {
+		var dropped []string
+		var warning string
+		recipes, dropped, warning = s.excludeAllergens(ctx, recipes, allergens)
+		if len(dropped) > 0 {
+			logger.DebugContext(ctx, "excluded recipes with allergens", "recipe_ids", dropped)
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	if s.emptyRecipes == EmptyRecipeExclude {
+		var excluded []string
+		recipes, excluded = excludeEmptyRecipes(recipes)
+		if len(excluded) > 0 {
+			logger.DebugContext(ctx, "excluded recipes with no ingredients", "recipe_ids", excluded)
+			warnings = append(warnings, fmt.Sprintf("excluded %d recipes with no ingredients", len(excluded)))
+		}
+	}
+	if opts.Strategy == StrategyQuantity || opts.MealPlan {
+		if mixed := mixedUnitIngredients(buildPantryStock(pantryItems)); len(mixed) > 0 {
+			logger.DebugContext(ctx, "pantry ingredients stocked in incompatible units", "ingredient_ids", mixed)
+			warnings = append(warnings, fmt.Sprintf(
+				"%d pantry ingredients are stocked in incompatible units; only quantities convertible to the recipe unit count",
+				len(mixed)))
+		}
+	}
+	pantrySet := buildPantrySet(pantryItems, s.quantityFloor)
+	for id := range s.staplesFor(opts) {
+		pantrySet[id] = true
+	}
+	return recipes, pantrySet, warnings
+}
+
+// rankRecipes is the in-memory core of scoring: it scores recipes against
+// the pantry, ranks them, applies the meal plan, near-miss and coverage
+// rules, and returns the includable results with the meal plan's remaining
+// pantry. It makes no upstream calls; substitutes come from subsMap (for
+// scoring) and suggestionSubs (for suggest_subs).
+func (s *Service) rankRecipes(
+	recipes []clients.Recipe,
+	pantryItems []clients.PantryItem,
+	pantrySet map[string]bool,
+	subsMap, suggestionSubs map[string][]clients.IngredientSubstitute,
+	opts ScoreOptions,
+) ([]MatchResult, []PantryRemainder) {
+	scorer, err := s.scorer(opts.Strategy)
+	if err != nil {
+		// Callers validate the strategy before fetching.
+		scorer = presenceScorer{}
+	}
+	rulesFor := func(recipe clients.Recipe) scoreRules {
+		rules := scoreRules{
+			maxMissing:         opts.MaxMissing,
+			minCoverage:        opts.CanMakeMinCoverage,
+			optionalAsRequired: opts.TreatOptionalAsRequired,
+			optionalSubs:       s.optionalSubs,
+			includeUsed:        opts.IncludeUsed,
+		}
+		if !opts.PantryConstrained {
+			rules.maxMissing = s.effectiveMaxMissing(recipe, rules.maxMissing)
+			rules.tagMinCoverage, rules.hasTagMinCoverage = tagMinCoverage(recipe)
+		}
+		return rules
+	}
+	subsFor := func(recipe clients.Recipe) map[string][]clients.IngredientSubstitute {
+		if !opts.AllowSubs {
+			return subsMap
+		}
+		return withRecipeSubstitutes(recipe, subsMap, opts.ExcludeSubs)
+	}
+	stock := buildPantryStock(pantryItems)
+	in := scoreInput{pantrySet: pantrySet, stock: stock, staples: s.staplesFor(opts)}
+	results := make([]MatchResult, 0, len(recipes))
+	for _, recipe := range recipes {
+		in.rules = rulesFor(recipe)
+		in.subsMap = subsFor(recipe)
+		results = append(results, scorer.score(recipe, in))
+	}
+
+	sortResults(results, opts.RankSeed, s.subPenalty)
+	sortBy(results, opts.Sort)
+	rerankByPrompt(results, promptTerms(opts.Prompt))
+
+	var remaining []PantryRemainder
+	if opts.MealPlan {
+		results, remaining = planMeals(results, in, rulesFor, subsFor)
+	}
+
+	if opts.IncludeHave {
+		attachHaveQuantities(results, stock)
+	}
+	if opts.SuggestSubs {
+		attachSuggestions(results, suggestionSubs, pantrySet)
+	}
+
+	markNearMisses(results, opts.NearMissMaxMissing, opts.NearMissMinCoverage)
+
+	// Filter to only includable recipes (can_make == true or near misses)
+	// that reach the coverage floor.
+	filtered := results
+	if !opts.IncludeUnmakeable || opts.MinCoverage > 0 {
+		filtered = make([]MatchResult, 0, len(results))
+		for _, r := range results {
+			if (opts.IncludeUnmakeable || r.CanMake || r.NearMiss) && r.CoveragePct >= opts.MinCoverage {
+				filtered = append(filtered, r)
+			}
+		}
+	}
+	return filtered, remaining
+}
+
+// clearMatched drops MatchedIngredients from results that did not ask for
+// them.
+func clearMatched(results []MatchResult) {
+	for i := range results {
+		results[i].MatchedIngredients = nil
+	}
+}
+
+// budgetRemains reports whether enough time is left before the context
+// deadline to start a best-effort dictionary stage.
+func budgetRemains(ctx context.Context) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return true
+	}
+	return time.Until(deadline) >= bestEffortReserve
+}
+
+// dedupeRecipes keeps one copy of each recipe ID, chosen by policy, and
+// returns the IDs that appeared more than once. Kept recipes stay in catalog
+// order.
+func dedupeRecipes(recipes []clients.Recipe, policy DuplicateRecipePolicy) ([]clients.Recipe, []string) {
+	keep := make(map[string]int, len(recipes))
+	var duplicates []string
+	for i, recipe := range recipes {
+		if _, seen := keep[recipe.ID]; seen {
+			if policy == DuplicateRecipeKeepLast {
+				keep[recipe.ID] = i
+			}
+			duplicates = append(duplicates, recipe.ID)
+			continue
+		}
+		keep[recipe.ID] = i
+	}
+	if len(duplicates) == 0 {
+		return recipes, nil
+	}
+
+	kept := make([]clients.Recipe, 0, len(keep))
+	for i, recipe := range recipes {
+		if keep[recipe.ID] == i {
+			kept = append(kept, recipe)
+		}
+	}
+	return kept, slices.Compact(slices.Sorted(slices.Values(duplicates)))
+}
+
+// constrained returns opts with every option that would admit a recipe the
+// pantry cannot fully cover switched off.
+func (opts ScoreOptions) constrained() ScoreOptions {
+	opts.MaxMissing = 0
+	opts.IncludeUnmakeable = false
+	opts.NearMissMaxMissing = 0
+	opts.NearMissMinCoverage = 0
+	return opts
+}
+
+// filterRecipes drops recipes excluded by request filters before scoring.
+func filterRecipes(recipes []clients.Recipe, opts ScoreOptions) []clients.Recipe {
+	if opts.MaxCalories <= 0 && opts.MaxTotalMinutes == nil && len(opts.Tags) == 0 {
+		return recipes
+	}
+
+	filtered := make([]clients.Recipe, 0, len(recipes))
+	for _, recipe := range recipes {
+		if opts.MaxCalories > 0 && recipe.Nutrition != nil && recipe.Nutrition.Calories > opts.MaxCalories {
+			continue
+		}
+		if opts.MaxTotalMinutes != nil && totalMinutes(recipe) > *opts.MaxTotalMinutes {
+			continue
+		}
+		if !hasTags(recipe, opts.Tags, opts.TagsAny) {
+			continue
+		}
+		filtered = append(filtered, recipe)
+	}
+	return filtered
+}
+
+// hasTags reports whether recipe carries all of tags, or any of them when
+// anyTag is set. An empty tags list matches every recipe.
+func hasTags(recipe clients.Recipe, tags []string, anyTag bool) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for _, tag := range tags {
+		if slices.Contains(recipe.Tags, tag) == anyTag {
+			return anyTag
+		}
+	}
+	return !anyTag
+}
+
+// excludeEmptyRecipes drops recipes with no ingredients and returns their IDs.
+func excludeEmptyRecipes(recipes []clients.Recipe) ([]clients.Recipe, []string) {
+	kept := make([]clients.Recipe, 0, len(recipes))
+	var excluded []string
+	for _, recipe := range recipes {
+		if len(recipe.Ingredients) == 0 {
+			excluded = append(excluded, recipe.ID)
+			continue
+		}
+		kept = append(kept, recipe)
+	}
+	return kept, excluded
+}
+
+// effectiveMaxMissing returns the max_missing threshold for a recipe: the
+// request value, raised by any configured tag threshold the recipe carries.
+func (s *Service) effectiveMaxMissing(recipe clients.Recipe, maxMissing int) int {
+	effective := maxMissing
+	for _, tag := range recipe.Tags {
+		if n, ok := s.tagMaxMissing[tag]; ok && n > effective {
+			effective = n
+		}
+	}
+	return effective
+}
+
+// minCoverageTagPrefix marks a recipe tag carrying the recipe's own CanMake
+// coverage threshold, e.g. "min_coverage:80".
+const minCoverageTagPrefix = "min_coverage:"
+
+// tagMinCoverage returns the coverage percentage set by the recipe's first
+// well-formed min_coverage:N tag, where N is a number between 0 and 100.
+// Malformed values are ignored; ok is false when no tag applies.
+func tagMinCoverage(recipe clients.Recipe) (pct float64, ok bool) {
+	for _, tag := range recipe.Tags {
+		v, found := strings.CutPrefix(tag, minCoverageTagPrefix)
+		if !found {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(n) || n < 0 || n > coveragePercentScale {
+			continue
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+// Result orderings for ScoreOptions.Sort.
+const (
+	// SortCoverage ranks by coverage, fewest missing, then faster recipes.
+	SortCoverage = "coverage"
+	// SortUseMost ranks recipes using the most distinct pantry ingredients
+	// first, falling back to the coverage ordering for ties.
+	SortUseMost = "use_most"
+	// SortTime ranks quicker recipes (lower TotalMinutes) first, unknown
+	// times last, falling back to the coverage ordering for ties.
+	SortTime = "time"
+	// SortMissing ranks recipes missing the fewest required ingredients
+	// first, falling back to the coverage ordering for ties.
+	SortMissing = "missing"
+)
+
+// sortComparators holds the orderings a ScoreOptions.Sort mode applies on
+// top of the coverage ordering, keyed by mode. Each returns a negative number
+// when a ranks before b; results it ties keep their coverage order.
+var sortComparators = map[string]func(a, b MatchResult) int{
+	SortUseMost: func(a, b MatchResult) int {
+		return cmp.Compare(pantryIngredientsUsed(b), pantryIngredientsUsed(a))
+	},
+	SortTime: compareTotalMinutes,
+	SortMissing: func(a, b MatchResult) int {
+		return cmp.Compare(len(a.MissingIngredients), len(b.MissingIngredients))
+	},
+}
 
-type MissingIngredient struct {
-	IngredientID string  `json:"ingredient_id"`
-	Name         string  `json:"name,omitempty"`
-	Quantity     float64 `json:"quantity"`
-	Unit         string  `json:"unit"`
+// SortModes returns the accepted ScoreOptions.Sort values.
+func SortModes() []string {
+	return []string{SortCoverage, SortUseMost, SortTime, SortMissing}
 }
 
-type MatchResult struct {
-	Recipe             clients.Recipe      `json:"recipe"`
-	CoveragePct        float64             `json:"coverage_pct"`
-	MissingIngredients []MissingIngredient `json:"missing_ingredients"`
-	CanMake            bool                `json:"can_make"`
+// sortResults orders results by coverage descending, less subPenalty
+// percentage points per substituted ingredient, then fewest missing, then
+// highest OptionalCoveragePct as tiebreakers. A non-empty seed breaks
+// remaining ties by a seeded hash of the recipe ID, so variants can be
+// compared reproducibly. Otherwise faster recipes (lower TotalMinutes) come
+// first, with unknown times last. Recipe ID settles any remaining tie either
+// way, so the order never depends on the catalog's order and pages stay
+// stable across requests.
+func sortResults(results []MatchResult, seed string, subPenalty float64) {
+	var shuffle func(recipeID string) uint64
+	if seed != "" {
+		shuffle = func(recipeID string) uint64 { return seededRank(seed, recipeID) }
+	}
+	sortResultsBy(results, shuffle, subPenalty)
 }
 
-type Service struct {
-	pantry     PantryFetcher
-	recipes    RecipeFetcher
-	dictionary DictionaryFetcher
+// sortResultsBy is sortResults with the seeded hash given as shuffle, or nil
+// without a seed.
+func sortResultsBy(results []MatchResult, shuffle func(recipeID string) uint64, subPenalty float64) {
+	sort.SliceStable(results, func(i, j int) bool {
+		ri, rj := rankScore(results[i], subPenalty), rankScore(results[j], subPenalty)
+		if ri != rj {
+			return ri > rj
+		}
+		if len(results[i].MissingIngredients) != len(results[j].MissingIngredients) {
+			return len(results[i].MissingIngredients) < len(results[j].MissingIngredients)
+		}
+		if oi, oj := results[i].OptionalCoveragePct, results[j].OptionalCoveragePct; oi != oj {
+			return oi > oj
+		}
+		if shuffle != nil {
+			if si, sj := shuffle(results[i].Recipe.ID), shuffle(results[j].Recipe.ID); si != sj {
+				return si < sj
+			}
+		} else if c := compareTotalMinutes(results[i], results[j]); c != 0 {
+			return c < 0
+		}
+		return results[i].Recipe.ID < results[j].Recipe.ID
+	})
 }
 
-func New(pantry PantryFetcher, recipes RecipeFetcher, dictionary DictionaryFetcher) *Service {
-	return &Service{pantry: pantry, recipes: recipes, dictionary: dictionary}
+// sortBy stably reorders coverage-sorted results by the comparator for mode.
+// The coverage mode and unknown modes leave results as they are.
+func sortBy(results []MatchResult, mode string) {
+	if compare, ok := sortComparators[mode]; ok {
+		slices.SortStableFunc(results, compare)
+	}
 }
 
-// Score fetches live pantry and recipe data, scores each recipe by ingredient
-// coverage, and returns results ranked by coverage descending.
-// Only recipes with missing_count <= maxMissing are included in the result.
-func (s *Service) Score(ctx context.Context, allowSubs bool, maxMissing int) ([]MatchResult, error) {
-	logger := slog.Default()
+// compareTotalMinutes orders quicker recipes first. A TotalMinutes of zero
+// means the time is unknown and sorts last.
+func compareTotalMinutes(a, b MatchResult) int {
+	switch ta, tb := a.TotalMinutes, b.TotalMinutes; {
+	case ta == tb:
+		return 0
+	case ta == 0:
+		return 1
+	case tb == 0:
+		return -1
+	default:
+		return cmp.Compare(ta, tb)
+	}
+}
 
-	pantryItems, err := s.pantry.GetPantry(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("fetch pantry: %w", err)
+// pantryIngredientsUsed counts the distinct pantry ingredients r consumes,
+// whether matched directly or as a substitute.
+func pantryIngredientsUsed(r MatchResult) int {
+	ids := make(map[string]bool, len(r.MatchedIngredients))
+	for _, m := range r.MatchedIngredients {
+		if m.Source == MatchSourceSubstitute {
+			ids[m.SubstituteID] = true
+		} else {
+			ids[m.IngredientID] = true
+		}
 	}
+	return len(ids)
+}
 
-	recipes, err := s.recipes.GetRecipes(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("fetch recipes: %w", err)
+// rankScore is the coverage used for ranking: CoveragePct reduced by
+// subPenalty for each ingredient matched via a substitute.
+func rankScore(r MatchResult, subPenalty float64) float64 {
+	if subPenalty <= 0 {
+		return r.CoveragePct
+	}
+	subs := 0
+	for _, m := range r.MatchedIngredients {
+		if m.Source == MatchSourceSubstitute {
+			subs++
+		}
 	}
+	return r.CoveragePct - subPenalty*float64(subs)
+}
 
-	logger.DebugContext(
-		ctx,
-		"scoring started",
-		"pantry_items",
-		len(pantryItems),
-		"recipes",
-		len(recipes),
-		"allow_subs",
-		allowSubs,
-		"max_missing",
-		maxMissing,
-	)
+func seededRank(seed, recipeID string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(seed + "\x00" + recipeID)) //nolint:errcheck
+	return h.Sum64()
+}
 
-	pantrySet := buildPantrySet(pantryItems)
+// pantryStock is the total quantity of one ingredient held in the pantry, in
+// the unit of the first item seen. Items in units that cannot be converted to
+// it are kept apart in incompatible, summed per unit, rather than added as if
+// the units matched.
+type pantryStock struct {
+	Quantity     float64
+	Unit         string
+	incompatible []pantryStock
+}
 
-	subsMap := make(map[string][]clients.IngredientSubstitute)
-	if allowSubs {
-		subsMap = s.prefetchSubstitutes(ctx, recipes, pantrySet)
+// add adds qty in unit to the first entry of s it converts to, or keeps it
+// apart as a new entry.
+func (s *pantryStock) add(qty float64, unit string) {
+	if c, err := ConvertQuantity(qty, unit, s.Unit); err == nil {
+		s.Quantity += c
+		return
 	}
-
-	results := make([]MatchResult, 0, len(recipes))
-	for _, recipe := range recipes {
-		results = append(results, scoreRecipe(recipe, pantrySet, subsMap, maxMissing))
+	for i := range s.incompatible {
+		if c, err := ConvertQuantity(qty, unit, s.incompatible[i].Unit); err == nil {
+			s.incompatible[i].Quantity += c
+			return
+		}
 	}
+	s.incompatible = append(s.incompatible, pantryStock{Quantity: qty, Unit: unit})
+}
 
-	// Sort by coverage descending, then fewest missing as tiebreaker.
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].CoveragePct != results[j].CoveragePct {
-			return results[i].CoveragePct > results[j].CoveragePct
+// buildPantryStock sums pantry quantities per ingredient ID in the unit of
+// the first item seen, converting later items to it where possible.
+func buildPantryStock(pantryItems []clients.PantryItem) map[string]pantryStock {
+	stock := make(map[string]pantryStock, len(pantryItems))
+	for _, item := range pantryItems {
+		st, ok := stock[item.IngredientID]
+		if !ok {
+			stock[item.IngredientID] = pantryStock{Quantity: item.Quantity, Unit: item.Unit}
+			continue
 		}
-		return len(results[i].MissingIngredients) < len(results[j].MissingIngredients)
-	})
+		st.add(item.Quantity, item.Unit)
+		stock[item.IngredientID] = st
+	}
+	return stock
+}
 
-	// Filter to only includable recipes (can_make == true).
-	filtered := make([]MatchResult, 0, len(results))
-	for _, r := range results {
-		if r.CanMake {
-			filtered = append(filtered, r)
+// mixedUnitIngredients returns the IDs of ingredients stocked in units that
+// do not convert to each other, sorted.
+func mixedUnitIngredients(stock map[string]pantryStock) []string {
+	var ids []string
+	for id, st := range stock {
+		if len(st.incompatible) > 0 {
+			ids = append(ids, id)
 		}
 	}
+	slices.Sort(ids)
+	return ids
+}
 
-	// Best-effort: resolve ingredient names from dictionary for missing ingredients.
-	// Errors are silently ignored — the caller still receives results without names.
-	s.resolveNames(ctx, filtered)
-
-	logger.DebugContext(ctx, "scoring complete", "total_recipes", len(recipes), "matched", len(filtered))
+// attachHaveQuantities fills HaveQuantity/HaveUnit on missing ingredients
+// that are partially stocked in the pantry.
+func attachHaveQuantities(results []MatchResult, stock map[string]pantryStock) {
+	for i := range results {
+		for j := range results[i].MissingIngredients {
+			m := &results[i].MissingIngredients[j]
+			if st, ok := stock[m.IngredientID]; ok {
+				m.HaveQuantity = st.Quantity
+				m.HaveUnit = st.Unit
+			}
+		}
+	}
+}
 
-	return filtered, nil
+// staplesFor returns the ingredients scored as present with unlimited stock
+// under opts: the pantry staples that apply, plus any extraPantry IDs, which
+// are hypothetical purchases of whatever amount a recipe needs.
+func (s *Service) staplesFor(opts ScoreOptions) map[string]bool {
+	ids := s.staples
+	if opts.Staples != nil {
+		ids = opts.Staples
+	}
+	staples := make(map[string]bool, len(ids)+len(opts.extraPantry))
+	for _, id := range slices.Concat(ids, opts.extraPantry) {
+		if id != "" {
+			staples[id] = true
+		}
+	}
+	return staples
 }
 
-func buildPantrySet(pantryItems []clients.PantryItem) map[string]bool {
+// buildPantrySet returns the IDs of ingredients present in the pantry. When
+// floor is non-nil, items whose quantity does not exceed it are skipped;
+// otherwise only items reporting a quantity of 0 or less are, so a used-up
+// item does not cover a recipe ingredient but one without a quantity does.
+func buildPantrySet(pantryItems []clients.PantryItem, floor *float64) map[string]bool {
 	pantrySet := make(map[string]bool, len(pantryItems))
 	for _, item := range pantryItems {
+		if floor != nil && item.Quantity <= *floor {
+			continue
+		}
+		if floor == nil && item.HasQuantity() && item.Quantity <= 0 {
+			continue
+		}
 		pantrySet[item.IngredientID] = true
 	}
 	return pantrySet
 }
 
-func (s *Service) prefetchSubstitutes(
+// loadSubstitutes fetches substitute data for every missing required
+// ingredient. It returns the substitutes scoring may apply (pairwise only when
+// allow_subs is set, followed sub_depth hops, plus substitution groups when
+// use_groups is set) and, when suggest_subs is set, every known direct
+// substitute for suggestions. Excluded substitutes are removed from both.
+func (s *Service) loadSubstitutes(
 	ctx context.Context,
 	recipes []clients.Recipe,
 	pantrySet map[string]bool,
+	opts ScoreOptions,
+) (scoring, suggestions map[string][]clients.IngredientSubstitute) {
+	missingIDs := s.substituteCandidates(recipes, pantrySet, opts)
+
+	var pairs map[string][]clients.IngredientSubstitute
+	if opts.AllowSubs || opts.SuggestSubs {
+		pairs = s.prefetchSubstitutes(ctx, missingIDs)
+		if s.validateSubs {
+			s.dropUnknownSubstitutes(ctx, pairs)
+		}
+	}
+	var groups map[string]*clients.SubstitutionGroup
+	if opts.UseGroups {
+		groups = s.prefetchGroups(ctx, missingIDs)
+	}
+
+	scoring = make(map[string][]clients.IngredientSubstitute)
+	if opts.AllowSubs {
+		scoring = cloneSubstitutes(pairs)
+		if opts.SubDepth > 1 {
+			chains := s.prefetchSubstituteChains(ctx, pairs, missingIDs, opts.SubDepth)
+			scoring = expandSubstituteChains(chains, opts.SubDepth)
+		}
+	}
+	mergeGroupSubstitutes(scoring, groups)
+	removeExcludedSubstitutes(scoring, opts.ExcludeSubs)
+
+	if opts.SuggestSubs {
+		suggestions = cloneSubstitutes(pairs)
+		mergeGroupSubstitutes(suggestions, groups)
+		removeExcludedSubstitutes(suggestions, opts.ExcludeSubs)
+	}
+	return scoring, suggestions
+}
+
+// substituteCandidates returns the missing ingredients worth looking up
+// substitutes for: every missing required ingredient, limited to recipes
+// above [WithSubstituteMinCoverage] when set.
+func (s *Service) substituteCandidates(
+	recipes []clients.Recipe,
+	pantrySet map[string]bool,
+	opts ScoreOptions,
+) map[string]bool {
+	if s.subsMinCoverage > 0 {
+		recipes = recipesAboveCoverage(recipes, pantrySet, opts.TreatOptionalAsRequired, s.subsMinCoverage)
+	}
+	return collectMissingIngredientIDs(recipes, pantrySet, opts.TreatOptionalAsRequired && s.optionalSubs)
+}
+
+// cloneSubstitutes deep-copies subsMap so callers may filter or append to
+// the result without aliasing the original slices.
+func cloneSubstitutes(subsMap map[string][]clients.IngredientSubstitute) map[string][]clients.IngredientSubstitute {
+	out := make(map[string][]clients.IngredientSubstitute, len(subsMap))
+	for id, subs := range subsMap {
+		out[id] = slices.Clone(subs)
+	}
+	return out
+}
+
+// prefetchSubstitutes loads the substitutes for missingIDs, in one
+// round-trip when the dictionary is a [SubstituteBatchFetcher]. Lookup
+// failures leave an ingredient without substitutes.
+func (s *Service) prefetchSubstitutes(
+	ctx context.Context,
+	missingIDs map[string]bool,
 ) map[string][]clients.IngredientSubstitute {
-	missingIDs := collectMissingIngredientIDs(recipes, pantrySet)
 	subsMap := make(map[string][]clients.IngredientSubstitute, len(missingIDs))
 
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	for id := range missingIDs {
-		wg.Add(1)
-		go func(ingredientID string) {
-			defer wg.Done()
-			subs, err := s.dictionary.GetSubstitutes(ctx, ingredientID)
-			if err != nil || len(subs) == 0 {
-				return
+	pending := missingIDs
+	if batch, ok := s.dictionary.(SubstituteBatchFetcher); ok && len(missingIDs) > 0 {
+		found, err := batch.GetSubstitutesBatch(ctx, slices.Sorted(maps.Keys(missingIDs)))
+		for id, subs := range found {
+			if missingIDs[id] && len(subs) > 0 {
+				subsMap[id] = limitSubstitutes(subs, s.maxSubs)
+			}
+		}
+		if err == nil {
+			return subsMap
+		}
+		// Retry whatever the batch did not return one ingredient at a time.
+		pending = make(map[string]bool, len(missingIDs))
+		for id := range missingIDs {
+			if _, ok := found[id]; !ok {
+				pending[id] = true
 			}
+		}
+	}
+
+	var mu sync.Mutex
+	s.fanOut(ctx, "substitutes", pending, func(ctx context.Context, ingredientID string) {
+		subs, err := s.dictionary.GetSubstitutes(ctx, ingredientID)
+		if err != nil || len(subs) == 0 {
+			return
+		}
+		subs = limitSubstitutes(subs, s.maxSubs)
+		mu.Lock()
+		subsMap[ingredientID] = subs
+		mu.Unlock()
+	})
+
+	return subsMap
+}
+
+// dropUnknownSubstitutes removes substitutes whose substitute_id the
+// dictionary reports as not found. Lookup failures other than not-found keep
+// the substitute. subsMap is modified in place.
+func (s *Service) dropUnknownSubstitutes(ctx context.Context, subsMap map[string][]clients.IngredientSubstitute) {
+	ids := make(map[string]bool)
+	for _, subs := range subsMap {
+		for _, sub := range subs {
+			ids[sub.SubstituteID] = true
+		}
+	}
+
+	unknown := make(map[string]bool)
+	var mu sync.Mutex
+	s.fanOut(ctx, "validate_substitutes", ids, func(ctx context.Context, substituteID string) {
+		if _, err := s.dictionary.GetIngredient(ctx, substituteID); errors.Is(err, clients.ErrIngredientNotFound) {
 			mu.Lock()
-			subsMap[ingredientID] = subs
+			unknown[substituteID] = true
 			mu.Unlock()
-		}(id)
+		}
+	})
+
+	if len(unknown) == 0 {
+		return
 	}
-	wg.Wait()
+	for id, subs := range subsMap {
+		subsMap[id] = slices.DeleteFunc(subs, func(sub clients.IngredientSubstitute) bool {
+			return unknown[sub.SubstituteID]
+		})
+	}
+	slog.Default().WarnContext(ctx, "dropped substitutes for unknown ingredients", "substitute_ids", slices.Sorted(maps.Keys(unknown)))
+}
 
-	return subsMap
+// limitSubstitutes returns at most n substitutes, ordered by how close their
+// ratio is to 1:1. n <= 0 means no limit.
+func limitSubstitutes(subs []clients.IngredientSubstitute, n int) []clients.IngredientSubstitute {
+	if n <= 0 || len(subs) <= n {
+		return subs
+	}
+
+	sorted := slices.Clone(subs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return math.Abs(sorted[i].Ratio-1) < math.Abs(sorted[j].Ratio-1)
+	})
+	return sorted[:n]
+}
+
+func (s *Service) prefetchGroups(
+	ctx context.Context,
+	missingIDs map[string]bool,
+) map[string]*clients.SubstitutionGroup {
+	groups := make(map[string]*clients.SubstitutionGroup, len(missingIDs))
+
+	var mu sync.Mutex
+	s.fanOut(ctx, "groups", missingIDs, func(ctx context.Context, ingredientID string) {
+		group, err := s.dictionary.GetSubstitutionGroup(ctx, ingredientID)
+		if err != nil || group == nil {
+			return
+		}
+		mu.Lock()
+		groups[ingredientID] = group
+		mu.Unlock()
+	})
+
+	return groups
+}
+
+// withRecipeSubstitutes returns subsMap with the recipe's own substitutes
+// listed ahead of the dictionary's for each ingredient that has any, minus
+// excluded ones. subsMap is not modified, and is returned as is when the
+// recipe lists no substitutes.
+func withRecipeSubstitutes(
+	recipe clients.Recipe,
+	subsMap map[string][]clients.IngredientSubstitute,
+	excluded []string,
+) map[string][]clients.IngredientSubstitute {
+	local := make(map[string][]clients.IngredientSubstitute)
+	for _, ing := range recipe.Ingredients {
+		for _, sub := range ing.Substitutes {
+			sub.IngredientID = ing.IngredientID
+			local[ing.IngredientID] = append(local[ing.IngredientID], sub)
+		}
+	}
+	if len(local) == 0 {
+		return subsMap
+	}
+
+	for id, subs := range local {
+		for _, sub := range subsMap[id] {
+			if !slices.ContainsFunc(subs, func(l clients.IngredientSubstitute) bool {
+				return l.SubstituteID == sub.SubstituteID
+			}) {
+				subs = append(subs, sub)
+			}
+		}
+		local[id] = subs
+	}
+	removeExcludedSubstitutes(local, excluded)
+
+	merged := make(map[string][]clients.IngredientSubstitute, len(subsMap)+len(local))
+	maps.Copy(merged, subsMap)
+	maps.Copy(merged, local)
+	return merged
+}
+
+// mergeGroupSubstitutes adds every other member of an ingredient's
+// substitution group to subsMap as a 1:1 substitute, after any pairwise
+// substitutes already present. Group substitutes carry no notes, so
+// SubstituteNotes only ever holds the dictionary's own notes.
+func mergeGroupSubstitutes(
+	subsMap map[string][]clients.IngredientSubstitute,
+	groups map[string]*clients.SubstitutionGroup,
+) {
+	for ingredientID, group := range groups {
+		for _, memberID := range group.MemberIDs {
+			if memberID == ingredientID {
+				continue
+			}
+			subsMap[ingredientID] = append(subsMap[ingredientID], clients.IngredientSubstitute{
+				IngredientID: ingredientID,
+				SubstituteID: memberID,
+				Ratio:        1,
+			})
+		}
+	}
+}
+
+// removeExcludedSubstitutes drops excluded substitutes from subsMap in place.
+// See [ScoreOptions.ExcludeSubs] for the entry format.
+func removeExcludedSubstitutes(subsMap map[string][]clients.IngredientSubstitute, excluded []string) {
+	if len(excluded) == 0 {
+		return
+	}
+
+	everywhere := make(map[string]bool)
+	perIngredient := make(map[string]bool)
+	for _, e := range excluded {
+		if _, _, ok := strings.Cut(e, ":"); ok {
+			perIngredient[e] = true
+		} else {
+			everywhere[e] = true
+		}
+	}
+
+	for ingredientID, subs := range subsMap {
+		kept := subs[:0]
+		for _, sub := range subs {
+			if everywhere[sub.SubstituteID] || perIngredient[ingredientID+":"+sub.SubstituteID] {
+				continue
+			}
+			kept = append(kept, sub)
+		}
+		subsMap[ingredientID] = kept
+	}
+}
+
+// recipesAboveCoverage returns the recipes whose direct coverage, before any
+// substitution, is at least minCoverage percent.
+func recipesAboveCoverage(
+	recipes []clients.Recipe,
+	pantrySet map[string]bool,
+	optionalAsRequired bool,
+	minCoverage float64,
+) []clients.Recipe {
+	rules := scoreRules{optionalAsRequired: optionalAsRequired}
+	kept := make([]clients.Recipe, 0, len(recipes))
+	for _, recipe := range recipes {
+		required, have := 0.0, 0.0
+		for _, ing := range recipe.Ingredients {
+			if !rules.isRequired(ing) {
+				continue
+			}
+			required += ingredientWeight(ing)
+			if pantrySet[ing.IngredientID] {
+				have += ingredientWeight(ing)
+			}
+		}
+		if required == 0 || have/required*coveragePercentScale >= minCoverage {
+			kept = append(kept, recipe)
+		}
+	}
+	return kept
 }
 
-func collectMissingIngredientIDs(recipes []clients.Recipe, pantrySet map[string]bool) map[string]bool {
+func collectMissingIngredientIDs(
+	recipes []clients.Recipe,
+	pantrySet map[string]bool,
+	optionalAsRequired bool,
+) map[string]bool {
 	missingIDs := make(map[string]bool)
 	for _, recipe := range recipes {
 		for _, ing := range recipe.Ingredients {
-			if !ing.IsOptional && !pantrySet[ing.IngredientID] {
+			if (!ing.IsOptional || optionalAsRequired) && !pantrySet[ing.IngredientID] {
 				missingIDs[ing.IngredientID] = true
 			}
 		}
@@ -150,45 +1715,142 @@ func collectMissingIngredientIDs(recipes []clients.Recipe, pantrySet map[string]
 	return missingIDs
 }
 
+// scoreRules are the per-recipe rules scoreRecipe applies.
+type scoreRules struct {
+	// maxMissing is the effective max_missing threshold for the recipe.
+	maxMissing int
+	// minCoverage is the coverage percentage CanMake additionally requires.
+	minCoverage float64
+	// optionalAsRequired counts optional ingredients as required.
+	optionalAsRequired bool
+	// optionalSubs lets substitutes satisfy optional ingredients counted as
+	// required.
+	optionalSubs bool
+	// includeUsed records the pantry stock each match consumes.
+	includeUsed bool
+	// tagMinCoverage replaces maxMissing as the CanMake rule when
+	// hasTagMinCoverage is set by a min_coverage:N recipe tag.
+	tagMinCoverage    float64
+	hasTagMinCoverage bool
+}
+
+// canMake reports whether a recipe with the given missing count and coverage
+// is makeable: within maxMissing, or at least tagMinCoverage when the recipe
+// sets one, and always at least minCoverage.
+func (r scoreRules) canMake(missing int, coveragePct float64) bool {
+	if coveragePct < r.minCoverage {
+		return false
+	}
+	if r.hasTagMinCoverage {
+		return coveragePct >= r.tagMinCoverage
+	}
+	return missing <= r.maxMissing
+}
+
+// isRequired reports whether ing counts toward coverage under the rules.
+func (r scoreRules) isRequired(ing clients.RecipeIngredient) bool {
+	return !ing.IsOptional || r.optionalAsRequired
+}
+
 // scoreRecipe computes a single recipe's coverage score against the pantry set.
 // subsMap provides pre-fetched substitute data for allow_subs scoring.
 func scoreRecipe(
 	recipe clients.Recipe,
 	pantrySet map[string]bool,
 	subsMap map[string][]clients.IngredientSubstitute,
-	maxMissing int,
+	rules scoreRules,
+) MatchResult {
+	return scoreRecipeWith(recipe, pantrySet, pantrySet, subsMap, rules)
+}
+
+// scoreRecipeWith is [scoreRecipe] with separate sets for the ingredients
+// stocked well enough to use directly and those stocked well enough to stand
+// in as a substitute, which differ when quantities are compared.
+func scoreRecipeWith(
+	recipe clients.Recipe,
+	pantrySet, subStocked map[string]bool,
+	subsMap map[string][]clients.IngredientSubstitute,
+	rules scoreRules,
 ) MatchResult {
 	required := make([]clients.RecipeIngredient, 0, len(recipe.Ingredients))
 	for _, ing := range recipe.Ingredients {
-		if !ing.IsOptional {
+		if rules.isRequired(ing) {
 			required = append(required, ing)
 		}
 	}
 
 	if len(required) == 0 {
 		return MatchResult{
-			Recipe:             recipe,
-			CoveragePct:        coveragePercentScale,
-			MissingIngredients: []MissingIngredient{},
-			CanMake:            true,
+			Recipe:              recipe,
+			CoveragePct:         coveragePercentScale,
+			OptionalCoveragePct: optionalCoverage(recipe, pantrySet, rules),
+			TotalMinutes:        totalMinutes(recipe),
+			MissingIngredients:  []MissingIngredient{},
+			CanMake:             true,
 		}
 	}
 
 	missing := make([]MissingIngredient, 0)
-	matched := 0
+	matchedIngredients := make([]MatchedIngredient, 0, len(required))
+	var substitutedWith, substituteNotes map[string]string
+	var used []UsedIngredient
+	matched, total := 0.0, 0.0
 
 	for _, ing := range required {
+		total += ingredientWeight(ing)
 		if pantrySet[ing.IngredientID] {
-			matched++
+			matched += ingredientWeight(ing)
+			matchedIngredients = append(matchedIngredients, MatchedIngredient{
+				IngredientID: ing.IngredientID,
+				Quantity:     ing.Quantity,
+				Unit:         ing.Unit,
+				Source:       MatchSourceDirect,
+			})
+			if rules.includeUsed {
+				used = append(used, UsedIngredient{IngredientID: ing.IngredientID, Quantity: ing.Quantity, Unit: ing.Unit})
+			}
 			continue
 		}
 
 		// Check if any substitute for this ingredient is in the pantry.
+		var subs []clients.IngredientSubstitute
+		if !ing.IsOptional || rules.optionalSubs {
+			subs = subsMap[ing.IngredientID]
+		}
 		foundSub := false
-		for _, sub := range subsMap[ing.IngredientID] {
-			if pantrySet[sub.SubstituteID] {
-				matched++
+		for _, sub := range subs {
+			if subStocked[sub.SubstituteID] {
+				matched += ingredientWeight(ing)
 				foundSub = true
+				if substitutedWith == nil {
+					substitutedWith = make(map[string]string)
+				}
+				substitutedWith[ing.IngredientID] = sub.SubstituteID
+				if sub.Notes != "" {
+					if substituteNotes == nil {
+						substituteNotes = make(map[string]string)
+					}
+					substituteNotes[ing.IngredientID] = sub.Notes
+				}
+				matchedIngredients = append(matchedIngredients, MatchedIngredient{
+					IngredientID: ing.IngredientID,
+					Quantity:     ing.Quantity,
+					Unit:         ing.Unit,
+					Source:       MatchSourceSubstitute,
+					SubstituteID: sub.SubstituteID,
+				})
+				if rules.includeUsed {
+					ratio := sub.Ratio
+					if ratio <= 0 {
+						ratio = 1
+					}
+					used = append(used, UsedIngredient{
+						IngredientID:    sub.SubstituteID,
+						Quantity:        ing.Quantity * ratio,
+						Unit:            ing.Unit,
+						ForIngredientID: ing.IngredientID,
+					})
+				}
 				break
 			}
 		}
@@ -202,52 +1864,205 @@ func scoreRecipe(
 		}
 	}
 
-	coveragePct := float64(matched) / float64(len(required)) * coveragePercentScale
+	coveragePct := matched / total * coveragePercentScale
 	return MatchResult{
-		Recipe:             recipe,
-		CoveragePct:        coveragePct,
-		MissingIngredients: missing,
-		CanMake:            len(missing) <= maxMissing,
+		Recipe:              recipe,
+		CoveragePct:         coveragePct,
+		OptionalCoveragePct: optionalCoverage(recipe, pantrySet, rules),
+		TotalMinutes:        totalMinutes(recipe),
+		MissingIngredients:  missing,
+		MatchedIngredients:  matchedIngredients,
+		UsedIngredients:     used,
+		CanMake:             rules.canMake(len(missing), coveragePct),
+		SubstitutedWith:     substitutedWith,
+		SubstituteNotes:     substituteNotes,
+	}
+}
+
+// optionalCoverage is the weighted percentage of the recipe's optional
+// ingredients, those not counted as required under rules, that are in
+// pantrySet. Substitutes are not considered. Returns 0 when there are none.
+func optionalCoverage(recipe clients.Recipe, pantrySet map[string]bool, rules scoreRules) float64 {
+	have, total := 0.0, 0.0
+	for _, ing := range recipe.Ingredients {
+		if rules.isRequired(ing) {
+			continue
+		}
+		total += ingredientWeight(ing)
+		if pantrySet[ing.IngredientID] {
+			have += ingredientWeight(ing)
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return have / total * coveragePercentScale
+}
+
+// ingredientWeight is the ingredient's share of recipe coverage. Recipes
+// without weights count every ingredient as 1, so coverage is the plain
+// fraction of ingredients covered.
+func ingredientWeight(ing clients.RecipeIngredient) float64 {
+	if ing.Weight <= 0 {
+		return 1
 	}
+	return ing.Weight
+}
+
+// totalMinutes is the recipe's prep plus cook time. Missing or negative times
+// count as zero.
+func totalMinutes(recipe clients.Recipe) int {
+	return max(recipe.PrepMinutes, 0) + max(recipe.CookMinutes, 0)
 }
 
 // resolveNames fetches ingredient names from the dictionary for all unique
-// missing ingredient IDs across results, populating the Name field in-place.
-func (s *Service) resolveNames(ctx context.Context, results []MatchResult) {
+// missing and matched ingredient IDs across results, populating the Name
+// fields in-place. With recipeNames set it also names every ingredient of each
+// result's recipe. With a name cap configured, only the first IDs in ranked
+// order are resolved; it returns how many were left unnamed by the cap.
+func (s *Service) resolveNames(ctx context.Context, results []MatchResult, recipeNames bool) int {
 	seen := make(map[string]bool)
+	capped := make(map[string]bool)
+	add := func(id string) {
+		switch {
+		case seen[id] || capped[id]:
+		case s.maxNames > 0 && len(seen) >= s.maxNames:
+			capped[id] = true
+		default:
+			seen[id] = true
+		}
+	}
 	for _, r := range results {
 		for _, m := range r.MissingIngredients {
-			seen[m.IngredientID] = true
+			add(m.IngredientID)
+			for _, sg := range m.Suggestions {
+				add(sg.SubstituteID)
+			}
+		}
+		for _, m := range r.MatchedIngredients {
+			add(m.IngredientID)
+			if m.SubstituteID != "" {
+				add(m.SubstituteID)
+			}
+		}
+		if recipeNames {
+			for _, ing := range r.Recipe.Ingredients {
+				add(ing.IngredientID)
+			}
 		}
 	}
 	if len(seen) == 0 {
-		return
+		return len(capped)
 	}
 
-	nameMap := make(map[string]string, len(seen))
+	resolved := s.lookupNames(ctx, seen)
+	nameMap := s.withFallbackNames(resolved, seen)
+
+	for i := range results {
+		for j := range results[i].MissingIngredients {
+			m := &results[i].MissingIngredients[j]
+			if name, ok := nameMap[m.IngredientID]; ok && name != "" {
+				m.Name = name
+				m.NameSource = NameSourceFallback
+				if _, ok := resolved[m.IngredientID]; ok {
+					m.NameSource = NameSourceExact
+				}
+			}
+			for k := range m.Suggestions {
+				sg := &m.Suggestions[k]
+				sg.Name = nameMap[sg.SubstituteID]
+			}
+		}
+		for j := range results[i].MatchedIngredients {
+			m := &results[i].MatchedIngredients[j]
+			m.Name = nameMap[m.IngredientID]
+			if m.SubstituteID != "" {
+				m.SubstituteName = nameMap[m.SubstituteID]
+			}
+		}
+		if recipeNames {
+			// The ingredient list is shared with the fetched catalog.
+			ings := slices.Clone(results[i].Recipe.Ingredients)
+			for j := range ings {
+				ings[j].Name = nameMap[ings[j].IngredientID]
+			}
+			results[i].Recipe.Ingredients = ings
+		}
+	}
+	return len(capped)
+}
+
+// fetchNames concurrently resolves ingredient names from the dictionary.
+// Lookups that fail are named by the service's [NameFallbackPolicy]; under
+// the default policy they are omitted from the returned map.
+func (s *Service) fetchNames(ctx context.Context, ids map[string]bool) map[string]string {
+	return s.withFallbackNames(s.lookupNames(ctx, ids), ids)
+}
+
+func cappedNamesWarning(n int) string {
+	return fmt.Sprintf("name resolution capped: %d ingredient names not resolved", n)
+}
+
+// lookupNames resolves ids through the dictionary. IDs it cannot resolve, or
+// resolves to an empty name, are absent from the result.
+func (s *Service) lookupNames(ctx context.Context, ids map[string]bool) map[string]string {
+	nameMap := make(map[string]string, len(ids))
 	var mu sync.Mutex
+	s.fanOut(ctx, "names", ids, func(ctx context.Context, ingredientID string) {
+		detail, err := s.dictionary.GetIngredient(ctx, ingredientID)
+		if err != nil || detail == nil || detail.Name == "" {
+			return
+		}
+		mu.Lock()
+		nameMap[ingredientID] = detail.Name
+		mu.Unlock()
+	})
+	return nameMap
+}
+
+// fanOut calls fn once per id concurrently, with at most the service's
+// dictionary concurrency in flight, and returns when all calls are done.
+// lookup names the kind of dictionary lookup on the fan-out's span; fn
+// receives a ctx carrying that span.
+func (s *Service) fanOut(
+	ctx context.Context,
+	lookup string,
+	ids map[string]bool,
+	fn func(ctx context.Context, id string),
+) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "dictionary.fanout", trace.WithAttributes(
+		attribute.String("dictionary.lookup", lookup),
+		attribute.Int("dictionary.ids", len(ids)),
+	))
+	defer span.End()
+
+	limit := s.dictConcurrency
+	if limit < 1 {
+		limit = DefaultDictionaryConcurrency
+	}
+	sem := make(chan struct{}, limit)
 	var wg sync.WaitGroup
-	for id := range seen {
-		wg.Add(1)
-		go func(ingredientID string) {
-			defer wg.Done()
-			detail, err := s.dictionary.GetIngredient(ctx, ingredientID)
-			if err != nil || detail == nil {
-				return
-			}
-			mu.Lock()
-			nameMap[ingredientID] = detail.Name
-			mu.Unlock()
-		}(id)
+	for id := range ids {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			fn(ctx, id)
+		})
 	}
 	wg.Wait()
+}
 
-	for i := range results {
-		for j := range results[i].MissingIngredients {
-			id := results[i].MissingIngredients[j].IngredientID
-			if name, ok := nameMap[id]; ok {
-				results[i].MissingIngredients[j].Name = name
-			}
+// withFallbackNames returns resolved plus, for every id it lacks, the name
+// from the service's [NameFallbackPolicy]. resolved is not modified.
+func (s *Service) withFallbackNames(resolved map[string]string, ids map[string]bool) map[string]string {
+	if s.nameFallback == NameFallbackEmpty || s.nameFallback == "" {
+		return resolved
+	}
+	nameMap := maps.Clone(resolved)
+	for id := range ids {
+		if _, ok := nameMap[id]; !ok {
+			nameMap[id] = s.nameFallback.name(id)
 		}
 	}
+	return nameMap
 }
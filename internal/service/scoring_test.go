@@ -1,9 +1,16 @@
 package service
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -25,7 +32,7 @@ func TestScoreRecipe_AllInPantry(t *testing.T) {
 	}
 	pantrySet := map[string]bool{"ing1": true, "ing2": true}
 
-	result := scoreRecipe(recipe, pantrySet, nil, 0)
+	result := scoreRecipe(recipe, pantrySet, nil, scoreRules{})
 
 	assert.InDelta(t, 100.0, result.CoveragePct, 0.0001)
 	assert.True(t, result.CanMake)
@@ -44,7 +51,7 @@ func TestScoreRecipe_PartialCoverage(t *testing.T) {
 	}
 	pantrySet := map[string]bool{"ing1": true}
 
-	result := scoreRecipe(recipe, pantrySet, nil, 0)
+	result := scoreRecipe(recipe, pantrySet, nil, scoreRules{})
 
 	assert.InDelta(t, 50.0, result.CoveragePct, 0.0001)
 	assert.False(t, result.CanMake)
@@ -64,7 +71,7 @@ func TestScoreRecipe_OptionalOnlyMissing(t *testing.T) {
 	}
 	pantrySet := map[string]bool{"ing1": true}
 
-	result := scoreRecipe(recipe, pantrySet, nil, 0)
+	result := scoreRecipe(recipe, pantrySet, nil, scoreRules{})
 
 	assert.InDelta(t, 100.0, result.CoveragePct, 0.0001)
 	assert.True(t, result.CanMake)
@@ -85,11 +92,11 @@ func TestScoreRecipe_MaxMissingFiltering(t *testing.T) {
 	pantrySet := map[string]bool{"ing1": true}
 
 	// Missing 2 ingredients, maxMissing=2 → can make
-	result := scoreRecipe(recipe, pantrySet, nil, 2)
+	result := scoreRecipe(recipe, pantrySet, nil, scoreRules{maxMissing: 2})
 	assert.True(t, result.CanMake)
 
 	// Missing 2 ingredients, maxMissing=1 → cannot make
-	result = scoreRecipe(recipe, pantrySet, nil, 1)
+	result = scoreRecipe(recipe, pantrySet, nil, scoreRules{maxMissing: 1})
 	assert.False(t, result.CanMake)
 }
 
@@ -108,11 +115,39 @@ func TestScoreRecipe_SubstituteMatching(t *testing.T) {
 		"ing2": {{IngredientID: "ing2", SubstituteID: "sub_ing2", Ratio: 1.0}},
 	}
 
-	result := scoreRecipe(recipe, pantrySet, subsMap, 0)
+	result := scoreRecipe(recipe, pantrySet, subsMap, scoreRules{})
 
 	assert.InDelta(t, 100.0, result.CoveragePct, 0.0001)
 	assert.True(t, result.CanMake)
 	assert.Empty(t, result.MissingIngredients)
+	assert.Equal(t, map[string]string{"ing2": "sub_ing2"}, result.SubstitutedWith)
+}
+
+func TestScoreRecipe_SubstituteNotes(t *testing.T) {
+	t.Parallel()
+	recipe := clients.Recipe{
+		ID: "r1",
+		Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "butter"}, {IngredientID: "egg"}, {IngredientID: "milk"},
+		},
+	}
+	pantrySet := map[string]bool{"flour": true, "oil": true, "applesauce": true}
+	subsMap := map[string][]clients.IngredientSubstitute{
+		"flour":  {{IngredientID: "flour", SubstituteID: "oat_flour", Notes: "sift twice"}},
+		"butter": {{IngredientID: "butter", SubstituteID: "oil", Ratio: 0.8, Notes: "melt the butter's share"}},
+		"egg":    {{IngredientID: "egg", SubstituteID: "applesauce", Ratio: 0.25}},
+		"milk":   {{IngredientID: "milk", SubstituteID: "cream", Notes: "thin with water"}},
+	}
+
+	result := scoreRecipe(recipe, pantrySet, subsMap, scoreRules{})
+
+	assert.Equal(t, map[string]string{"butter": "oil", "egg": "applesauce"}, result.SubstitutedWith)
+	// Only applied substitutes with notes: not flour (stocked directly), egg
+	// (no notes) or milk (substitute not stocked).
+	assert.Equal(t, map[string]string{"butter": "melt the butter's share"}, result.SubstituteNotes)
+
+	result = scoreRecipe(recipe, map[string]bool{"flour": true}, subsMap, scoreRules{})
+	assert.Nil(t, result.SubstituteNotes)
 }
 
 func TestScoreRecipe_EmptyPantry(t *testing.T) {
@@ -126,7 +161,7 @@ func TestScoreRecipe_EmptyPantry(t *testing.T) {
 	}
 	pantrySet := map[string]bool{}
 
-	result := scoreRecipe(recipe, pantrySet, nil, 0)
+	result := scoreRecipe(recipe, pantrySet, nil, scoreRules{})
 
 	assert.InDelta(t, 0.0, result.CoveragePct, 0.0001)
 	assert.False(t, result.CanMake)
@@ -141,7 +176,7 @@ func TestScoreRecipe_EmptyRecipeIngredients(t *testing.T) {
 	}
 	pantrySet := map[string]bool{}
 
-	result := scoreRecipe(recipe, pantrySet, nil, 0)
+	result := scoreRecipe(recipe, pantrySet, nil, scoreRules{})
 
 	assert.InDelta(t, 100.0, result.CoveragePct, 0.0001)
 	assert.True(t, result.CanMake)
@@ -159,7 +194,7 @@ func TestScoreRecipe_AllOptional(t *testing.T) {
 	}
 	pantrySet := map[string]bool{}
 
-	result := scoreRecipe(recipe, pantrySet, nil, 0)
+	result := scoreRecipe(recipe, pantrySet, nil, scoreRules{})
 
 	assert.InDelta(t, 100.0, result.CoveragePct, 0.0001)
 	assert.True(t, result.CanMake)
@@ -199,8 +234,9 @@ func TestScore_RankingOrder(t *testing.T) {
 		Return(&clients.IngredientDetail{ID: "ing2", Name: "butter"}, nil)
 
 	svc := New(pantryMock, recipeMock, dictMock)
-	results, err := svc.Score(context.Background(), false, 1)
+	res, err := svc.Score(context.Background(), ScoreOptions{MaxMissing: 1})
 	require.NoError(t, err)
+	results := res.Results
 
 	require.Len(t, results, 2)
 	assert.Equal(t, "Full match", results[0].Recipe.Title)
@@ -241,8 +277,9 @@ func TestScore_FiltersByCanMake(t *testing.T) {
 	svc := New(pantryMock, recipeMock, dictMock)
 
 	// maxMissing=0 → only fully matched recipes
-	results, err := svc.Score(context.Background(), false, 0)
+	res, err := svc.Score(context.Background(), ScoreOptions{})
 	require.NoError(t, err)
+	results := res.Results
 	require.Len(t, results, 1)
 	assert.Equal(t, "Full match", results[0].Recipe.Title)
 }
@@ -255,11 +292,13 @@ func TestScore_PantryFetchError(t *testing.T) {
 	dictMock := mocks.NewMockDictionaryFetcher(t)
 
 	pantryMock.EXPECT().GetPantry(mock.Anything).Return(nil, errors.New("pantry down"))
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{}, nil).Maybe()
 
 	svc := New(pantryMock, recipeMock, dictMock)
-	_, err := svc.Score(context.Background(), false, 0)
-	require.Error(t, err)
+	_, err := svc.Score(context.Background(), ScoreOptions{})
+	require.ErrorIs(t, err, ErrPantryUnavailable)
 	assert.Contains(t, err.Error(), "pantry")
+	assert.NotErrorIs(t, err, ErrRecipesUnavailable)
 }
 
 func TestScore_RecipeFetchError(t *testing.T) {
@@ -273,7 +312,2257 @@ func TestScore_RecipeFetchError(t *testing.T) {
 	recipeMock.EXPECT().GetRecipes(mock.Anything).Return(nil, errors.New("recipes down"))
 
 	svc := New(pantryMock, recipeMock, dictMock)
-	_, err := svc.Score(context.Background(), false, 0)
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "recipes")
+	_, err := svc.Score(context.Background(), ScoreOptions{})
+	require.ErrorIs(t, err, ErrRecipesUnavailable)
+	assert.Contains(t, err.Error(), "recipes down")
+	assert.NotErrorIs(t, err, ErrPantryUnavailable)
+}
+
+func TestScore_FetchesPantryAndRecipesConcurrently(t *testing.T) {
+	t.Parallel()
+	const delay = 150 * time.Millisecond
+	slow := func(body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.Write([]byte(body))
+		}))
+	}
+	pantrySrv, recipeSrv := slow(`{"items":[]}`), slow(`[]`)
+	defer pantrySrv.Close()
+	defer recipeSrv.Close()
+
+	svc := New(
+		clients.NewPantryClient(pantrySrv.URL),
+		clients.NewRecipeClient(recipeSrv.URL),
+		mocks.NewMockDictionaryFetcher(t),
+	)
+	start := time.Now()
+	_, err := svc.Score(context.Background(), ScoreOptions{})
+	elapsed := time.Since(start)
+
+	require.NoError(t, err)
+	assert.Less(t, elapsed, 2*delay-delay/3, "fetches should overlap, not add up")
+}
+
+func TestScore_FetchErrorCancelsOtherFetch(t *testing.T) {
+	t.Parallel()
+	pantrySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer pantrySrv.Close()
+	recipeSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer recipeSrv.Close()
+
+	svc := New(
+		clients.NewPantryClient(pantrySrv.URL),
+		clients.NewRecipeClient(recipeSrv.URL),
+		mocks.NewMockDictionaryFetcher(t),
+	)
+	start := time.Now()
+	_, err := svc.Score(context.Background(), ScoreOptions{})
+
+	require.ErrorIs(t, err, ErrPantryUnavailable)
+	assert.NotErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestScore_NullRecipesWarns(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).
+		Return(nil, fmt.Errorf("recipe service: %w", clients.ErrNullResponse))
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, res.Results)
+	require.Len(t, res.Warnings, 1)
+	assert.Contains(t, res.Warnings[0], "null")
+}
+
+func TestScore_NullRecipesErrorPolicy(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).
+		Return(nil, fmt.Errorf("recipe service: %w", clients.ErrNullResponse))
+
+	svc := New(pantryMock, recipeMock, dictMock, WithNullRecipePolicy(NullRecipeError))
+	_, err := svc.Score(context.Background(), ScoreOptions{})
+	require.ErrorIs(t, err, clients.ErrNullResponse)
+	assert.ErrorIs(t, err, ErrRecipesUnavailable)
+}
+
+func TestScore_TagMaxMissing(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "ing1"},
+	}, nil)
+
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{
+			ID:    "r1",
+			Title: "Flexible stew",
+			Tags:  []string{"flexible"},
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri1", IngredientID: "ing1"},
+				{ID: "ri2", IngredientID: "ing2"},
+				{ID: "ri3", IngredientID: "ing3"},
+			},
+		},
+		{
+			ID:    "r2",
+			Title: "Strict stew",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri4", IngredientID: "ing1"},
+				{ID: "ri5", IngredientID: "ing2"},
+				{ID: "ri6", IngredientID: "ing3"},
+			},
+		},
+	}, nil)
+
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(nil, clients.ErrIngredientNotFound)
+
+	svc := New(pantryMock, recipeMock, dictMock, WithTagMaxMissing(map[string]int{"flexible": 2}))
+	res, err := svc.Score(context.Background(), ScoreOptions{})
+	require.NoError(t, err)
+	results := res.Results
+
+	require.Len(t, results, 1)
+	assert.Equal(t, "Flexible stew", results[0].Recipe.Title)
+	assert.Len(t, results[0].MissingIngredients, 2)
+}
+
+func TestScore_TagMinCoverage(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "ing1"},
+		{ID: "p2", IngredientID: "ing2"},
+		{ID: "p3", IngredientID: "ing3"},
+	}, nil)
+	// Each recipe has three of four ingredients: 75% coverage, one missing.
+	recipe := func(id string, tags ...string) clients.Recipe {
+		return clients.Recipe{ID: id, Tags: tags, Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "ing1"}, {IngredientID: "ing2"}, {IngredientID: "ing3"}, {IngredientID: "ing4"},
+		}}
+	}
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		recipe("staple", "min_coverage:70"),
+		recipe("too-strict", "min_coverage:80"),
+		recipe("malformed", "min_coverage:lots", "min_coverage:120"),
+		recipe("untagged"),
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(nil, clients.ErrIngredientNotFound)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{IncludeUnmakeable: true})
+	require.NoError(t, err)
+
+	canMake := make(map[string]bool, len(res.Results))
+	for _, r := range res.Results {
+		canMake[r.Recipe.ID] = r.CanMake
+	}
+	// The tag threshold replaces max_missing=0; malformed tags fall back to it.
+	assert.Equal(t, map[string]bool{
+		"staple":     true,
+		"too-strict": false,
+		"malformed":  false,
+		"untagged":   false,
+	}, canMake)
+
+	// A tag threshold of 80% also overrides a lenient max_missing.
+	res, err = svc.Score(context.Background(), ScoreOptions{MaxMissing: 1})
+	require.NoError(t, err)
+	ids := make([]string, 0, len(res.Results))
+	for _, r := range res.Results {
+		ids = append(ids, r.Recipe.ID)
+	}
+	assert.ElementsMatch(t, []string{"staple", "malformed", "untagged"}, ids)
+}
+
+func TestTagMinCoverage(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		tags   []string
+		want   float64
+		wantOK bool
+	}{
+		{tags: []string{"quick", "min_coverage:80"}, want: 80, wantOK: true},
+		{tags: []string{"min_coverage:62.5"}, want: 62.5, wantOK: true},
+		{tags: []string{"min_coverage:0"}, want: 0, wantOK: true},
+		{tags: []string{"min_coverage:abc", "min_coverage:70"}, want: 70, wantOK: true},
+		{tags: []string{"min_coverage:101"}},
+		{tags: []string{"min_coverage:-5"}},
+		{tags: []string{"min_coverage:NaN"}},
+		{tags: []string{"min_coverage:"}},
+		{tags: []string{"quick"}},
+		{},
+	} {
+		got, ok := tagMinCoverage(clients.Recipe{Tags: tc.tags})
+		assert.Equal(t, tc.wantOK, ok, tc.tags)
+		assert.InDelta(t, tc.want, got, 0.0001, tc.tags)
+	}
+}
+
+func TestScore_SubstituteValidation(t *testing.T) {
+	t.Parallel()
+
+	for _, validate := range []bool{false, true} {
+		pantryMock := mocks.NewMockPantryFetcher(t)
+		recipeMock := mocks.NewMockRecipeFetcher(t)
+		dictMock := mocks.NewMockDictionaryFetcher(t)
+
+		pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+			{ID: "p1", IngredientID: "ghost"},
+		}, nil)
+		recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+			{ID: "r1", Ingredients: []clients.RecipeIngredient{{ID: "ri1", IngredientID: "butter"}}},
+		}, nil)
+		dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+			{IngredientID: "butter", SubstituteID: "ghost", Ratio: 1},
+			{IngredientID: "butter", SubstituteID: "oil", Ratio: 1},
+		}, nil)
+		dictMock.EXPECT().GetIngredient(mock.Anything, "ghost").Return(nil, clients.ErrIngredientNotFound).Maybe()
+		dictMock.EXPECT().GetIngredient(mock.Anything, "oil").
+			Return(&clients.IngredientDetail{ID: "oil", Name: "oil"}, nil).Maybe()
+		dictMock.EXPECT().GetIngredient(mock.Anything, "butter").
+			Return(&clients.IngredientDetail{ID: "butter", Name: "butter"}, nil).Maybe()
+
+		svc := New(pantryMock, recipeMock, dictMock, WithSubstituteValidation(validate))
+		res, err := svc.Score(context.Background(), ScoreOptions{
+			AllowSubs:         true,
+			IncludeUnmakeable: true,
+			SuggestSubs:       true,
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Results, 1)
+
+		result := res.Results[0]
+		assert.Equal(t, !validate, result.CanMake, "validate=%v", validate)
+		if validate {
+			require.Len(t, result.MissingIngredients, 1)
+			suggestions := result.MissingIngredients[0].Suggestions
+			require.Len(t, suggestions, 1)
+			assert.Equal(t, "oil", suggestions[0].SubstituteID)
+		}
+	}
+}
+
+func TestScore_RecipeSubstitutes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		opts        ScoreOptions
+		wantSubs    map[string]string
+		wantCanMake bool
+	}{
+		{
+			name:        "recipe substitutes come first",
+			opts:        ScoreOptions{AllowSubs: true},
+			wantSubs:    map[string]string{"eggs": "applesauce", "butter": "oil"},
+			wantCanMake: true,
+		},
+		{
+			name:     "excluded recipe substitutes are skipped",
+			opts:     ScoreOptions{AllowSubs: true, ExcludeSubs: []string{"eggs:applesauce"}},
+			wantSubs: map[string]string{"butter": "oil"},
+		},
+		{name: "ignored without allow_subs"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			dictMock := mocks.NewMockDictionaryFetcher(t)
+
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+				{IngredientID: "applesauce", Quantity: 1},
+				{IngredientID: "margarine", Quantity: 1},
+				{IngredientID: "oil", Quantity: 1},
+			}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+				{ID: "cake", Ingredients: []clients.RecipeIngredient{
+					{IngredientID: "eggs", Substitutes: []clients.IngredientSubstitute{
+						{SubstituteID: "applesauce", Ratio: 0.25},
+					}},
+					{IngredientID: "butter", Substitutes: []clients.IngredientSubstitute{
+						{SubstituteID: "oil", Ratio: 0.8},
+					}},
+				}},
+			}, nil)
+			// The dictionary knows nothing for eggs and prefers margarine for butter.
+			dictMock.EXPECT().GetSubstitutes(mock.Anything, "eggs").Return(nil, nil).Maybe()
+			dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+				{IngredientID: "butter", SubstituteID: "margarine", Ratio: 1},
+			}, nil).Maybe()
+
+			svc := New(pantryMock, recipeMock, dictMock)
+			opts := tc.opts
+			opts.IncludeUnmakeable = true
+			opts.skipNames = true
+			res, err := svc.Score(context.Background(), opts)
+			require.NoError(t, err)
+
+			require.Len(t, res.Results, 1)
+			assert.Equal(t, tc.wantCanMake, res.Results[0].CanMake)
+			assert.Equal(t, tc.wantSubs, res.Results[0].SubstitutedWith)
+		})
+	}
+}
+
+func TestWithRecipeSubstitutes(t *testing.T) {
+	t.Parallel()
+
+	dictionary := map[string][]clients.IngredientSubstitute{
+		"butter": {{IngredientID: "butter", SubstituteID: "margarine"}, {IngredientID: "butter", SubstituteID: "oil"}},
+		"milk":   {{IngredientID: "milk", SubstituteID: "oat-milk"}},
+	}
+	recipe := clients.Recipe{Ingredients: []clients.RecipeIngredient{
+		{IngredientID: "butter", Substitutes: []clients.IngredientSubstitute{{SubstituteID: "oil", Ratio: 0.8}}},
+		{IngredientID: "milk"},
+	}}
+
+	got := withRecipeSubstitutes(recipe, dictionary, nil)
+	assert.Equal(t, []clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 0.8},
+		{IngredientID: "butter", SubstituteID: "margarine"},
+	}, got["butter"])
+	assert.Equal(t, dictionary["milk"], got["milk"])
+	assert.Len(t, dictionary["butter"], 2, "dictionary substitutes are not modified")
+
+	plain := clients.Recipe{Ingredients: []clients.RecipeIngredient{{IngredientID: "milk"}}}
+	assert.Equal(t, dictionary, withRecipeSubstitutes(plain, dictionary, nil))
+}
+
+func TestScore_SubstituteMinCoverage(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "flour"},
+		{ID: "p2", IngredientID: "milk"},
+		{ID: "p3", IngredientID: "oil"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		// 67% direct coverage: substitutes are fetched.
+		{ID: "pancakes", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "milk"}, {IngredientID: "butter"},
+		}},
+		// 25% direct coverage: no substitute lookups.
+		{ID: "souffle", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "milk"}, {IngredientID: "eggs"}, {IngredientID: "gruyere"}, {IngredientID: "cream"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 1},
+	}, nil).Once()
+
+	svc := New(pantryMock, recipeMock, dictMock, WithSubstituteMinCoverage(50))
+	res, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "pancakes", res.Results[0].Recipe.ID)
+	dictMock.AssertNotCalled(t, "GetSubstitutes", mock.Anything, "eggs")
+	dictMock.AssertNotCalled(t, "GetSubstitutes", mock.Anything, "gruyere")
+	dictMock.AssertNotCalled(t, "GetSubstitutes", mock.Anything, "cream")
+}
+
+// batchDictionary adds GetSubstitutesBatch to the dictionary mock.
+type batchDictionary struct {
+	*mocks.MockDictionaryFetcher
+	batch func(ids []string) (map[string][]clients.IngredientSubstitute, error)
+	calls [][]string
+}
+
+func (d *batchDictionary) GetSubstitutesBatch(
+	_ context.Context,
+	ids []string,
+) (map[string][]clients.IngredientSubstitute, error) {
+	d.calls = append(d.calls, ids)
+	return d.batch(ids)
+}
+
+func TestScore_PrefersSubstituteBatch(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dict := &batchDictionary{
+		MockDictionaryFetcher: mocks.NewMockDictionaryFetcher(t),
+		batch: func([]string) (map[string][]clients.IngredientSubstitute, error) {
+			return map[string][]clients.IngredientSubstitute{
+				"butter": {{IngredientID: "butter", SubstituteID: "oil", Ratio: 1}},
+			}, nil
+		},
+	}
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "flour"},
+		{ID: "p2", IngredientID: "oil"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "shortbread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "butter"}, {IngredientID: "sugar"},
+		}},
+	}, nil)
+	dict.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(nil, clients.ErrIngredientNotFound).Maybe()
+
+	svc := New(pantryMock, recipeMock, dict)
+	res, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true, MaxMissing: 1})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, map[string]string{"butter": "oil"}, res.Results[0].SubstitutedWith)
+	assert.Equal(t, [][]string{{"butter", "sugar"}}, dict.calls)
+	dict.AssertNotCalled(t, "GetSubstitutes", mock.Anything, mock.Anything)
+}
+
+func TestScore_SubstituteBatchErrorFallsBackPerID(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dict := &batchDictionary{
+		MockDictionaryFetcher: mocks.NewMockDictionaryFetcher(t),
+		batch: func([]string) (map[string][]clients.IngredientSubstitute, error) {
+			// A partial result: sugar has no substitutes, butter failed.
+			return map[string][]clients.IngredientSubstitute{"sugar": {}}, errors.New("substitutes for butter: boom")
+		},
+	}
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "flour"},
+		{ID: "p2", IngredientID: "oil"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "shortbread", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "butter"}, {IngredientID: "sugar"},
+		}},
+	}, nil)
+	dict.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 1},
+	}, nil).Once()
+	dict.EXPECT().GetIngredient(mock.Anything, mock.Anything).Return(nil, clients.ErrIngredientNotFound).Maybe()
+
+	svc := New(pantryMock, recipeMock, dict)
+	res, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true, MaxMissing: 1})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, map[string]string{"butter": "oil"}, res.Results[0].SubstitutedWith)
+	dict.AssertNotCalled(t, "GetSubstitutes", mock.Anything, "sugar")
+}
+
+func TestScore_SubstituteNotes(t *testing.T) {
+	t.Parallel()
+
+	for _, allowSubs := range []bool{true, false} {
+		pantryMock := mocks.NewMockPantryFetcher(t)
+		recipeMock := mocks.NewMockRecipeFetcher(t)
+		dictMock := mocks.NewMockDictionaryFetcher(t)
+
+		pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+			{ID: "p1", IngredientID: "oil", Quantity: 1},
+		}, nil)
+		recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+			{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "butter"}}},
+		}, nil)
+		dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+			{IngredientID: "butter", SubstituteID: "oil", Ratio: 0.8, Notes: "use a little less"},
+		}, nil).Maybe()
+		dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).
+			Return(nil, clients.ErrIngredientNotFound).Maybe()
+
+		svc := New(pantryMock, recipeMock, dictMock)
+		res, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: allowSubs, IncludeUnmakeable: true})
+		require.NoError(t, err)
+		require.Len(t, res.Results, 1)
+
+		if allowSubs {
+			assert.Equal(t, map[string]string{"butter": "use a little less"}, res.Results[0].SubstituteNotes)
+		} else {
+			assert.Nil(t, res.Results[0].SubstituteNotes)
+		}
+	}
+}
+
+func TestRecipesAboveCoverage(t *testing.T) {
+	t.Parallel()
+
+	recipes := []clients.Recipe{
+		{ID: "none"},
+		{ID: "half", Ingredients: []clients.RecipeIngredient{{IngredientID: "a"}, {IngredientID: "b"}}},
+		{ID: "optional", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "a"}, {IngredientID: "b", IsOptional: true}, {IngredientID: "c", IsOptional: true},
+		}},
+	}
+	pantry := map[string]bool{"a": true}
+
+	ids := func(rs []clients.Recipe) []string {
+		out := make([]string, 0, len(rs))
+		for _, r := range rs {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"none", "half", "optional"}, ids(recipesAboveCoverage(recipes, pantry, false, 50)))
+	assert.Equal(t, []string{"none", "half"}, ids(recipesAboveCoverage(recipes, pantry, true, 50)))
+	assert.Equal(t, []string{"none", "optional"}, ids(recipesAboveCoverage(recipes, pantry, false, 75)))
+}
+
+func TestScore_SortUseMost(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "rice"},
+		{ID: "p2", IngredientID: "onion"},
+		{ID: "p3", IngredientID: "carrot"},
+		{ID: "p4", IngredientID: "pea"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "plain-rice", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice"}}},
+		{ID: "fried-rice", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "rice"}, {IngredientID: "onion"}, {IngredientID: "carrot"}, {IngredientID: "pea"},
+			{IngredientID: "egg"},
+		}},
+		{ID: "pilaf", Ingredients: []clients.RecipeIngredient{{IngredientID: "rice"}, {IngredientID: "onion"}}},
+	}, nil)
+
+	dictMock.EXPECT().GetIngredient(mock.Anything, "egg").Return(&clients.IngredientDetail{ID: "egg", Name: "egg"}, nil)
+	svc := New(pantryMock, recipeMock, dictMock)
+
+	ids := func(res *ScoreResult) []string {
+		out := make([]string, 0, len(res.Results))
+		for _, r := range res.Results {
+			out = append(out, r.Recipe.ID)
+		}
+		return out
+	}
+
+	res, err := svc.Score(context.Background(), ScoreOptions{MaxMissing: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pilaf", "plain-rice", "fried-rice"}, ids(res))
+
+	res, err = svc.Score(context.Background(), ScoreOptions{MaxMissing: 1, Sort: SortUseMost})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fried-rice", "pilaf", "plain-rice"}, ids(res))
+	for _, r := range res.Results {
+		assert.Nil(t, r.MatchedIngredients)
+	}
+}
+
+func TestSortBy_UseMost(t *testing.T) {
+	t.Parallel()
+
+	matched := func(ids ...string) []MatchedIngredient {
+		out := make([]MatchedIngredient, 0, len(ids))
+		for _, id := range ids {
+			out = append(out, MatchedIngredient{IngredientID: id, Source: MatchSourceDirect})
+		}
+		return out
+	}
+	results := []MatchResult{
+		{Recipe: clients.Recipe{ID: "full"}, CoveragePct: 100, MatchedIngredients: matched("a")},
+		{Recipe: clients.Recipe{ID: "big"}, CoveragePct: 75, MatchedIngredients: matched("a", "b", "c")},
+		{Recipe: clients.Recipe{ID: "subbed"}, CoveragePct: 50, MatchedIngredients: []MatchedIngredient{
+			{IngredientID: "a", Source: MatchSourceDirect},
+			{IngredientID: "x", Source: MatchSourceSubstitute, SubstituteID: "b"},
+		}},
+		{Recipe: clients.Recipe{ID: "pair"}, CoveragePct: 40, MatchedIngredients: matched("a", "b")},
+		// Two ingredients satisfied by the same pantry item count once.
+		{Recipe: clients.Recipe{ID: "shared"}, CoveragePct: 30, MatchedIngredients: []MatchedIngredient{
+			{IngredientID: "a", Source: MatchSourceDirect},
+			{IngredientID: "y", Source: MatchSourceSubstitute, SubstituteID: "a"},
+		}},
+	}
+
+	sortBy(results, SortUseMost)
+
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Recipe.ID)
+	}
+	// Equal use keeps the incoming (coverage) order.
+	assert.Equal(t, []string{"big", "subbed", "pair", "full", "shared"}, ids)
+}
+
+func TestSortBy(t *testing.T) {
+	t.Parallel()
+
+	missing := func(n int) []MissingIngredient { return make([]MissingIngredient, n) }
+	// Already in coverage order.
+	coverageSorted := func() []MatchResult {
+		return []MatchResult{
+			{Recipe: clients.Recipe{ID: "full-slow"}, CoveragePct: 100, TotalMinutes: 90},
+			{Recipe: clients.Recipe{ID: "full-unknown"}, CoveragePct: 100},
+			{Recipe: clients.Recipe{ID: "most-quick"}, CoveragePct: 80, TotalMinutes: 10, MissingIngredients: missing(1)},
+			{Recipe: clients.Recipe{ID: "half-slow"}, CoveragePct: 50, TotalMinutes: 90, MissingIngredients: missing(2)},
+			{Recipe: clients.Recipe{ID: "half-quick"}, CoveragePct: 50, TotalMinutes: 10, MissingIngredients: missing(1)},
+		}
+	}
+	ids := func(results []MatchResult) []string {
+		out := make([]string, 0, len(results))
+		for _, r := range results {
+			out = append(out, r.Recipe.ID)
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		mode string
+		want []string
+	}{
+		{mode: "", want: []string{"full-slow", "full-unknown", "most-quick", "half-slow", "half-quick"}},
+		{mode: SortCoverage, want: []string{"full-slow", "full-unknown", "most-quick", "half-slow", "half-quick"}},
+		// Equal times keep coverage order; unknown times sort last.
+		{mode: SortTime, want: []string{"most-quick", "half-quick", "full-slow", "half-slow", "full-unknown"}},
+		// Equal missing counts keep coverage order.
+		{mode: SortMissing, want: []string{"full-slow", "full-unknown", "most-quick", "half-quick", "half-slow"}},
+	} {
+		t.Run(cmp.Or(tc.mode, "default"), func(t *testing.T) {
+			t.Parallel()
+			results := coverageSorted()
+			sortBy(results, tc.mode)
+			assert.Equal(t, tc.want, ids(results))
+		})
+	}
+}
+
+func TestScore_SortTime(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "egg"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "frittata", PrepMinutes: 10, CookMinutes: 25, Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}}},
+		{ID: "boiled", CookMinutes: 8, Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}}},
+		{ID: "scrambled", PrepMinutes: 2, CookMinutes: 6, Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{Sort: SortTime})
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(res.Results))
+	for _, r := range res.Results {
+		ids = append(ids, r.Recipe.ID)
+	}
+	// Tied at 8 minutes, boiled and scrambled keep the ID tiebreak.
+	assert.Equal(t, []string{"boiled", "scrambled", "frittata"}, ids)
+}
+
+func TestEffectiveMaxMissing(t *testing.T) {
+	t.Parallel()
+
+	svc := New(nil, nil, nil, WithTagMaxMissing(map[string]int{"flexible": 3, "lenient": 1}))
+
+	assert.Equal(t, 3, svc.effectiveMaxMissing(clients.Recipe{Tags: []string{"lenient", "flexible"}}, 0))
+	assert.Equal(t, 5, svc.effectiveMaxMissing(clients.Recipe{Tags: []string{"flexible"}}, 5))
+	assert.Equal(t, 1, svc.effectiveMaxMissing(clients.Recipe{Tags: []string{"quick"}}, 1))
+
+	// No mapping configured: request value is used unchanged.
+	assert.Equal(t, 0, New(nil, nil, nil).effectiveMaxMissing(clients.Recipe{Tags: []string{"flexible"}}, 0))
+}
+
+func TestScoreRecipe_MatchedIngredientSources(t *testing.T) {
+	t.Parallel()
+	recipe := clients.Recipe{
+		ID:    "r1",
+		Title: "Cake",
+		Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "ing1", Quantity: 2, Unit: "cup"},
+			{ID: "ri2", IngredientID: "ing2", Quantity: 1, Unit: "tbsp"},
+			{ID: "ri3", IngredientID: "ing3"},
+		},
+	}
+	pantrySet := map[string]bool{"ing1": true, "sub_ing2": true}
+	subsMap := map[string][]clients.IngredientSubstitute{
+		"ing2": {{IngredientID: "ing2", SubstituteID: "sub_ing2", Ratio: 1.0}},
+	}
+
+	result := scoreRecipe(recipe, pantrySet, subsMap, scoreRules{maxMissing: 1})
+
+	require.Len(t, result.MatchedIngredients, 2)
+	assert.Equal(t, MatchedIngredient{
+		IngredientID: "ing1", Quantity: 2, Unit: "cup", Source: MatchSourceDirect,
+	}, result.MatchedIngredients[0])
+	assert.Equal(t, MatchedIngredient{
+		IngredientID: "ing2", Quantity: 1, Unit: "tbsp", Source: MatchSourceSubstitute, SubstituteID: "sub_ing2",
+	}, result.MatchedIngredients[1])
+	require.Len(t, result.MissingIngredients, 1)
+	assert.Equal(t, "ing3", result.MissingIngredients[0].IngredientID)
+}
+
+func TestScore_IncludeMatched(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "ing1"},
+		{ID: "p2", IngredientID: "oil"},
+	}, nil).Times(2)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{
+			ID:    "r1",
+			Title: "Garlic bread",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri1", IngredientID: "ing1"},
+				{ID: "ri2", IngredientID: "butter"},
+			},
+		},
+	}, nil).Times(2)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 0.8},
+	}, nil).Times(2)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "ing1").Return(&clients.IngredientDetail{ID: "ing1", Name: "garlic"}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "butter").Return(&clients.IngredientDetail{ID: "butter", Name: "butter"}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "oil").Return(&clients.IngredientDetail{ID: "oil", Name: "olive oil"}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+
+	res, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true, IncludeMatched: true})
+	require.NoError(t, err)
+	results := res.Results
+	require.Len(t, results, 1)
+	require.Len(t, results[0].MatchedIngredients, 2)
+	assert.Equal(t, "garlic", results[0].MatchedIngredients[0].Name)
+	assert.Equal(t, MatchSourceDirect, results[0].MatchedIngredients[0].Source)
+	assert.Equal(t, "butter", results[0].MatchedIngredients[1].Name)
+	assert.Equal(t, MatchSourceSubstitute, results[0].MatchedIngredients[1].Source)
+	assert.Equal(t, "oil", results[0].MatchedIngredients[1].SubstituteID)
+	assert.Equal(t, "olive oil", results[0].MatchedIngredients[1].SubstituteName)
+
+	res, err = svc.Score(context.Background(), ScoreOptions{AllowSubs: true})
+	require.NoError(t, err)
+	results = res.Results
+	require.Len(t, results, 1)
+	assert.Nil(t, results[0].MatchedIngredients)
+}
+
+func TestSortResults_Seed(t *testing.T) {
+	t.Parallel()
+
+	newResults := func() []MatchResult {
+		results := []MatchResult{
+			{Recipe: clients.Recipe{ID: "best"}, CoveragePct: 100, MissingIngredients: []MissingIngredient{}},
+			{Recipe: clients.Recipe{ID: "worst"}, CoveragePct: 10, MissingIngredients: []MissingIngredient{{}}},
+		}
+		for _, id := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+			results = append(results, MatchResult{
+				Recipe:             clients.Recipe{ID: id},
+				CoveragePct:        50,
+				MissingIngredients: []MissingIngredient{{}},
+			})
+		}
+		return results
+	}
+	ids := func(results []MatchResult) []string {
+		out := make([]string, 0, len(results))
+		for _, r := range results {
+			out = append(out, r.Recipe.ID)
+		}
+		return out
+	}
+
+	first := newResults()
+	sortResults(first, "variant-a", 0)
+	again := newResults()
+	sortResults(again, "variant-a", 0)
+	assert.Equal(t, ids(first), ids(again))
+
+	other := newResults()
+	sortResults(other, "variant-b", 0)
+	assert.NotEqual(t, ids(first), ids(other))
+
+	// Only the tied middle block may move; the strict ranking is preserved.
+	for _, sorted := range [][]MatchResult{first, other} {
+		assert.Equal(t, "best", sorted[0].Recipe.ID)
+		assert.Equal(t, "worst", sorted[len(sorted)-1].Recipe.ID)
+		assert.ElementsMatch(t, []string{"a", "b", "c", "d", "e", "f", "g", "h"}, ids(sorted[1:len(sorted)-1]))
+	}
+
+	// Without a seed, ties fall back to recipe ID.
+	unseeded := newResults()
+	slices.Reverse(unseeded)
+	sortResults(unseeded, "", 0)
+	assert.Equal(t, []string{"best", "a", "b", "c", "d", "e", "f", "g", "h", "worst"}, ids(unseeded))
+}
+
+func TestSortResults_FasterRecipesFirst(t *testing.T) {
+	t.Parallel()
+
+	tied := func(id string, minutes int) MatchResult {
+		return MatchResult{
+			Recipe:             clients.Recipe{ID: id},
+			CoveragePct:        75,
+			TotalMinutes:       minutes,
+			MissingIngredients: []MissingIngredient{{}},
+		}
+	}
+	results := []MatchResult{
+		tied("slow", 90),
+		tied("unknown", 0),
+		tied("quick-b", 15),
+		{Recipe: clients.Recipe{ID: "full"}, CoveragePct: 100, TotalMinutes: 120},
+		tied("medium", 40),
+		tied("quick-a", 15),
+	}
+
+	sortResults(results, "", 0)
+
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Recipe.ID)
+	}
+	assert.Equal(t, []string{"full", "quick-a", "quick-b", "medium", "slow", "unknown"}, ids)
+}
+
+func TestSortResults_OptionalCoverageBreaksTies(t *testing.T) {
+	t.Parallel()
+
+	results := []MatchResult{
+		{Recipe: clients.Recipe{ID: "no-extras"}, CoveragePct: 75, MissingIngredients: []MissingIngredient{{}}},
+		{Recipe: clients.Recipe{ID: "fewer-missing"}, CoveragePct: 75, OptionalCoveragePct: 0},
+		{
+			Recipe:              clients.Recipe{ID: "all-extras"},
+			CoveragePct:         75,
+			OptionalCoveragePct: 100,
+			MissingIngredients:  []MissingIngredient{{}},
+			TotalMinutes:        90,
+		},
+		{
+			Recipe:              clients.Recipe{ID: "some-extras"},
+			CoveragePct:         75,
+			OptionalCoveragePct: 50,
+			MissingIngredients:  []MissingIngredient{{}},
+			TotalMinutes:        10,
+		},
+		{Recipe: clients.Recipe{ID: "higher"}, CoveragePct: 100},
+	}
+
+	sortResults(results, "", 0)
+
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Recipe.ID)
+	}
+	// Coverage and missing count still rank first; optional coverage beats
+	// total time among exact ties.
+	assert.Equal(t, []string{"higher", "fewer-missing", "all-extras", "some-extras", "no-extras"}, ids)
+}
+
+func TestSortResults_RecipeIDBreaksTies(t *testing.T) {
+	t.Parallel()
+
+	tied := func(id string) MatchResult {
+		return MatchResult{
+			Recipe:             clients.Recipe{ID: id},
+			CoveragePct:        50,
+			TotalMinutes:       30,
+			MissingIngredients: []MissingIngredient{{}},
+		}
+	}
+	orders := [][]string{
+		{"delta", "alpha", "charlie", "bravo"},
+		{"bravo", "charlie", "delta", "alpha"},
+		{"alpha", "delta", "bravo", "charlie"},
+	}
+	for _, order := range orders {
+		results := make([]MatchResult, 0, len(order))
+		for _, id := range order {
+			results = append(results, tied(id))
+		}
+
+		sortResults(results, "", 0)
+
+		ids := make([]string, 0, len(results))
+		for _, r := range results {
+			ids = append(ids, r.Recipe.ID)
+		}
+		assert.Equal(t, []string{"alpha", "bravo", "charlie", "delta"}, ids, "input order %v", order)
+	}
+}
+
+func TestSortResults_RecipeIDBreaksSeededTies(t *testing.T) {
+	t.Parallel()
+
+	// Every recipe gets the same shuffle key, as on a hash collision. Total
+	// time, which would order them the other way, is ignored under a seed.
+	sameKey := func(string) uint64 { return 7 }
+	tied := func(id string, minutes int) MatchResult {
+		return MatchResult{Recipe: clients.Recipe{ID: id}, CoveragePct: 50, TotalMinutes: minutes}
+	}
+	orders := [][]MatchResult{
+		{tied("charlie", 10), tied("alpha", 30), tied("bravo", 20)},
+		{tied("bravo", 20), tied("charlie", 10), tied("alpha", 30)},
+	}
+	for _, results := range orders {
+		sortResultsBy(results, sameKey, 0)
+
+		ids := make([]string, 0, len(results))
+		for _, r := range results {
+			ids = append(ids, r.Recipe.ID)
+		}
+		assert.Equal(t, []string{"alpha", "bravo", "charlie"}, ids)
+	}
+}
+
+func TestSortResults_SubstitutionPenalty(t *testing.T) {
+	t.Parallel()
+
+	direct := MatchedIngredient{Source: MatchSourceDirect}
+	sub := MatchedIngredient{Source: MatchSourceSubstitute, SubstituteID: "x"}
+	newResults := func() []MatchResult {
+		return []MatchResult{
+			{Recipe: clients.Recipe{ID: "a-heavy"}, CoveragePct: 100, MatchedIngredients: []MatchedIngredient{sub, sub, direct, direct}},
+			{Recipe: clients.Recipe{ID: "b-light"}, CoveragePct: 100, MatchedIngredients: []MatchedIngredient{sub, direct, direct, direct}},
+			{Recipe: clients.Recipe{ID: "c-direct"}, CoveragePct: 100, MatchedIngredients: []MatchedIngredient{direct, direct}},
+			{Recipe: clients.Recipe{ID: "d-partial"}, CoveragePct: 90, MatchedIngredients: []MatchedIngredient{direct}},
+		}
+	}
+	ids := func(results []MatchResult) []string {
+		out := make([]string, 0, len(results))
+		for _, r := range results {
+			out = append(out, r.Recipe.ID)
+		}
+		return out
+	}
+
+	unpenalized := newResults()
+	sortResults(unpenalized, "", 0)
+	assert.Equal(t, []string{"a-heavy", "b-light", "c-direct", "d-partial"}, ids(unpenalized))
+
+	penalized := newResults()
+	sortResults(penalized, "", 2)
+	assert.Equal(t, []string{"c-direct", "b-light", "a-heavy", "d-partial"}, ids(penalized))
+
+	// A large enough penalty ranks heavy substitution below lower coverage.
+	steep := newResults()
+	sortResults(steep, "", 10)
+	assert.Equal(t, []string{"c-direct", "b-light", "d-partial", "a-heavy"}, ids(steep))
+	assert.InDelta(t, 100.0, steep[3].CoveragePct, 0.0001, "reported coverage unchanged")
+}
+
+func TestScore_SubstitutionPenalty(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour"}, {IngredientID: "oil"}, {IngredientID: "butter"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "a-substituted", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}, {IngredientID: "ghee"}}},
+		{ID: "b-stocked", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}, {IngredientID: "butter"}}},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "ghee").Return([]clients.IngredientSubstitute{
+		{IngredientID: "ghee", SubstituteID: "oil", Ratio: 1},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock, WithSubstitutionPenalty(1))
+	res, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true, skipNames: true})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 2)
+	assert.Equal(t, "b-stocked", res.Results[0].Recipe.ID)
+	assert.Equal(t, "a-substituted", res.Results[1].Recipe.ID)
+	assert.True(t, res.Results[1].CanMake)
+}
+
+func TestMergeGroupSubstitutes(t *testing.T) {
+	t.Parallel()
+	subsMap := map[string][]clients.IngredientSubstitute{
+		"spinach": {{IngredientID: "spinach", SubstituteID: "arugula", Ratio: 1.0}},
+	}
+	groups := map[string]*clients.SubstitutionGroup{
+		"spinach": {ID: "g1", Name: "leafy greens", MemberIDs: []string{"spinach", "kale"}},
+	}
+
+	mergeGroupSubstitutes(subsMap, groups)
+
+	require.Len(t, subsMap["spinach"], 2)
+	assert.Equal(t, "arugula", subsMap["spinach"][0].SubstituteID)
+	assert.Equal(t, "kale", subsMap["spinach"][1].SubstituteID)
+	assert.InDelta(t, 1.0, subsMap["spinach"][1].Ratio, 0.0001)
+	assert.Empty(t, subsMap["spinach"][1].Notes)
+}
+
+func TestScore_SubstitutionGroup(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "kale"},
+	}, nil).Times(2)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{
+			ID:    "r1",
+			Title: "Green salad",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri1", IngredientID: "spinach"},
+			},
+		},
+	}, nil).Times(2)
+	dictMock.EXPECT().GetSubstitutionGroup(mock.Anything, "spinach").Return(&clients.SubstitutionGroup{
+		ID: "g1", Name: "leafy greens", MemberIDs: []string{"spinach", "kale", "chard"},
+	}, nil).Once()
+	dictMock.EXPECT().GetIngredient(mock.Anything, "spinach").Return(nil, clients.ErrIngredientNotFound).Once()
+
+	svc := New(pantryMock, recipeMock, dictMock)
+
+	res, err := svc.Score(context.Background(), ScoreOptions{UseGroups: true})
+	require.NoError(t, err)
+	results := res.Results
+	require.Len(t, results, 1)
+	assert.InDelta(t, 100.0, results[0].CoveragePct, 0.0001)
+	assert.True(t, results[0].CanMake)
+	assert.Equal(t, map[string]string{"spinach": "kale"}, results[0].SubstitutedWith)
+	assert.Empty(t, results[0].SubstituteNotes)
+
+	// Without use_groups the group member does not count.
+	res, err = svc.Score(context.Background(), ScoreOptions{MaxMissing: 1})
+	require.NoError(t, err)
+	results = res.Results
+	require.Len(t, results, 1)
+	assert.InDelta(t, 0.0, results[0].CoveragePct, 0.0001)
+}
+
+func TestScore_BudgetExhaustedSkipsNameResolution(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{
+			ID:    "r1",
+			Title: "Toast",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri1", IngredientID: "bread"},
+			},
+		},
+	}, nil)
+	// No dictionary expectations: neither subs nor names may be fetched.
+
+	svc := New(pantryMock, recipeMock, dictMock, WithScoreBudget(bestEffortReserve/2))
+	res, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true, MaxMissing: 1})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "bread", res.Results[0].MissingIngredients[0].IngredientID)
+	assert.Empty(t, res.Results[0].MissingIngredients[0].Name)
+	assert.Equal(t, []string{
+		"substitute lookup skipped: score budget exhausted",
+		"name resolution skipped: score budget exhausted",
+	}, res.Warnings)
+}
+
+func TestScore_ContextDeadlineSkipsNameResolution(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{ID: "ri1", IngredientID: "bread"}}},
+	}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), bestEffortReserve/2)
+	defer cancel()
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(ctx, ScoreOptions{MaxMissing: 1})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, []string{"name resolution skipped: score budget exhausted"}, res.Warnings)
+}
+
+func TestScore_BudgetAmpleResolvesNames(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{ID: "ri1", IngredientID: "bread"}}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "bread").Return(&clients.IngredientDetail{ID: "bread", Name: "bread"}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock, WithScoreBudget(time.Minute))
+	res, err := svc.Score(context.Background(), ScoreOptions{MaxMissing: 1})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.Equal(t, "bread", res.Results[0].MissingIngredients[0].Name)
+	assert.Empty(t, res.Warnings)
+}
+
+func TestRemoveExcludedSubstitutes(t *testing.T) {
+	t.Parallel()
+	newSubsMap := func() map[string][]clients.IngredientSubstitute {
+		return map[string][]clients.IngredientSubstitute{
+			"sour_cream": {
+				{IngredientID: "sour_cream", SubstituteID: "yogurt"},
+				{IngredientID: "sour_cream", SubstituteID: "creme_fraiche"},
+			},
+			"buttermilk": {
+				{IngredientID: "buttermilk", SubstituteID: "yogurt"},
+			},
+		}
+	}
+
+	subsMap := newSubsMap()
+	removeExcludedSubstitutes(subsMap, []string{"yogurt"})
+	require.Len(t, subsMap["sour_cream"], 1)
+	assert.Equal(t, "creme_fraiche", subsMap["sour_cream"][0].SubstituteID)
+	assert.Empty(t, subsMap["buttermilk"])
+
+	subsMap = newSubsMap()
+	removeExcludedSubstitutes(subsMap, []string{"sour_cream:yogurt"})
+	require.Len(t, subsMap["sour_cream"], 1)
+	assert.Equal(t, "creme_fraiche", subsMap["sour_cream"][0].SubstituteID)
+	require.Len(t, subsMap["buttermilk"], 1)
+	assert.Equal(t, "yogurt", subsMap["buttermilk"][0].SubstituteID)
+}
+
+func TestScore_ExcludedSubstituteNotUsed(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "yogurt"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{
+			ID:    "r1",
+			Title: "Cheesecake",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri1", IngredientID: "sour_cream"},
+			},
+		},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "sour_cream").Return([]clients.IngredientSubstitute{
+		{IngredientID: "sour_cream", SubstituteID: "yogurt", Ratio: 1.0},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "sour_cream").Return(nil, clients.ErrIngredientNotFound)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{
+		AllowSubs:   true,
+		MaxMissing:  1,
+		ExcludeSubs: []string{"yogurt"},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.InDelta(t, 0.0, res.Results[0].CoveragePct, 0.0001)
+	require.Len(t, res.Results[0].MissingIngredients, 1)
+	assert.Equal(t, "sour_cream", res.Results[0].MissingIngredients[0].IngredientID)
+}
+
+func TestScore_MaxCaloriesAndNutritionPassthrough(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Title: "Salad", Nutrition: &clients.Nutrition{Calories: 350, ProteinGrams: 12}},
+		{ID: "r2", Title: "Lasagne", Nutrition: &clients.Nutrition{Calories: 900}},
+		{ID: "r3", Title: "Mystery"},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{MaxCalories: 600})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 2)
+	titles := []string{res.Results[0].Recipe.Title, res.Results[1].Recipe.Title}
+	assert.ElementsMatch(t, []string{"Salad", "Mystery"}, titles)
+	for _, r := range res.Results {
+		if r.Recipe.ID == "r1" {
+			require.NotNil(t, r.Recipe.Nutrition)
+			assert.InDelta(t, 350.0, r.Recipe.Nutrition.Calories, 0.0001)
+			assert.InDelta(t, 12.0, r.Recipe.Nutrition.ProteinGrams, 0.0001)
+		}
+	}
+}
+
+func TestScore_TagsFilter(t *testing.T) {
+	t.Parallel()
+	recipes := []clients.Recipe{
+		{ID: "r1", Title: "Veg Curry", Tags: []string{"vegetarian", "spicy"}},
+		{ID: "r2", Title: "Quick Salad", Tags: []string{"vegetarian", "quick"}},
+		{ID: "r3", Title: "Steak", Tags: []string{"quick"}},
+		{ID: "r4", Title: "Stew"},
+	}
+
+	for name, tc := range map[string]struct {
+		tags    []string
+		tagsAny bool
+		want    []string
+	}{
+		"empty tags":     {want: []string{"r1", "r2", "r3", "r4"}},
+		"all tags":       {tags: []string{"vegetarian", "quick"}, want: []string{"r2"}},
+		"any tag":        {tags: []string{"spicy", "quick"}, tagsAny: true, want: []string{"r1", "r2", "r3"}},
+		"no recipe":      {tags: []string{"dessert"}, want: []string{}},
+		"any, no recipe": {tags: []string{"dessert"}, tagsAny: true, want: []string{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			dictMock := mocks.NewMockDictionaryFetcher(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+
+			svc := New(pantryMock, recipeMock, dictMock)
+			res, err := svc.Score(context.Background(), ScoreOptions{Tags: tc.tags, TagsAny: tc.tagsAny})
+			require.NoError(t, err)
+
+			ids := make([]string, 0, len(res.Results))
+			for _, r := range res.Results {
+				ids = append(ids, r.Recipe.ID)
+			}
+			assert.ElementsMatch(t, tc.want, ids)
+		})
+	}
+}
+
+func TestScore_MaxTotalMinutes(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "egg"}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Title: "Omelette", PrepMinutes: 10, CookMinutes: 20, Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}}},
+		{ID: "r2", Title: "Roast", PrepMinutes: 10, CookMinutes: 21, Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}}},
+		{ID: "r3", Title: "Quiche", CookMinutes: 25, Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}, {IngredientID: "cream"}}},
+		{ID: "r4", Title: "Boiled Egg", Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "cream").Return(&clients.IngredientDetail{ID: "cream", Name: "cream"}, nil).Maybe()
+
+	limit := 30
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{MaxTotalMinutes: &limit})
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(res.Results))
+	for _, r := range res.Results {
+		ids = append(ids, r.Recipe.ID)
+	}
+	// r1 sits exactly at the limit; r3 fits in time but misses an ingredient.
+	assert.ElementsMatch(t, []string{"r1", "r4"}, ids)
+}
+
+func TestScore_PantryConstrained(t *testing.T) {
+	t.Parallel()
+	recipes := []clients.Recipe{
+		{ID: "r1", Title: "Omelette", Ingredients: []clients.RecipeIngredient{{IngredientID: "egg"}}},
+		{ID: "r2", Title: "Quiche", Tags: []string{"flexible"}, Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "egg"}, {IngredientID: "cream"},
+		}},
+	}
+
+	for name, tc := range map[string]struct {
+		constrained bool
+		want        []string
+	}{
+		"unconstrained honours max_missing": {want: []string{"r1", "r2"}},
+		"constrained forces max_missing 0":  {constrained: true, want: []string{"r1"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			dictMock := mocks.NewMockDictionaryFetcher(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "egg"}}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+			dictMock.EXPECT().GetIngredient(mock.Anything, "cream").Return(&clients.IngredientDetail{ID: "cream", Name: "cream"}, nil).Maybe()
+
+			svc := New(pantryMock, recipeMock, dictMock, WithTagMaxMissing(map[string]int{"flexible": 2}))
+			res, err := svc.Score(context.Background(), ScoreOptions{
+				PantryConstrained:  tc.constrained,
+				MaxMissing:         1,
+				IncludeUnmakeable:  true,
+				NearMissMaxMissing: 1,
+			})
+			require.NoError(t, err)
+
+			ids := make([]string, 0, len(res.Results))
+			for _, r := range res.Results {
+				ids = append(ids, r.Recipe.ID)
+				if tc.constrained {
+					assert.True(t, r.CanMake)
+				}
+			}
+			assert.Equal(t, tc.want, ids)
+		})
+	}
+}
+
+func TestScore_DictionaryConcurrencyBounded(t *testing.T) {
+	t.Parallel()
+	ingredients := make([]clients.RecipeIngredient, 0, 20)
+	for i := range 20 {
+		ingredients = append(ingredients, clients.RecipeIngredient{IngredientID: fmt.Sprintf("ing%02d", i)})
+	}
+	recipes := []clients.Recipe{{ID: "r1", Title: "Feast", Ingredients: ingredients}}
+
+	score := func(t *testing.T, limit int) ([]MatchResult, int32) {
+		t.Helper()
+		var inFlight, peak atomic.Int32
+		track := func() func() {
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(2 * time.Millisecond)
+			return func() { inFlight.Add(-1) }
+		}
+
+		pantryMock := mocks.NewMockPantryFetcher(t)
+		recipeMock := mocks.NewMockRecipeFetcher(t)
+		dictMock := mocks.NewMockDictionaryFetcher(t)
+		pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "ing00-sub"}}, nil)
+		recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+		dictMock.EXPECT().GetSubstitutes(mock.Anything, mock.Anything).RunAndReturn(
+			func(_ context.Context, id string) ([]clients.IngredientSubstitute, error) {
+				defer track()()
+				return []clients.IngredientSubstitute{{IngredientID: id, SubstituteID: id + "-sub", Ratio: 1}}, nil
+			})
+		dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).RunAndReturn(
+			func(_ context.Context, id string) (*clients.IngredientDetail, error) {
+				defer track()()
+				return &clients.IngredientDetail{ID: id, Name: "name " + id}, nil
+			})
+
+		svc := New(pantryMock, recipeMock, dictMock, WithDictionaryConcurrency(limit))
+		res, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true, MaxMissing: 20, IncludeMatched: true})
+		require.NoError(t, err)
+		return res.Results, peak.Load()
+	}
+
+	bounded, peak := score(t, 3)
+	assert.LessOrEqual(t, peak, int32(3))
+	assert.Positive(t, peak)
+
+	unbounded, _ := score(t, 50)
+	assert.Equal(t, unbounded, bounded)
+}
+
+func TestLimitSubstitutes(t *testing.T) {
+	t.Parallel()
+	subs := []clients.IngredientSubstitute{
+		{SubstituteID: "half", Ratio: 0.5},
+		{SubstituteID: "exact", Ratio: 1.0},
+		{SubstituteID: "double", Ratio: 2.0},
+		{SubstituteID: "close", Ratio: 0.9},
+		{SubstituteID: "near", Ratio: 1.1},
+	}
+
+	limited := limitSubstitutes(subs, 3)
+	require.Len(t, limited, 3)
+	assert.Equal(t, "exact", limited[0].SubstituteID)
+	assert.Equal(t, "close", limited[1].SubstituteID)
+	assert.Equal(t, "near", limited[2].SubstituteID)
+	assert.Equal(t, "half", subs[0].SubstituteID, "input must not be reordered")
+
+	assert.Len(t, limitSubstitutes(subs, 0), 5)
+	assert.Len(t, limitSubstitutes(subs, 10), 5)
+}
+
+func TestScore_MaxSubstitutes(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "applesauce"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Title: "Muffins", Ingredients: []clients.RecipeIngredient{{ID: "ri1", IngredientID: "egg"}}},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "egg").Return([]clients.IngredientSubstitute{
+		{IngredientID: "egg", SubstituteID: "flax", Ratio: 1.0},
+		{IngredientID: "egg", SubstituteID: "applesauce", Ratio: 0.25},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "egg").Return(nil, clients.ErrIngredientNotFound)
+
+	svc := New(pantryMock, recipeMock, dictMock, WithMaxSubstitutes(1))
+	res, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true, MaxMissing: 1})
+	require.NoError(t, err)
+
+	// Only flax (ratio closest to 1) is kept, so applesauce in the pantry does not help.
+	require.Len(t, res.Results, 1)
+	assert.InDelta(t, 0.0, res.Results[0].CoveragePct, 0.0001)
+}
+
+func TestScore_EmptyRecipePolicy(t *testing.T) {
+	t.Parallel()
+
+	recipes := []clients.Recipe{
+		{ID: "r1", Title: "Nothing", Ingredients: []clients.RecipeIngredient{}},
+		{ID: "r2", Title: "Toast", Ingredients: []clients.RecipeIngredient{{ID: "ri1", IngredientID: "bread"}}},
+	}
+
+	for name, tc := range map[string]struct {
+		opts     []Option
+		wantIDs  []string
+		warnings []string
+	}{
+		"default":  {wantIDs: []string{"r1", "r2"}},
+		"makeable": {opts: []Option{WithEmptyRecipePolicy(EmptyRecipeMakeable)}, wantIDs: []string{"r1", "r2"}},
+		"exclude": {
+			opts:     []Option{WithEmptyRecipePolicy(EmptyRecipeExclude)},
+			wantIDs:  []string{"r2"},
+			warnings: []string{"excluded 1 recipes with no ingredients"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			dictMock := mocks.NewMockDictionaryFetcher(t)
+
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "bread"}}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+
+			svc := New(pantryMock, recipeMock, dictMock, tc.opts...)
+			res, err := svc.Score(context.Background(), ScoreOptions{})
+			require.NoError(t, err)
+
+			ids := make([]string, 0, len(res.Results))
+			for _, r := range res.Results {
+				ids = append(ids, r.Recipe.ID)
+			}
+			assert.Equal(t, tc.wantIDs, ids)
+			assert.Equal(t, tc.warnings, res.Warnings)
+		})
+	}
+}
+
+func TestBuildPantrySet_QuantityFloor(t *testing.T) {
+	t.Parallel()
+
+	items := []clients.PantryItem{
+		{ID: "p1", IngredientID: "flour", Quantity: 500, Unit: "g"},
+		{ID: "p2", IngredientID: "salt", Quantity: 0, Unit: "g", QuantityReported: true},
+		{ID: "p3", IngredientID: "yeast", Quantity: 0.01, Unit: "g"},
+		{ID: "p4", IngredientID: "sugar", Quantity: -1, Unit: "g"},
+		{ID: "p5", IngredientID: "pepper"},
+	}
+
+	assert.Equal(t, map[string]bool{"flour": true, "yeast": true, "pepper": true}, buildPantrySet(items, nil),
+		"without a floor only reported quantities of 0 or less are absent")
+
+	zero := 0.0
+	assert.Equal(t, map[string]bool{"flour": true, "yeast": true}, buildPantrySet(items, &zero))
+
+	tiny := 0.05
+	assert.Equal(t, map[string]bool{"flour": true}, buildPantrySet(items, &tiny))
+
+	negative := -1.0
+	assert.Equal(t, map[string]bool{"flour": true, "salt": true, "yeast": true, "pepper": true},
+		buildPantrySet(items, &negative), "a negative floor counts zero-quantity items")
+}
+
+func TestScore_PantryQuantityFloor(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour", Quantity: 500},
+		{IngredientID: "milk", Quantity: 0},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "milk"},
+		}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, nil, WithPantryQuantityFloor(0))
+	res, err := svc.Score(context.Background(), ScoreOptions{IncludeUnmakeable: true, skipNames: true})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.False(t, res.Results[0].CanMake)
+	require.Len(t, res.Results[0].MissingIngredients, 1)
+	assert.Equal(t, "milk", res.Results[0].MissingIngredients[0].IngredientID)
+}
+
+func TestScore_PantryStaples(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		opts        ScoreOptions
+		wantMissing []string
+	}{
+		{name: "configured staples are present", wantMissing: []string{"eggs"}},
+		{
+			name:        "request override replaces them",
+			opts:        ScoreOptions{Staples: []string{"water"}},
+			wantMissing: []string{"salt", "eggs"},
+		},
+		{
+			name:        "empty override disables them",
+			opts:        ScoreOptions{Staples: []string{}},
+			wantMissing: []string{"salt", "water", "eggs"},
+		},
+		{
+			name:        "staples have unlimited stock",
+			opts:        ScoreOptions{Strategy: StrategyQuantity},
+			wantMissing: []string{"eggs"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+				{IngredientID: "flour", Quantity: 500, Unit: "g"},
+			}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+				{ID: "bread", Ingredients: []clients.RecipeIngredient{
+					{IngredientID: "flour", Quantity: 400, Unit: "g"},
+					{IngredientID: "salt", Quantity: 10, Unit: "g"},
+					{IngredientID: "water", Quantity: 300, Unit: "ml"},
+					{IngredientID: "eggs", Quantity: 2},
+				}},
+			}, nil)
+
+			svc := New(pantryMock, recipeMock, nil, WithPantryStaples([]string{"salt", "water"}))
+			opts := tc.opts
+			opts.IncludeUnmakeable = true
+			opts.skipNames = true
+			res, err := svc.Score(context.Background(), opts)
+			require.NoError(t, err)
+
+			require.Len(t, res.Results, 1)
+			var missing []string
+			for _, m := range res.Results[0].MissingIngredients {
+				missing = append(missing, m.IngredientID)
+			}
+			assert.Equal(t, tc.wantMissing, missing)
+		})
+	}
+}
+
+func TestScore_CoverageRounding(t *testing.T) {
+	t.Parallel()
+
+	recipes := []clients.Recipe{
+		// 66.66% raw: ranks below the recipe at 66.67% even though both
+		// are emitted as 66.7 and its ID sorts first.
+		{ID: "a-lower", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Weight: 0.6666}, {IngredientID: "eggs", Weight: 0.3334},
+		}},
+		{ID: "z-higher", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour", Weight: 2}, {IngredientID: "eggs"},
+		}},
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []float64
+	}{
+		{name: "default one decimal", want: []float64{66.7, 66.7}},
+		{name: "whole numbers", opts: []Option{WithCoverageDecimals(0)}, want: []float64{67, 67}},
+		{name: "unrounded", opts: []Option{WithCoverageDecimals(-1)}, want: []float64{200.0 / 3, 66.66}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+				{IngredientID: "flour", Quantity: 1},
+			}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+
+			svc := New(pantryMock, recipeMock, nil, tc.opts...)
+			res, err := svc.Score(context.Background(), ScoreOptions{IncludeUnmakeable: true, skipNames: true})
+			require.NoError(t, err)
+
+			require.Len(t, res.Results, 2)
+			assert.Equal(t, "z-higher", res.Results[0].Recipe.ID)
+			assert.Equal(t, "a-lower", res.Results[1].Recipe.ID)
+			assert.InDelta(t, tc.want[0], res.Results[0].CoveragePct, 1e-9)
+			assert.InDelta(t, tc.want[1], res.Results[1].CoveragePct, 1e-9)
+		})
+	}
+}
+
+func TestScore_InlinePantry(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, nil)
+	for _, tc := range []struct {
+		name    string
+		pantry  []clients.PantryItem
+		wantLen int
+	}{
+		{name: "stocked", pantry: []clients.PantryItem{{IngredientID: "flour", Quantity: 1}}, wantLen: 1},
+		{name: "empty", pantry: []clients.PantryItem{}, wantLen: 0},
+	} {
+		res, err := svc.Score(context.Background(), ScoreOptions{Pantry: tc.pantry, skipNames: true})
+		require.NoError(t, err, tc.name)
+		assert.Len(t, res.Results, tc.wantLen, tc.name)
+		assert.Equal(t, len(tc.pantry), res.Meta.PantryItems, tc.name)
+	}
+	pantryMock.AssertNotCalled(t, "GetPantry", mock.Anything)
+}
+
+func TestScore_Meta(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour", Quantity: 500},
+		{IngredientID: "milk", Quantity: 0},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Tags: []string{"quick"}, Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}}},
+		{ID: "r2", Ingredients: []clients.RecipeIngredient{{IngredientID: "eggs"}}},
+		{ID: "r1", Tags: []string{"quick"}, Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}}},
+		{ID: "r3", Tags: []string{"quick"}, Ingredients: []clients.RecipeIngredient{{IngredientID: "eggs"}}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, nil)
+	res, err := svc.Score(context.Background(), ScoreOptions{Tags: []string{"quick"}, skipNames: true})
+	require.NoError(t, err)
+
+	// Every fetched recipe is considered, but the duplicate and the untagged
+	// recipe are not scored; every fetched pantry item is counted, depleted
+	// or not.
+	assert.Equal(t, ScoreMeta{RecipesConsidered: 4, RecipesScored: 2, PantryItems: 2}, res.Meta)
+	assert.Len(t, res.Results, 1)
+}
+
+func TestInputsHash(t *testing.T) {
+	t.Parallel()
+
+	pantry := []clients.PantryItem{{IngredientID: "flour", Quantity: 500}}
+	recipes := []clients.Recipe{{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}}}}
+
+	hash := inputsHash(pantry, recipes)
+	assert.NotEmpty(t, hash)
+	assert.Equal(t, hash, inputsHash(slices.Clone(pantry), slices.Clone(recipes)))
+	assert.NotEqual(t, hash, inputsHash([]clients.PantryItem{{IngredientID: "flour", Quantity: 250}}, recipes))
+	assert.NotEqual(t, hash, inputsHash(pantry, nil))
+}
+
+func TestScore_DepletedPantryItemsAbsent(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour", Quantity: 500},
+		{IngredientID: "milk", Quantity: 0, QuantityReported: true},
+		{IngredientID: "eggs", Quantity: -2},
+		// No quantity reported: present.
+		{IngredientID: "salt"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "milk"}, {IngredientID: "eggs"}, {IngredientID: "salt"},
+		}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, nil)
+	res, err := svc.Score(context.Background(), ScoreOptions{IncludeUnmakeable: true, skipNames: true})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	assert.False(t, res.Results[0].CanMake)
+	missing := make([]string, 0, len(res.Results[0].MissingIngredients))
+	for _, m := range res.Results[0].MissingIngredients {
+		missing = append(missing, m.IngredientID)
+	}
+	assert.Equal(t, []string{"milk", "eggs"}, missing)
+}
+
+func TestScore_DuplicateRecipes(t *testing.T) {
+	t.Parallel()
+
+	recipes := []clients.Recipe{
+		{ID: "r1", Title: "First copy", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}}},
+		{ID: "r2", Title: "Other", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}}},
+		{ID: "r1", Title: "Second copy", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}}},
+	}
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantTitle string
+	}{
+		{name: "keep first by default", wantTitle: "First copy"},
+		{name: "keep last", opts: []Option{WithDuplicateRecipePolicy(DuplicateRecipeKeepLast)}, wantTitle: "Second copy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "ing1"}}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+
+			svc := New(pantryMock, recipeMock, nil, tt.opts...)
+			res, err := svc.Score(context.Background(), ScoreOptions{})
+			require.NoError(t, err)
+
+			require.Len(t, res.Results, 2)
+			titles := map[string]string{}
+			for _, r := range res.Results {
+				titles[r.Recipe.ID] = r.Recipe.Title
+			}
+			assert.Equal(t, tt.wantTitle, titles["r1"])
+			assert.Equal(t, []string{"dropped 1 duplicate recipes"}, res.Warnings)
+		})
+	}
+}
+
+func TestBuildPantryStock(t *testing.T) {
+	t.Parallel()
+
+	stock := buildPantryStock([]clients.PantryItem{
+		{ID: "p1", IngredientID: "flour", Quantity: 100, Unit: "g"},
+		{ID: "p2", IngredientID: "flour", Quantity: 50, Unit: "g"},
+		{ID: "p3", IngredientID: "milk", Quantity: 0.5, Unit: "cup"},
+	})
+
+	assert.Equal(t, pantryStock{Quantity: 150, Unit: "g"}, stock["flour"])
+	assert.Equal(t, pantryStock{Quantity: 0.5, Unit: "cup"}, stock["milk"])
+}
+
+func TestAttachHaveQuantities(t *testing.T) {
+	t.Parallel()
+
+	results := []MatchResult{{
+		MissingIngredients: []MissingIngredient{
+			{IngredientID: "milk", Quantity: 2, Unit: "cup"},
+			{IngredientID: "eggs", Quantity: 3, Unit: "whole"},
+		},
+	}}
+
+	attachHaveQuantities(results, map[string]pantryStock{"milk": {Quantity: 0.5, Unit: "cup"}})
+
+	assert.InDelta(t, 0.5, results[0].MissingIngredients[0].HaveQuantity, 0.0001)
+	assert.Equal(t, "cup", results[0].MissingIngredients[0].HaveUnit)
+	assert.Zero(t, results[0].MissingIngredients[1].HaveQuantity)
+	assert.Empty(t, results[0].MissingIngredients[1].HaveUnit)
+}
+
+func TestScoreRecipe_OptionalAsRequired(t *testing.T) {
+	t.Parallel()
+	recipe := clients.Recipe{
+		ID:    "r1",
+		Title: "Salad",
+		Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "ing1", IsOptional: false},
+			{ID: "ri2", IngredientID: "ing2", IsOptional: true},
+		},
+	}
+	pantrySet := map[string]bool{"ing1": true}
+
+	result := scoreRecipe(recipe, pantrySet, nil, scoreRules{optionalAsRequired: true})
+
+	assert.InDelta(t, 50.0, result.CoveragePct, 0.0001)
+	assert.False(t, result.CanMake)
+	require.Len(t, result.MissingIngredients, 1)
+	assert.Equal(t, "ing2", result.MissingIngredients[0].IngredientID)
+
+	result = scoreRecipe(recipe, pantrySet, nil, scoreRules{})
+	assert.True(t, result.CanMake)
+}
+
+func TestScore_OptionalSubstitutes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		wantFetch    bool
+		wantCoverage float64
+	}{
+		{name: "required only by default", wantCoverage: 50},
+		{name: "enabled", opts: []Option{WithOptionalSubstitutes(true)}, wantFetch: true, wantCoverage: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			dictMock := mocks.NewMockDictionaryFetcher(t)
+
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+				{IngredientID: "ing1"}, {IngredientID: "parsley"},
+			}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+				{
+					ID: "r1",
+					Ingredients: []clients.RecipeIngredient{
+						{ID: "ri1", IngredientID: "ing1"},
+						{ID: "ri2", IngredientID: "cilantro", IsOptional: true},
+					},
+				},
+			}, nil)
+			if tt.wantFetch {
+				dictMock.EXPECT().GetSubstitutes(mock.Anything, "cilantro").Return([]clients.IngredientSubstitute{
+					{IngredientID: "cilantro", SubstituteID: "parsley", Ratio: 1},
+				}, nil)
+			}
+
+			svc := New(pantryMock, recipeMock, dictMock, tt.opts...)
+			res, err := svc.Score(context.Background(), ScoreOptions{
+				AllowSubs:               true,
+				TreatOptionalAsRequired: true,
+				IncludeUnmakeable:       true,
+				skipNames:               true,
+			})
+			require.NoError(t, err)
+
+			require.Len(t, res.Results, 1)
+			assert.InDelta(t, tt.wantCoverage, res.Results[0].CoveragePct, 0.0001)
+		})
+	}
+}
+
+func TestScoreRecipe_OptionalSubstitutesPerIngredient(t *testing.T) {
+	t.Parallel()
+
+	// cilantro is optional here, so a substitute fetched because another
+	// recipe requires it must not satisfy it unless optional subs are enabled.
+	recipe := clients.Recipe{
+		ID: "r1",
+		Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "cilantro", IsOptional: true},
+		},
+	}
+	pantrySet := map[string]bool{"parsley": true}
+	subsMap := map[string][]clients.IngredientSubstitute{
+		"cilantro": {{IngredientID: "cilantro", SubstituteID: "parsley", Ratio: 1}},
+	}
+
+	result := scoreRecipe(recipe, pantrySet, subsMap, scoreRules{optionalAsRequired: true})
+	assert.InDelta(t, 0.0, result.CoveragePct, 0.0001)
+
+	result = scoreRecipe(recipe, pantrySet, subsMap, scoreRules{optionalAsRequired: true, optionalSubs: true})
+	assert.InDelta(t, 100.0, result.CoveragePct, 0.0001)
+}
+
+func TestScoreRecipe_TotalMinutes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		prep, cook int
+		want       int
+	}{
+		{name: "sum", prep: 15, cook: 30, want: 45},
+		{name: "no cook time", prep: 10, want: 10},
+		{name: "no times", want: 0},
+		{name: "negative treated as zero", prep: -5, cook: 20, want: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			recipe := clients.Recipe{
+				ID:          "r1",
+				PrepMinutes: tt.prep,
+				CookMinutes: tt.cook,
+				Ingredients: []clients.RecipeIngredient{{IngredientID: "ing1"}},
+			}
+			result := scoreRecipe(recipe, map[string]bool{}, nil, scoreRules{})
+			assert.Equal(t, tt.want, result.TotalMinutes)
+
+			recipe.Ingredients = nil
+			result = scoreRecipe(recipe, map[string]bool{}, nil, scoreRules{})
+			assert.Equal(t, tt.want, result.TotalMinutes, "empty recipe")
+		})
+	}
+}
+
+func TestScore_NameFallback(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		want       string
+		wantSource NameSource
+	}{
+		{name: "empty by default", want: ""},
+		{name: "empty", opts: []Option{WithNameFallback(NameFallbackEmpty)}, want: ""},
+		{
+			name:       "id",
+			opts:       []Option{WithNameFallback(NameFallbackID)},
+			want:       "ing_unknown",
+			wantSource: NameSourceFallback,
+		},
+		{
+			name:       "placeholder",
+			opts:       []Option{WithNameFallback(NameFallbackPlaceholder)},
+			want:       UnknownIngredientName,
+			wantSource: NameSourceFallback,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			dictMock := mocks.NewMockDictionaryFetcher(t)
+
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+				{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "ing_unknown"}, {IngredientID: "ing_known"}}},
+			}, nil)
+			dictMock.EXPECT().GetIngredient(mock.Anything, "ing_unknown").Return(nil, clients.ErrIngredientNotFound)
+			dictMock.EXPECT().GetIngredient(mock.Anything, "ing_known").Return(&clients.IngredientDetail{ID: "ing_known", Name: "garlic"}, nil)
+
+			svc := New(pantryMock, recipeMock, dictMock, tt.opts...)
+			res, err := svc.Score(context.Background(), ScoreOptions{IncludeUnmakeable: true})
+			require.NoError(t, err)
+
+			require.Len(t, res.Results, 1)
+			names := map[string]string{}
+			sources := map[string]NameSource{}
+			for _, m := range res.Results[0].MissingIngredients {
+				names[m.IngredientID] = m.Name
+				sources[m.IngredientID] = m.NameSource
+			}
+			assert.Equal(t, tt.want, names["ing_unknown"])
+			assert.Equal(t, tt.wantSource, sources["ing_unknown"])
+			assert.Equal(t, "garlic", names["ing_known"])
+			assert.Equal(t, NameSourceExact, sources["ing_known"])
+		})
+	}
+}
+
+func TestScore_MaxNameLookups(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{ID: "p1", IngredientID: "flour"}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		// Ranked first (50%): its missing ingredient is resolved.
+		{ID: "bread", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}, {IngredientID: "yeast"}}},
+		// Ranked second (0%): past the cap.
+		{ID: "stew", Ingredients: []clients.RecipeIngredient{{IngredientID: "beef"}, {IngredientID: "carrot"}}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "yeast").
+		Return(&clients.IngredientDetail{ID: "yeast", Name: "yeast"}, nil).Once()
+
+	svc := New(pantryMock, recipeMock, dictMock, WithMaxNameLookups(1))
+	res, err := svc.Score(context.Background(), ScoreOptions{IncludeUnmakeable: true})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 2)
+	assert.Equal(t, "yeast", res.Results[0].MissingIngredients[0].Name)
+	for _, m := range res.Results[1].MissingIngredients {
+		assert.Empty(t, m.Name)
+	}
+	dictMock.AssertNumberOfCalls(t, "GetIngredient", 1)
+	assert.Contains(t, res.Warnings, "name resolution capped: 2 ingredient names not resolved")
+}
+
+func TestScoreRecipe_MinCoverageForCanMake(t *testing.T) {
+	t.Parallel()
+	recipe := clients.Recipe{
+		ID:    "r1",
+		Title: "Stew",
+		Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "ing1"},
+			{ID: "ri2", IngredientID: "ing2"},
+			{ID: "ri3", IngredientID: "ing3"},
+			{ID: "ri4", IngredientID: "ing4"},
+		},
+	}
+	pantrySet := map[string]bool{"ing1": true, "ing2": true, "ing3": true}
+
+	// 75% coverage, 1 missing: meets the missing cap but not a 90% minimum.
+	result := scoreRecipe(recipe, pantrySet, nil, scoreRules{maxMissing: 1, minCoverage: 90})
+	assert.InDelta(t, 75.0, result.CoveragePct, 0.0001)
+	assert.False(t, result.CanMake)
+
+	// Exactly at the minimum passes.
+	result = scoreRecipe(recipe, pantrySet, nil, scoreRules{maxMissing: 1, minCoverage: 75})
+	assert.True(t, result.CanMake)
+
+	// The missing cap still applies when coverage is sufficient.
+	result = scoreRecipe(recipe, pantrySet, nil, scoreRules{maxMissing: 0, minCoverage: 50})
+	assert.False(t, result.CanMake)
+}
+
+func TestScoreRecipe_WeightedCoverage(t *testing.T) {
+	t.Parallel()
+	pantrySet := map[string]bool{"chicken": true, "sub_rice": true}
+	subsMap := map[string][]clients.IngredientSubstitute{
+		"rice": {{IngredientID: "rice", SubstituteID: "sub_rice", Ratio: 1.0}},
+	}
+
+	unweighted := clients.Recipe{
+		ID: "r1",
+		Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "chicken"},
+			{ID: "ri2", IngredientID: "rice"},
+			{ID: "ri3", IngredientID: "parsley"},
+			{ID: "ri4", IngredientID: "salt"},
+		},
+	}
+	// Without weights every ingredient counts as 1: 2 of 4 covered.
+	result := scoreRecipe(unweighted, pantrySet, subsMap, scoreRules{maxMissing: 2})
+	assert.InDelta(t, 50.0, result.CoveragePct, 0.0001)
+
+	weighted := clients.Recipe{
+		ID: "r2",
+		Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "chicken", Weight: 4},
+			{ID: "ri2", IngredientID: "rice", Weight: 2},
+			{ID: "ri3", IngredientID: "parsley", Weight: 0.5},
+			{ID: "ri4", IngredientID: "salt"},
+		},
+	}
+	// Substitutes carry the weight of the ingredient they replace:
+	// (4 + 2) / (4 + 2 + 0.5 + 1).
+	result = scoreRecipe(weighted, pantrySet, subsMap, scoreRules{maxMissing: 2})
+	assert.InDelta(t, 80.0, result.CoveragePct, 0.0001)
+	assert.True(t, result.CanMake)
+
+	// Weights only affect coverage, not the missing count.
+	result = scoreRecipe(weighted, pantrySet, subsMap, scoreRules{maxMissing: 1})
+	assert.False(t, result.CanMake)
+	assert.Len(t, result.MissingIngredients, 2)
+}
+
+func TestIngredientWeight_DefaultsToOne(t *testing.T) {
+	t.Parallel()
+	assert.InDelta(t, 1.0, ingredientWeight(clients.RecipeIngredient{}), 0.0001)
+	assert.InDelta(t, 1.0, ingredientWeight(clients.RecipeIngredient{Weight: -2}), 0.0001)
+	assert.InDelta(t, 2.5, ingredientWeight(clients.RecipeIngredient{Weight: 2.5}), 0.0001)
+}
+
+func TestScoreRecipe_OptionalCoveragePct(t *testing.T) {
+	t.Parallel()
+	recipe := clients.Recipe{
+		ID: "r1",
+		Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "pasta"},
+			{ID: "ri2", IngredientID: "garlic"},
+			{ID: "ri3", IngredientID: "parsley", IsOptional: true},
+			{ID: "ri4", IngredientID: "chili", IsOptional: true, Weight: 3},
+		},
+	}
+	subsMap := map[string][]clients.IngredientSubstitute{
+		"parsley": {{IngredientID: "parsley", SubstituteID: "basil", Ratio: 1}},
+	}
+
+	none := scoreRecipe(recipe, map[string]bool{"pasta": true, "garlic": true}, subsMap, scoreRules{})
+	assert.InDelta(t, 0.0, none.OptionalCoveragePct, 0.0001)
+	assert.True(t, none.CanMake)
+
+	// Substitutes do not count; chili carries weight 3 of 4.
+	some := scoreRecipe(recipe, map[string]bool{"pasta": true, "garlic": true, "chili": true, "basil": true},
+		subsMap, scoreRules{optionalSubs: true})
+	assert.InDelta(t, 75.0, some.OptionalCoveragePct, 0.0001)
+	assert.InDelta(t, none.CoveragePct, some.CoveragePct, 0.0001)
+
+	// Optional extras never make a recipe makeable.
+	missingRequired := scoreRecipe(recipe, map[string]bool{"pasta": true, "parsley": true, "chili": true},
+		nil, scoreRules{})
+	assert.InDelta(t, 100.0, missingRequired.OptionalCoveragePct, 0.0001)
+	assert.False(t, missingRequired.CanMake)
+
+	// Counted as required, there are no optional ingredients left.
+	asRequired := scoreRecipe(recipe, map[string]bool{"parsley": true}, nil, scoreRules{optionalAsRequired: true})
+	assert.InDelta(t, 0.0, asRequired.OptionalCoveragePct, 0.0001)
+}
+
+func TestScoreRecipe_IncludeUsed(t *testing.T) {
+	t.Parallel()
+	recipe := clients.Recipe{
+		ID: "r1",
+		Ingredients: []clients.RecipeIngredient{
+			{ID: "ri1", IngredientID: "flour", Quantity: 200, Unit: "g"},
+			{ID: "ri2", IngredientID: "butter", Quantity: 100, Unit: "g"},
+			{ID: "ri3", IngredientID: "sugar", Quantity: 50, Unit: "g"},
+			{ID: "ri4", IngredientID: "vanilla", Quantity: 1, Unit: "tsp", IsOptional: true},
+		},
+	}
+	pantrySet := map[string]bool{"flour": true, "oil": true, "vanilla": true}
+	subsMap := map[string][]clients.IngredientSubstitute{
+		"butter": {{IngredientID: "butter", SubstituteID: "oil", Ratio: 0.8}},
+	}
+
+	result := scoreRecipe(recipe, pantrySet, subsMap, scoreRules{maxMissing: 1, includeUsed: true})
+
+	// Missing and optional ingredients draw nothing; the substitute is scaled.
+	assert.Equal(t, []UsedIngredient{
+		{IngredientID: "flour", Quantity: 200, Unit: "g"},
+		{IngredientID: "oil", Quantity: 80, Unit: "g", ForIngredientID: "butter"},
+	}, result.UsedIngredients)
+
+	result = scoreRecipe(recipe, pantrySet, subsMap, scoreRules{maxMissing: 1})
+	assert.Nil(t, result.UsedIngredients)
+}
+
+func TestScore_IncludeNames(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	catalog := []clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "pasta"},
+			{IngredientID: "garlic"},
+			{IngredientID: "parsley", IsOptional: true},
+		}},
+	}
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "pasta"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return(catalog, nil)
+	for id, name := range map[string]string{"pasta": "spaghetti", "garlic": "garlic", "parsley": "parsley"} {
+		dictMock.EXPECT().GetIngredient(mock.Anything, id).Return(&clients.IngredientDetail{ID: id, Name: name}, nil).Once()
+	}
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{MaxMissing: 1, IncludeMatched: true, IncludeNames: true})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	r := res.Results[0]
+	names := make([]string, 0, len(r.Recipe.Ingredients))
+	for _, ing := range r.Recipe.Ingredients {
+		names = append(names, ing.Name)
+	}
+	// Matched, missing and optional ingredients are all named.
+	assert.Equal(t, []string{"spaghetti", "garlic", "parsley"}, names)
+	assert.Equal(t, "spaghetti", r.MatchedIngredients[0].Name)
+	assert.Equal(t, "garlic", r.MissingIngredients[0].Name)
+	// The fetched catalog itself is left untouched.
+	assert.Empty(t, catalog[0].Ingredients[0].Name)
+}
+
+func TestScore_RecipeNamesOmittedByDefault(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "pasta"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "pasta"}, {IngredientID: "garlic"}}},
+	}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "garlic").Return(&clients.IngredientDetail{ID: "garlic", Name: "garlic"}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{MaxMissing: 1})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	for _, ing := range res.Results[0].Recipe.Ingredients {
+		assert.Empty(t, ing.Name)
+	}
+	dictMock.AssertNotCalled(t, "GetIngredient", mock.Anything, "pasta")
 }
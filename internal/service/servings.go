@@ -0,0 +1,30 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// scaleServings returns recipes with every ingredient quantity scaled from
+// the recipe's base servings to servings, and Servings set to match. Recipes
+// without a base are kept unscaled and counted in unscaled. The input slice
+// and its recipes are not modified.
+func scaleServings(recipes []clients.Recipe, servings int) (scaled []clients.Recipe, unscaled int) {
+	scaled = make([]clients.Recipe, len(recipes))
+	for i, recipe := range recipes {
+		switch {
+		case recipe.Servings <= 0:
+			unscaled++
+		case recipe.Servings != servings:
+			factor := float64(servings) / float64(recipe.Servings)
+			recipe.Ingredients = slices.Clone(recipe.Ingredients)
+			for j := range recipe.Ingredients {
+				recipe.Ingredients[j].Quantity *= factor
+			}
+			recipe.Servings = servings
+		}
+		scaled[i] = recipe
+	}
+	return scaled, unscaled
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestScore_Servings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		servings      int
+		wantCanMake   bool
+		wantQuantity  float64
+		wantShortfall float64
+	}{
+		{name: "base servings", servings: 0, wantCanMake: true},
+		{name: "scaled up", servings: 6, wantQuantity: 300, wantShortfall: 100},
+		{name: "scaled down", servings: 1, wantCanMake: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+				{ID: "p1", IngredientID: "flour", Quantity: 200, Unit: "g"},
+			}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+				{ID: "bread", Servings: 2, Ingredients: []clients.RecipeIngredient{
+					{IngredientID: "flour", Quantity: 100, Unit: "g"},
+				}},
+			}, nil)
+
+			svc := New(pantryMock, recipeMock, nil)
+			res, err := svc.Score(context.Background(), ScoreOptions{
+				Strategy:          StrategyQuantity,
+				Servings:          tc.servings,
+				IncludeUnmakeable: true,
+				skipNames:         true,
+			})
+			require.NoError(t, err)
+			require.Len(t, res.Results, 1)
+			assert.Empty(t, res.Warnings)
+
+			result := res.Results[0]
+			assert.Equal(t, tc.wantCanMake, result.CanMake)
+			if tc.wantCanMake {
+				assert.Empty(t, result.MissingIngredients)
+				return
+			}
+			require.Len(t, result.MissingIngredients, 1)
+			assert.InDelta(t, tc.wantQuantity, result.MissingIngredients[0].Quantity, 1e-9)
+			assert.InDelta(t, tc.wantShortfall, result.MissingIngredients[0].Shortfall, 1e-9)
+		})
+	}
+}
+
+func TestScaleServings(t *testing.T) {
+	t.Parallel()
+
+	recipes := []clients.Recipe{
+		{ID: "soup", Servings: 4, Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "stock", Quantity: 1000, Unit: "ml"},
+			{IngredientID: "salt"},
+		}},
+		{ID: "toast", Ingredients: []clients.RecipeIngredient{{IngredientID: "bread", Quantity: 2}}},
+	}
+
+	scaled, unscaled := scaleServings(recipes, 2)
+	assert.Equal(t, 1, unscaled)
+	assert.Equal(t, 2, scaled[0].Servings)
+	assert.InDelta(t, 500, scaled[0].Ingredients[0].Quantity, 1e-9)
+	assert.Zero(t, scaled[0].Ingredients[1].Quantity)
+	assert.Equal(t, recipes[1], scaled[1])
+	// The input recipes are shared with other requests and must not change.
+	assert.Equal(t, 4, recipes[0].Servings)
+	assert.InDelta(t, 1000, recipes[0].Ingredients[0].Quantity, 1e-9)
+}
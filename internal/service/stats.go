@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// coverageBucketWidth is the width of each histogram bucket in percentage
+// points. Exactly 100% coverage gets its own bucket.
+const coverageBucketWidth = 20.0
+
+// CoverageBucket counts recipes whose coverage falls in [MinPct, MaxPct).
+// The final bucket holds recipes at exactly 100%.
+type CoverageBucket struct {
+	Label  string  `json:"label"`
+	MinPct float64 `json:"min_pct"`
+	MaxPct float64 `json:"max_pct"`
+	Count  int     `json:"count"`
+}
+
+// Default versatility weights: a near-miss recipe counts half as much as a
+// makeable one.
+const (
+	defaultMakeableWeight = 1.0
+	defaultNearMissWeight = 0.5
+)
+
+// VersatilityWeights sets how much makeable and near-miss recipes contribute
+// to [CatalogStats.VersatilityScore].
+type VersatilityWeights struct {
+	Makeable float64
+	NearMiss float64
+}
+
+// CatalogStats summarises how well the pantry fits the whole recipe catalog.
+//
+// VersatilityScore is a single 0-100 measure of pantry fit:
+//
+//	100 * (Makeable*makeable_count + NearMiss*near_miss_count) / (Makeable*total_recipes)
+//
+// capped at 100 and rounded to one decimal. A pantry that can make every
+// recipe scores 100; an empty catalog scores 0.
+type CatalogStats struct {
+	TotalRecipes     int              `json:"total_recipes"`
+	MakeableCount    int              `json:"makeable_count"`
+	NearMissCount    int              `json:"near_miss_count"`
+	VersatilityScore float64          `json:"versatility_score"`
+	Histogram        []CoverageBucket `json:"histogram"`
+	// ExcludedRecipes counts recipes with no required ingredients left out
+	// of the statistics; see [WithStatsExcludeZeroRequired].
+	ExcludedRecipes int `json:"excluded_recipes,omitempty"`
+}
+
+// Stats scores the whole catalog, including unmakeable recipes, and returns
+// a coverage histogram, the number of makeable and near-miss recipes under
+// opts, and the pantry versatility score. Without near-miss thresholds in
+// opts, a recipe missing one more ingredient than max_missing is a near miss.
+func (s *Service) Stats(ctx context.Context, opts ScoreOptions) (*CatalogStats, error) {
+	opts.IncludeUnmakeable = true
+	opts.skipNames = true
+	opts.skipUsage = true
+	if opts.NearMissMaxMissing <= 0 && opts.NearMissMinCoverage <= 0 {
+		opts.NearMissMaxMissing = opts.MaxMissing + 1
+	}
+
+	res, err := s.scoreRaw(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results, excluded := res.Results, 0
+	if s.statsSkipZeroReq {
+		results = slices.DeleteFunc(results, func(r MatchResult) bool {
+			return !hasRequiredIngredients(r.Recipe, opts.TreatOptionalAsRequired)
+		})
+		excluded = len(res.Results) - len(results)
+	}
+
+	stats := buildCatalogStats(results, s.versatilityWeights())
+	stats.ExcludedRecipes = excluded
+	return stats, nil
+}
+
+// hasRequiredIngredients reports whether recipe has any ingredient that
+// counts toward coverage.
+func hasRequiredIngredients(recipe clients.Recipe, optionalAsRequired bool) bool {
+	rules := scoreRules{optionalAsRequired: optionalAsRequired}
+	return slices.ContainsFunc(recipe.Ingredients, rules.isRequired)
+}
+
+func (s *Service) versatilityWeights() VersatilityWeights {
+	if s.versatility != nil {
+		return *s.versatility
+	}
+	return VersatilityWeights{Makeable: defaultMakeableWeight, NearMiss: defaultNearMissWeight}
+}
+
+func buildCatalogStats(results []MatchResult, weights VersatilityWeights) *CatalogStats {
+	histogram := make([]CoverageBucket, 0, int(coveragePercentScale/coverageBucketWidth)+1)
+	for low := 0.0; low < coveragePercentScale; low += coverageBucketWidth {
+		high := low + coverageBucketWidth
+		histogram = append(histogram, CoverageBucket{
+			Label:  fmt.Sprintf("%.0f-%.0f%%", low, high),
+			MinPct: low,
+			MaxPct: high,
+		})
+	}
+	histogram = append(histogram, CoverageBucket{
+		Label:  "100%",
+		MinPct: coveragePercentScale,
+		MaxPct: coveragePercentScale,
+	})
+
+	stats := &CatalogStats{TotalRecipes: len(results), Histogram: histogram}
+	for _, r := range results {
+		switch {
+		case r.CanMake:
+			stats.MakeableCount++
+		case r.NearMiss:
+			stats.NearMissCount++
+		}
+		histogram[bucketIndex(r.CoveragePct, len(histogram))].Count++
+	}
+	stats.VersatilityScore = versatilityScore(stats, weights)
+	return stats
+}
+
+func versatilityScore(stats *CatalogStats, weights VersatilityWeights) float64 {
+	full := weights.Makeable * float64(stats.TotalRecipes)
+	if full <= 0 {
+		return 0
+	}
+	earned := weights.Makeable*float64(stats.MakeableCount) + weights.NearMiss*float64(stats.NearMissCount)
+	score := min(coveragePercentScale*earned/full, coveragePercentScale)
+	return math.Round(score*10) / 10
+}
+
+func bucketIndex(coveragePct float64, buckets int) int {
+	if coveragePct >= coveragePercentScale {
+		return buckets - 1
+	}
+	return min(max(int(coveragePct/coverageBucketWidth), 0), buckets-2)
+}
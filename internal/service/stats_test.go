@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+// recipeWithCoverage builds a recipe with total required ingredients of which
+// the first have are in statsPantry.
+func recipeWithCoverage(id string, have, total int) clients.Recipe {
+	ings := make([]clients.RecipeIngredient, 0, total)
+	for i := range total {
+		ingID := "missing"
+		if i < have {
+			ingID = "have"
+		}
+		ings = append(ings, clients.RecipeIngredient{IngredientID: fmt.Sprintf("%s_%s_%d", ingID, id, i)})
+	}
+	return clients.Recipe{ID: id, Title: id, Ingredients: ings}
+}
+
+func statsPantry(recipes []clients.Recipe) []clients.PantryItem {
+	var items []clients.PantryItem
+	for _, r := range recipes {
+		for _, ing := range r.Ingredients {
+			if strings.HasPrefix(ing.IngredientID, "have_") {
+				items = append(items, clients.PantryItem{IngredientID: ing.IngredientID})
+			}
+		}
+	}
+	return items
+}
+
+func TestStats_Histogram(t *testing.T) {
+	t.Parallel()
+
+	recipes := []clients.Recipe{
+		recipeWithCoverage("zero", 0, 2),    // 0%
+		recipeWithCoverage("tenth", 1, 10),  // 10%
+		recipeWithCoverage("third", 1, 3),   // 33.3%
+		recipeWithCoverage("half", 1, 2),    // 50%
+		recipeWithCoverage("most", 4, 5),    // 80%
+		recipeWithCoverage("nearly", 9, 10), // 90%
+		recipeWithCoverage("full", 2, 2),    // 100%
+		recipeWithCoverage("full2", 1, 1),   // 100%
+	}
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return(statsPantry(recipes), nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+	// No dictionary expectations: stats never resolve names.
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	stats, err := svc.Stats(context.Background(), ScoreOptions{MaxMissing: 1})
+	require.NoError(t, err)
+
+	assert.Equal(t, 8, stats.TotalRecipes)
+	// full, full2, half (1 missing), most (1 missing), nearly (1 missing).
+	assert.Equal(t, 5, stats.MakeableCount)
+
+	counts := map[string]int{}
+	for _, b := range stats.Histogram {
+		counts[b.Label] = b.Count
+	}
+	assert.Equal(t, map[string]int{
+		"0-20%":   2,
+		"20-40%":  1,
+		"40-60%":  1,
+		"60-80%":  0,
+		"80-100%": 2,
+		"100%":    2,
+	}, counts)
+	assert.Len(t, stats.Histogram, 6)
+}
+
+func TestStats_ExcludeZeroRequired(t *testing.T) {
+	t.Parallel()
+
+	recipes := []clients.Recipe{
+		recipeWithCoverage("half", 1, 2),
+		{ID: "empty", Title: "empty"},
+		{ID: "garnish", Title: "garnish", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "parsley", IsOptional: true},
+		}},
+	}
+
+	for _, exclude := range []bool{false, true} {
+		pantryMock := mocks.NewMockPantryFetcher(t)
+		recipeMock := mocks.NewMockRecipeFetcher(t)
+		dictMock := mocks.NewMockDictionaryFetcher(t)
+
+		pantryMock.EXPECT().GetPantry(mock.Anything).Return(statsPantry(recipes), nil)
+		recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+
+		svc := New(pantryMock, recipeMock, dictMock, WithStatsExcludeZeroRequired(exclude))
+		stats, err := svc.Stats(context.Background(), ScoreOptions{})
+		require.NoError(t, err)
+
+		top := stats.Histogram[len(stats.Histogram)-1]
+		if exclude {
+			assert.Equal(t, 1, stats.TotalRecipes)
+			assert.Equal(t, 2, stats.ExcludedRecipes)
+			assert.Equal(t, 0, top.Count)
+			assert.Equal(t, 0, stats.MakeableCount)
+		} else {
+			assert.Equal(t, 3, stats.TotalRecipes)
+			assert.Equal(t, 0, stats.ExcludedRecipes)
+			assert.Equal(t, 2, top.Count)
+		}
+	}
+}
+
+func TestStats_ExcludeZeroRequired_OptionalAsRequired(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "garnish", Ingredients: []clients.RecipeIngredient{{IngredientID: "parsley", IsOptional: true}}},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock, WithStatsExcludeZeroRequired(true))
+	stats, err := svc.Stats(context.Background(), ScoreOptions{TreatOptionalAsRequired: true})
+	require.NoError(t, err)
+
+	// Counting optional ingredients gives the recipe something to cover.
+	assert.Equal(t, 1, stats.TotalRecipes)
+	assert.Equal(t, 0, stats.ExcludedRecipes)
+}
+
+func TestStats_VersatilityScore(t *testing.T) {
+	t.Parallel()
+
+	recipes := []clients.Recipe{
+		recipeWithCoverage("full", 2, 2),  // makeable
+		recipeWithCoverage("full2", 1, 1), // makeable
+		recipeWithCoverage("one", 2, 3),   // near miss: 1 missing
+		recipeWithCoverage("two", 1, 3),   // 2 missing
+		recipeWithCoverage("three", 0, 3), // 3 missing
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want float64
+	}{
+		// 100 * (1*2 + 0.5*1) / (1*5)
+		{name: "default weights", want: 50},
+		// 100 * (2*2 + 1*1) / (2*5)
+		{name: "custom weights", opts: []Option{WithVersatilityWeights(VersatilityWeights{Makeable: 2, NearMiss: 1})}, want: 50},
+		// 100 * (1*2 + 0*1) / (1*5)
+		{name: "near misses ignored", opts: []Option{WithVersatilityWeights(VersatilityWeights{Makeable: 1})}, want: 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return(statsPantry(recipes), nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+
+			svc := New(pantryMock, recipeMock, nil, tt.opts...)
+			stats, err := svc.Stats(context.Background(), ScoreOptions{})
+			require.NoError(t, err)
+
+			assert.Equal(t, 2, stats.MakeableCount)
+			assert.Equal(t, 1, stats.NearMissCount)
+			assert.InDelta(t, tt.want, stats.VersatilityScore, 0.0001)
+		})
+	}
+}
+
+func TestVersatilityScore_Bounds(t *testing.T) {
+	t.Parallel()
+
+	weights := VersatilityWeights{Makeable: 1, NearMiss: 0.5}
+	assert.Zero(t, versatilityScore(&CatalogStats{}, weights), "empty catalog")
+	assert.InDelta(t, 100.0, versatilityScore(&CatalogStats{TotalRecipes: 3, MakeableCount: 3}, weights), 0.0001)
+	assert.InDelta(t, 100.0, versatilityScore(&CatalogStats{TotalRecipes: 2, NearMissCount: 2},
+		VersatilityWeights{Makeable: 1, NearMiss: 3}), 0.0001, "capped at 100")
+	assert.InDelta(t, 33.3, versatilityScore(&CatalogStats{TotalRecipes: 3, MakeableCount: 1}, weights), 0.0001)
+}
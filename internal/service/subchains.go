@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"maps"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// MaxSubDepth is the largest substitute chain length ScoreOptions.SubDepth
+// may request. Each hop beyond the first costs a round of dictionary
+// lookups.
+const MaxSubDepth = 5
+
+// prefetchSubstituteChains extends direct, the substitutes of the missing
+// ingredients, with the substitutes of those substitutes, hop by hop, until
+// chains of depth hops are known. fetched lists the ingredients whose
+// substitutes have already been looked up; it is not modified. The result
+// maps every looked-up ingredient to its substitutes.
+func (s *Service) prefetchSubstituteChains(
+	ctx context.Context,
+	direct map[string][]clients.IngredientSubstitute,
+	fetched map[string]bool,
+	depth int,
+) map[string][]clients.IngredientSubstitute {
+	graph := maps.Clone(direct)
+	fetched = maps.Clone(fetched)
+	frontier := unfetchedSubstitutes(direct, fetched)
+
+	for hop := 1; hop < depth && len(frontier) > 0; hop++ {
+		layer := s.prefetchSubstitutes(ctx, frontier)
+		if s.validateSubs {
+			s.dropUnknownSubstitutes(ctx, layer)
+		}
+		maps.Copy(fetched, frontier)
+		maps.Copy(graph, layer)
+		frontier = unfetchedSubstitutes(layer, fetched)
+	}
+	return graph
+}
+
+// unfetchedSubstitutes returns the substitute IDs in subsMap that are not in
+// fetched.
+func unfetchedSubstitutes(subsMap map[string][]clients.IngredientSubstitute, fetched map[string]bool) map[string]bool {
+	ids := make(map[string]bool)
+	for _, subs := range subsMap {
+		for _, sub := range subs {
+			if !fetched[sub.SubstituteID] {
+				ids[sub.SubstituteID] = true
+			}
+		}
+	}
+	return ids
+}
+
+// expandSubstituteChains flattens graph into each root ingredient's
+// substitutes reachable within depth hops, nearest first, so scoring tries a
+// direct substitute before a substitute of a substitute. A transitive
+// substitute's ratio is the product of the ratios along its chain, each
+// unset ratio counting as 1:1. An ingredient already on a root's chain is
+// never revisited, which cuts cycles.
+func expandSubstituteChains(
+	graph map[string][]clients.IngredientSubstitute,
+	depth int,
+) map[string][]clients.IngredientSubstitute {
+	type link struct {
+		id    string
+		ratio float64
+	}
+
+	out := make(map[string][]clients.IngredientSubstitute, len(graph))
+	for root := range graph {
+		visited := map[string]bool{root: true}
+		var subs []clients.IngredientSubstitute
+		frontier := []link{{id: root, ratio: 1}}
+		for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+			var next []link
+			for _, l := range frontier {
+				for _, sub := range graph[l.id] {
+					if visited[sub.SubstituteID] {
+						continue
+					}
+					visited[sub.SubstituteID] = true
+					ratio := l.ratio * substituteRatio(sub)
+					if hop > 0 {
+						sub = clients.IngredientSubstitute{
+							IngredientID: root,
+							SubstituteID: sub.SubstituteID,
+							Ratio:        ratio,
+							Notes:        sub.Notes,
+						}
+					}
+					subs = append(subs, sub)
+					next = append(next, link{id: sub.SubstituteID, ratio: ratio})
+				}
+			}
+			frontier = next
+		}
+		if len(subs) > 0 {
+			out[root] = subs
+		}
+	}
+	return out
+}
+
+// substituteRatio is sub's quantity ratio, treating an unset ratio as 1:1.
+func substituteRatio(sub clients.IngredientSubstitute) float64 {
+	if sub.Ratio <= 0 {
+		return 1
+	}
+	return sub.Ratio
+}
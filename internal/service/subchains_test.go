@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestScore_SubDepth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		depth       int
+		wantCanMake bool
+	}{
+		{name: "default is direct only", depth: 0, wantCanMake: false},
+		{name: "depth 1 is direct only", depth: 1, wantCanMake: false},
+		{name: "depth 2 follows one more hop", depth: 2, wantCanMake: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			pantryMock := mocks.NewMockPantryFetcher(t)
+			recipeMock := mocks.NewMockRecipeFetcher(t)
+			dictMock := mocks.NewMockDictionaryFetcher(t)
+
+			pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+				{ID: "p1", IngredientID: "oil", Quantity: 1},
+			}, nil)
+			recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+				{ID: "r1", Ingredients: []clients.RecipeIngredient{
+					{ID: "ri1", IngredientID: "butter", Quantity: 100, Unit: "g"},
+				}},
+			}, nil)
+			dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+				{IngredientID: "butter", SubstituteID: "margarine", Ratio: 1},
+			}, nil).Once()
+			if tc.depth > 1 {
+				dictMock.EXPECT().GetSubstitutes(mock.Anything, "margarine").Return([]clients.IngredientSubstitute{
+					{IngredientID: "margarine", SubstituteID: "oil", Ratio: 0.8},
+				}, nil).Once()
+			}
+			dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).
+				Return(nil, clients.ErrIngredientNotFound).Maybe()
+
+			svc := New(pantryMock, recipeMock, dictMock)
+			res, err := svc.Score(context.Background(), ScoreOptions{
+				AllowSubs:         true,
+				SubDepth:          tc.depth,
+				IncludeUnmakeable: true,
+				IncludeUsed:       true,
+			})
+			require.NoError(t, err)
+			require.Len(t, res.Results, 1)
+
+			result := res.Results[0]
+			assert.Equal(t, tc.wantCanMake, result.CanMake)
+			if !tc.wantCanMake {
+				assert.Nil(t, result.SubstitutedWith)
+				return
+			}
+			assert.Equal(t, map[string]string{"butter": "oil"}, result.SubstitutedWith)
+			require.Len(t, result.UsedIngredients, 1)
+			assert.Equal(t, "oil", result.UsedIngredients[0].IngredientID)
+			assert.InDelta(t, 80, result.UsedIngredients[0].Quantity, 1e-9)
+		})
+	}
+}
+
+func TestScore_SubDepthCycle(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{ID: "p1", IngredientID: "flour", Quantity: 1},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}, {IngredientID: "butter"}}},
+	}, nil)
+	// butter -> margarine -> ghee -> butter: each is looked up exactly once.
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "margarine", Ratio: 1},
+	}, nil).Once()
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "margarine").Return([]clients.IngredientSubstitute{
+		{IngredientID: "margarine", SubstituteID: "ghee", Ratio: 1},
+	}, nil).Once()
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "ghee").Return([]clients.IngredientSubstitute{
+		{IngredientID: "ghee", SubstituteID: "butter", Ratio: 1},
+	}, nil).Once()
+	dictMock.EXPECT().GetIngredient(mock.Anything, mock.Anything).
+		Return(nil, clients.ErrIngredientNotFound).Maybe()
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{
+		AllowSubs:         true,
+		SubDepth:          MaxSubDepth,
+		IncludeUnmakeable: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, res.Results, 1)
+	assert.False(t, res.Results[0].CanMake)
+	require.Len(t, res.Results[0].MissingIngredients, 1)
+	assert.Equal(t, "butter", res.Results[0].MissingIngredients[0].IngredientID)
+}
+
+func TestExpandSubstituteChains(t *testing.T) {
+	t.Parallel()
+
+	graph := map[string][]clients.IngredientSubstitute{
+		"a": {{IngredientID: "a", SubstituteID: "b", Ratio: 2}, {IngredientID: "a", SubstituteID: "c"}},
+		"b": {{IngredientID: "b", SubstituteID: "d", Ratio: 0.5}, {IngredientID: "b", SubstituteID: "a", Ratio: 1}},
+		"d": {{IngredientID: "d", SubstituteID: "e", Ratio: 3, Notes: "melted"}},
+	}
+
+	got := expandSubstituteChains(graph, 3)
+	assert.Equal(t, []clients.IngredientSubstitute{
+		{IngredientID: "a", SubstituteID: "b", Ratio: 2},
+		{IngredientID: "a", SubstituteID: "c"},
+		{IngredientID: "a", SubstituteID: "d", Ratio: 1},
+		{IngredientID: "a", SubstituteID: "e", Ratio: 3, Notes: "melted"},
+	}, got["a"])
+	// b reaches a, its own substitute's root, but never itself.
+	assert.Equal(t, []clients.IngredientSubstitute{
+		{IngredientID: "b", SubstituteID: "d", Ratio: 0.5},
+		{IngredientID: "b", SubstituteID: "a", Ratio: 1},
+		{IngredientID: "b", SubstituteID: "e", Ratio: 1.5, Notes: "melted"},
+		{IngredientID: "b", SubstituteID: "c", Ratio: 1},
+	}, got["b"])
+
+	got = expandSubstituteChains(graph, 1)
+	assert.Equal(t, graph["a"], got["a"])
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+)
+
+// SubstituteSuggestion is a known substitute for a missing ingredient.
+// InPantry marks substitutes the user can use right away.
+type SubstituteSuggestion struct {
+	SubstituteID string  `json:"substitute_id"`
+	Name         string  `json:"name,omitempty"`
+	Ratio        float64 `json:"ratio"`
+	Notes        string  `json:"notes,omitempty"`
+	InPantry     bool    `json:"in_pantry"`
+}
+
+// SubstitutionUnlock reports a required ingredient that substitutes covered
+// in RecipeCount of the returned recipes, and which substitutes did so.
+// Under [SubstitutionReportAvailable], UnusedSubstituteIDs lists in-pantry
+// substitutes for the ingredient that went unused because the pantry held
+// the ingredient itself.
+type SubstitutionUnlock struct {
+	IngredientID        string   `json:"ingredient_id"`
+	Name                string   `json:"name,omitempty"`
+	RecipeCount         int      `json:"recipe_count"`
+	SubstituteIDs       []string `json:"substitute_ids"`
+	UnusedSubstituteIDs []string `json:"unused_substitute_ids,omitempty"`
+}
+
+// SubstitutionReportPolicy decides which substitutes the substitution
+// summary reports.
+type SubstitutionReportPolicy string
+
+const (
+	// SubstitutionReportNecessary reports only substitutes that covered a
+	// missing ingredient (default).
+	SubstitutionReportNecessary SubstitutionReportPolicy = "necessary"
+	// SubstitutionReportAvailable also notes in-pantry substitutes for
+	// ingredients matched directly. It costs extra dictionary lookups.
+	SubstitutionReportAvailable SubstitutionReportPolicy = "available"
+)
+
+// summarizeSubstitutions aggregates substitute matches across results, most
+// widely useful substitutions first.
+func summarizeSubstitutions(results []MatchResult) []SubstitutionUnlock {
+	index := make(map[string]int)
+	summary := make([]SubstitutionUnlock, 0)
+	for _, r := range results {
+		for _, m := range r.MatchedIngredients {
+			if m.Source != MatchSourceSubstitute {
+				continue
+			}
+			i, ok := index[m.IngredientID]
+			if !ok {
+				i = len(summary)
+				index[m.IngredientID] = i
+				summary = append(summary, SubstitutionUnlock{IngredientID: m.IngredientID, Name: m.Name})
+			}
+			summary[i].RecipeCount++
+			if !slices.Contains(summary[i].SubstituteIDs, m.SubstituteID) {
+				summary[i].SubstituteIDs = append(summary[i].SubstituteIDs, m.SubstituteID)
+			}
+		}
+	}
+
+	sort.SliceStable(summary, func(i, j int) bool {
+		if summary[i].RecipeCount != summary[j].RecipeCount {
+			return summary[i].RecipeCount > summary[j].RecipeCount
+		}
+		return summary[i].IngredientID < summary[j].IngredientID
+	})
+	return summary
+}
+
+// loadUnusedSubstitutes fetches substitutes for the ingredients results
+// matched directly, honouring the same substitute sources and exclusions as
+// scoring.
+func (s *Service) loadUnusedSubstitutes(
+	ctx context.Context,
+	results []MatchResult,
+	opts ScoreOptions,
+) map[string][]clients.IngredientSubstitute {
+	direct := make(map[string]bool)
+	for _, r := range results {
+		for _, m := range r.MatchedIngredients {
+			if m.Source == MatchSourceDirect {
+				direct[m.IngredientID] = true
+			}
+		}
+	}
+	if len(direct) == 0 {
+		return nil
+	}
+
+	subsMap := make(map[string][]clients.IngredientSubstitute)
+	if opts.AllowSubs {
+		subsMap = s.prefetchSubstitutes(ctx, direct)
+		if s.validateSubs {
+			s.dropUnknownSubstitutes(ctx, subsMap)
+		}
+	}
+	if opts.UseGroups {
+		mergeGroupSubstitutes(subsMap, s.prefetchGroups(ctx, direct))
+	}
+	removeExcludedSubstitutes(subsMap, opts.ExcludeSubs)
+	return subsMap
+}
+
+// noteUnusedSubstitutes adds the in-pantry substitutes of directly matched
+// ingredients to summary. Ingredients no substitute was needed for are
+// appended after the existing entries, ordered by ingredient ID.
+func noteUnusedSubstitutes(
+	summary []SubstitutionUnlock,
+	results []MatchResult,
+	subsMap map[string][]clients.IngredientSubstitute,
+	pantrySet map[string]bool,
+) []SubstitutionUnlock {
+	index := make(map[string]int, len(summary))
+	for i, u := range summary {
+		index[u.IngredientID] = i
+	}
+	added := len(summary)
+	for _, r := range results {
+		for _, m := range r.MatchedIngredients {
+			if m.Source != MatchSourceDirect {
+				continue
+			}
+			for _, sub := range subsMap[m.IngredientID] {
+				if !pantrySet[sub.SubstituteID] {
+					continue
+				}
+				i, ok := index[m.IngredientID]
+				if !ok {
+					i = len(summary)
+					index[m.IngredientID] = i
+					summary = append(summary, SubstitutionUnlock{
+						IngredientID:  m.IngredientID,
+						Name:          m.Name,
+						SubstituteIDs: []string{},
+					})
+				}
+				if !slices.Contains(summary[i].UnusedSubstituteIDs, sub.SubstituteID) {
+					summary[i].UnusedSubstituteIDs = append(summary[i].UnusedSubstituteIDs, sub.SubstituteID)
+				}
+			}
+		}
+	}
+
+	tail := summary[added:]
+	sort.Slice(tail, func(i, j int) bool {
+		return tail[i].IngredientID < tail[j].IngredientID
+	})
+	return summary
+}
+
+// attachSuggestions lists substitutes on each missing ingredient, with
+// in-pantry substitutes ahead of ones that would need to be bought.
+func attachSuggestions(
+	results []MatchResult,
+	subsMap map[string][]clients.IngredientSubstitute,
+	pantrySet map[string]bool,
+) {
+	for i := range results {
+		for j := range results[i].MissingIngredients {
+			m := &results[i].MissingIngredients[j]
+			m.Suggestions = buildSuggestions(subsMap[m.IngredientID], pantrySet)
+		}
+	}
+}
+
+func buildSuggestions(subs []clients.IngredientSubstitute, pantrySet map[string]bool) []SubstituteSuggestion {
+	if len(subs) == 0 {
+		return nil
+	}
+
+	suggestions := make([]SubstituteSuggestion, 0, len(subs))
+	for _, sub := range subs {
+		suggestions = append(suggestions, SubstituteSuggestion{
+			SubstituteID: sub.SubstituteID,
+			Ratio:        sub.Ratio,
+			Notes:        sub.Notes,
+			InPantry:     pantrySet[sub.SubstituteID],
+		})
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].InPantry && !suggestions[j].InPantry
+	})
+	return suggestions
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestBuildSuggestions_InPantryFirst(t *testing.T) {
+	t.Parallel()
+
+	suggestions := buildSuggestions([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "margarine", Ratio: 1},
+		{IngredientID: "butter", SubstituteID: "ghee", Ratio: 1},
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 0.75, Notes: "for baking"},
+	}, map[string]bool{"oil": true})
+
+	require.Len(t, suggestions, 3)
+	assert.Equal(t, "oil", suggestions[0].SubstituteID)
+	assert.True(t, suggestions[0].InPantry)
+	assert.InDelta(t, 0.75, suggestions[0].Ratio, 0.0001)
+	assert.Equal(t, "for baking", suggestions[0].Notes)
+	assert.Equal(t, "margarine", suggestions[1].SubstituteID)
+	assert.Equal(t, "ghee", suggestions[2].SubstituteID)
+	assert.False(t, suggestions[1].InPantry)
+
+	assert.Nil(t, buildSuggestions(nil, nil))
+}
+
+func TestScore_SuggestSubs(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour"}, {IngredientID: "oil"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{
+			ID: "r1",
+			Ingredients: []clients.RecipeIngredient{
+				{ID: "ri1", IngredientID: "flour"},
+				{ID: "ri2", IngredientID: "butter"},
+			},
+		},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "margarine", Ratio: 1},
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 1},
+	}, nil)
+	for id, name := range map[string]string{"butter": "Butter", "margarine": "Margarine", "oil": "Oil"} {
+		dictMock.EXPECT().GetIngredient(mock.Anything, id).Return(&clients.IngredientDetail{ID: id, Name: name}, nil)
+	}
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{SuggestSubs: true, IncludeUnmakeable: true, MaxMissing: 0})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 1)
+	result := res.Results[0]
+	assert.False(t, result.CanMake, "suggestions alone must not satisfy an ingredient")
+	require.Len(t, result.MissingIngredients, 1)
+
+	suggestions := result.MissingIngredients[0].Suggestions
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, SubstituteSuggestion{SubstituteID: "oil", Name: "Oil", Ratio: 1, InPantry: true}, suggestions[0])
+	assert.Equal(t, SubstituteSuggestion{SubstituteID: "margarine", Name: "Margarine", Ratio: 1}, suggestions[1])
+}
+
+func TestScore_SuggestSubsDoesNotAffectCanMake(t *testing.T) {
+	t.Parallel()
+
+	recipes := []clients.Recipe{
+		{ID: "r1", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "butter"},
+		}},
+	}
+	subs := []clients.IngredientSubstitute{{IngredientID: "butter", SubstituteID: "oil", Ratio: 1}}
+
+	score := func(opts ScoreOptions) MatchResult {
+		pantryMock := mocks.NewMockPantryFetcher(t)
+		recipeMock := mocks.NewMockRecipeFetcher(t)
+		dictMock := mocks.NewMockDictionaryFetcher(t)
+		pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+			{IngredientID: "flour"}, {IngredientID: "oil"},
+		}, nil)
+		recipeMock.EXPECT().GetRecipes(mock.Anything).Return(recipes, nil)
+		if opts.AllowSubs || opts.SuggestSubs {
+			dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return(subs, nil)
+		}
+
+		opts.IncludeUnmakeable = true
+		opts.skipNames = true
+		res, err := New(pantryMock, recipeMock, dictMock).Score(context.Background(), opts)
+		require.NoError(t, err)
+		require.Len(t, res.Results, 1)
+		return res.Results[0]
+	}
+
+	strict := score(ScoreOptions{})
+	suggested := score(ScoreOptions{SuggestSubs: true})
+	allowed := score(ScoreOptions{AllowSubs: true, SuggestSubs: true})
+
+	// Suggestions are fetched even with allow_subs off, but leave scoring alone.
+	assert.Equal(t, strict.CanMake, suggested.CanMake)
+	assert.InDelta(t, strict.CoveragePct, suggested.CoveragePct, 0.0001)
+	assert.Empty(t, strict.MissingIngredients[0].Suggestions)
+	require.Len(t, suggested.MissingIngredients, 1)
+	require.Len(t, suggested.MissingIngredients[0].Suggestions, 1)
+	assert.True(t, suggested.MissingIngredients[0].Suggestions[0].InPantry)
+
+	// With allow_subs on, the same substitute makes the recipe.
+	assert.True(t, allowed.CanMake)
+	assert.Empty(t, allowed.MissingIngredients)
+}
+
+func TestScore_SubstitutionSummary(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour"}, {IngredientID: "oil"}, {IngredientID: "yogurt"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "cake", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "butter"}, {IngredientID: "buttermilk"},
+		}},
+		{ID: "cookies", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "butter"},
+		}},
+		{ID: "bread", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}}},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 1},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "buttermilk").Return([]clients.IngredientSubstitute{
+		{IngredientID: "buttermilk", SubstituteID: "yogurt", Ratio: 1},
+	}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true, SubstitutionSummary: true, skipNames: true})
+	require.NoError(t, err)
+
+	require.Len(t, res.Results, 3)
+	assert.Equal(t, []SubstitutionUnlock{
+		{IngredientID: "butter", RecipeCount: 2, SubstituteIDs: []string{"oil"}},
+		{IngredientID: "buttermilk", RecipeCount: 1, SubstituteIDs: []string{"yogurt"}},
+	}, res.SubstitutionSummary)
+	for _, r := range res.Results {
+		assert.Nil(t, r.MatchedIngredients, "matched ingredients only kept for the summary")
+	}
+}
+
+func TestScore_SubstitutionReportPolicy(t *testing.T) {
+	t.Parallel()
+
+	score := func(t *testing.T, opts ...Option) []SubstitutionUnlock {
+		t.Helper()
+		pantryMock := mocks.NewMockPantryFetcher(t)
+		recipeMock := mocks.NewMockRecipeFetcher(t)
+		dictMock := mocks.NewMockDictionaryFetcher(t)
+
+		// The pantry holds butter itself and oil, a substitute for it.
+		pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+			{IngredientID: "flour"}, {IngredientID: "butter"}, {IngredientID: "oil"}, {IngredientID: "yogurt"},
+		}, nil)
+		recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+			{ID: "cake", Ingredients: []clients.RecipeIngredient{
+				{IngredientID: "flour"}, {IngredientID: "butter"}, {IngredientID: "buttermilk"},
+			}},
+		}, nil)
+		dictMock.EXPECT().GetSubstitutes(mock.Anything, "buttermilk").Return([]clients.IngredientSubstitute{
+			{IngredientID: "buttermilk", SubstituteID: "yogurt", Ratio: 1},
+		}, nil)
+		dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+			{IngredientID: "butter", SubstituteID: "oil", Ratio: 1},
+			{IngredientID: "butter", SubstituteID: "margarine", Ratio: 1},
+		}, nil).Maybe()
+		dictMock.EXPECT().GetSubstitutes(mock.Anything, "flour").Return(nil, nil).Maybe()
+
+		res, err := New(pantryMock, recipeMock, dictMock, opts...).Score(context.Background(), ScoreOptions{
+			AllowSubs:           true,
+			SubstitutionSummary: true,
+			skipNames:           true,
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Results, 1)
+		return res.SubstitutionSummary
+	}
+
+	t.Run("necessary by default", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []SubstitutionUnlock{
+			{IngredientID: "buttermilk", RecipeCount: 1, SubstituteIDs: []string{"yogurt"}},
+		}, score(t))
+	})
+
+	t.Run("available", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, []SubstitutionUnlock{
+			{IngredientID: "buttermilk", RecipeCount: 1, SubstituteIDs: []string{"yogurt"}},
+			{IngredientID: "butter", SubstituteIDs: []string{}, UnusedSubstituteIDs: []string{"oil"}},
+		}, score(t, WithSubstitutionReport(SubstitutionReportAvailable)))
+	})
+}
+
+func TestSummarizeSubstitutions_Empty(t *testing.T) {
+	t.Parallel()
+
+	summary := summarizeSubstitutions([]MatchResult{{
+		MatchedIngredients: []MatchedIngredient{{IngredientID: "flour", Source: MatchSourceDirect}},
+	}})
+	assert.Empty(t, summary)
+	assert.NotNil(t, summary)
+}
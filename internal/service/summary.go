@@ -0,0 +1,37 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+// attachSummaries sets a one-line, human-readable Summary on each result.
+// Call it after name resolution so missing ingredients are named.
+func attachSummaries(results []MatchResult) {
+	for i := range results {
+		results[i].Summary = matchSummary(results[i])
+	}
+}
+
+// matchSummary describes r as "Ready to cook", "Makeable with substitutes",
+// or "Missing 2 ingredients: milk, eggs". Unnamed ingredients are listed by
+// ID.
+func matchSummary(r MatchResult) string {
+	switch {
+	case len(r.MissingIngredients) > 0:
+		names := make([]string, 0, len(r.MissingIngredients))
+		for _, m := range r.MissingIngredients {
+			names = append(names, cmp.Or(m.Name, m.IngredientID))
+		}
+		noun := "ingredients"
+		if len(names) == 1 {
+			noun = "ingredient"
+		}
+		return fmt.Sprintf("Missing %d %s: %s", len(names), noun, strings.Join(names, ", "))
+	case len(r.SubstitutedWith) > 0:
+		return "Makeable with substitutes"
+	default:
+		return "Ready to cook"
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func TestMatchSummary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		result MatchResult
+		want   string
+	}{
+		{name: "makeable", result: MatchResult{CanMake: true}, want: "Ready to cook"},
+		{
+			name:   "substituted",
+			result: MatchResult{CanMake: true, SubstitutedWith: map[string]string{"butter": "oil"}},
+			want:   "Makeable with substitutes",
+		},
+		{
+			name: "missing one",
+			result: MatchResult{MissingIngredients: []MissingIngredient{
+				{IngredientID: "milk", Name: "milk"},
+			}},
+			want: "Missing 1 ingredient: milk",
+		},
+		{
+			name: "missing several, one unnamed",
+			result: MatchResult{MissingIngredients: []MissingIngredient{
+				{IngredientID: "milk", Name: "milk"},
+				{IngredientID: "ing-eggs"},
+			}},
+			want: "Missing 2 ingredients: milk, ing-eggs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, matchSummary(tt.result))
+		})
+	}
+}
+
+func TestScore_IncludeSummary(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour"}, {IngredientID: "oil"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "bread", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}}},
+		{ID: "cake", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}, {IngredientID: "butter"}}},
+		{ID: "pancakes", Ingredients: []clients.RecipeIngredient{
+			{IngredientID: "flour"}, {IngredientID: "milk"}, {IngredientID: "eggs"},
+		}},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 1},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, mock.Anything).Return(nil, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "milk").Return(&clients.IngredientDetail{ID: "milk", Name: "milk"}, nil)
+	dictMock.EXPECT().GetIngredient(mock.Anything, "eggs").Return(&clients.IngredientDetail{ID: "eggs", Name: "eggs"}, nil)
+
+	svc := New(pantryMock, recipeMock, dictMock)
+	res, err := svc.Score(context.Background(), ScoreOptions{
+		AllowSubs:         true,
+		IncludeUnmakeable: true,
+		IncludeSummary:    true,
+	})
+	require.NoError(t, err)
+
+	summaries := map[string]string{}
+	for _, r := range res.Results {
+		summaries[r.Recipe.ID] = r.Summary
+	}
+	assert.Equal(t, map[string]string{
+		"bread":    "Ready to cook",
+		"cake":     "Makeable with substitutes",
+		"pancakes": "Missing 2 ingredients: milk, eggs",
+	}, summaries)
+}
+
+func TestScore_SummaryOffByDefault(t *testing.T) {
+	t.Parallel()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{{IngredientID: "flour"}}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "bread", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}}},
+	}, nil)
+
+	res, err := New(pantryMock, recipeMock, dictMock).Score(context.Background(), ScoreOptions{})
+	require.NoError(t, err)
+	require.Len(t, res.Results, 1)
+	assert.Empty(t, res.Results[0].Summary)
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrIncompatibleUnits is returned when a quantity cannot be converted
+// between two units, because they measure different dimensions or either
+// unit is unknown.
+var ErrIncompatibleUnits = errors.New("incompatible units")
+
+type dimension int
+
+const (
+	dimensionMass dimension = iota + 1
+	dimensionVolume
+)
+
+// unitFactor is how many of its dimension's base unit (grams or millilitres)
+// one unit holds.
+type unitFactor struct {
+	dim    dimension
+	factor float64
+}
+
+var units = map[string]unitFactor{
+	"g":           {dimensionMass, 1},
+	"gram":        {dimensionMass, 1},
+	"grams":       {dimensionMass, 1},
+	"kg":          {dimensionMass, 1000},
+	"kilogram":    {dimensionMass, 1000},
+	"kilograms":   {dimensionMass, 1000},
+	"oz":          {dimensionMass, 28.349523125},
+	"ounce":       {dimensionMass, 28.349523125},
+	"ounces":      {dimensionMass, 28.349523125},
+	"lb":          {dimensionMass, 453.59237},
+	"lbs":         {dimensionMass, 453.59237},
+	"pound":       {dimensionMass, 453.59237},
+	"pounds":      {dimensionMass, 453.59237},
+	"ml":          {dimensionVolume, 1},
+	"milliliter":  {dimensionVolume, 1},
+	"milliliters": {dimensionVolume, 1},
+	"millilitre":  {dimensionVolume, 1},
+	"millilitres": {dimensionVolume, 1},
+	"l":           {dimensionVolume, 1000},
+	"liter":       {dimensionVolume, 1000},
+	"liters":      {dimensionVolume, 1000},
+	"litre":       {dimensionVolume, 1000},
+	"litres":      {dimensionVolume, 1000},
+	"tsp":         {dimensionVolume, 4.92892159375},
+	"teaspoon":    {dimensionVolume, 4.92892159375},
+	"teaspoons":   {dimensionVolume, 4.92892159375},
+	"tbsp":        {dimensionVolume, 14.78676478125},
+	"tablespoon":  {dimensionVolume, 14.78676478125},
+	"tablespoons": {dimensionVolume, 14.78676478125},
+	"cup":         {dimensionVolume, 236.5882365},
+	"cups":        {dimensionVolume, 236.5882365},
+}
+
+func normalizeUnit(unit string) string {
+	return strings.ToLower(strings.TrimSpace(unit))
+}
+
+// ConvertQuantity converts value from one unit to another. Common mass (g,
+// kg, oz, lb) and volume (ml, l, tsp, tbsp, cup) units convert within their
+// dimension; any unit converts to itself, ignoring case. Anything else
+// returns [ErrIncompatibleUnits].
+func ConvertQuantity(value float64, from, to string) (float64, error) {
+	from, to = normalizeUnit(from), normalizeUnit(to)
+	if from == to {
+		return value, nil
+	}
+	f, fok := units[from]
+	t, tok := units[to]
+	if !fok || !tok || f.dim != t.dim {
+		return 0, fmt.Errorf("%w: %q to %q", ErrIncompatibleUnits, from, to)
+	}
+	return value * f.factor / t.factor, nil
+}
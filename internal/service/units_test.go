@@ -0,0 +1,41 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertQuantity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value    float64
+		from, to string
+		want     float64
+	}{
+		{value: 1, from: "kg", to: "g", want: 1000},
+		{value: 500, from: "g", to: "kg", want: 0.5},
+		{value: 1, from: "lb", to: "oz", want: 16},
+		{value: 1, from: "cup", to: "tbsp", want: 16},
+		{value: 1, from: "tbsp", to: "tsp", want: 3},
+		{value: 2, from: "L", to: "ml", want: 2000},
+		{value: 3, from: "Grams", to: "g", want: 3},
+		{value: 4, from: "whole", to: "WHOLE", want: 4},
+	}
+	for _, tt := range tests {
+		got, err := ConvertQuantity(tt.value, tt.from, tt.to)
+		require.NoError(t, err, "%s to %s", tt.from, tt.to)
+		assert.InDelta(t, tt.want, got, 0.0001, "%s to %s", tt.from, tt.to)
+	}
+}
+
+func TestConvertQuantity_Incompatible(t *testing.T) {
+	t.Parallel()
+
+	for _, pair := range [][2]string{{"g", "cup"}, {"ml", "lb"}, {"whole", "g"}, {"pinch", "dash"}} {
+		_, err := ConvertQuantity(1, pair[0], pair[1])
+		require.ErrorIs(t, err, ErrIncompatibleUnits, "%s to %s", pair[0], pair[1])
+	}
+}
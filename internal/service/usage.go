@@ -0,0 +1,73 @@
+package service
+
+import (
+	"sort"
+	"sync"
+)
+
+// SubstituteUsage counts how often a substitute covered an ingredient in
+// returned matches since the service started.
+type SubstituteUsage struct {
+	IngredientID string `json:"ingredient_id"`
+	SubstituteID string `json:"substitute_id"`
+	Count        int64  `json:"count"`
+}
+
+type usageKey struct {
+	ingredientID string
+	substituteID string
+}
+
+// substituteUsage is an in-memory, concurrency-safe set of usage counters.
+type substituteUsage struct {
+	mu     sync.Mutex
+	counts map[usageKey]int64
+}
+
+func newSubstituteUsage() *substituteUsage {
+	return &substituteUsage{counts: make(map[usageKey]int64)}
+}
+
+// record counts every substitute match in results.
+func (u *substituteUsage) record(results []MatchResult) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, r := range results {
+		for _, m := range r.MatchedIngredients {
+			if m.Source == MatchSourceSubstitute {
+				u.counts[usageKey{m.IngredientID, m.SubstituteID}]++
+			}
+		}
+	}
+}
+
+// snapshot returns the counters, most used first.
+func (u *substituteUsage) snapshot() []SubstituteUsage {
+	u.mu.Lock()
+	out := make([]SubstituteUsage, 0, len(u.counts))
+	for k, n := range u.counts {
+		out = append(out, SubstituteUsage{IngredientID: k.ingredientID, SubstituteID: k.substituteID, Count: n})
+	}
+	u.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		if out[i].IngredientID != out[j].IngredientID {
+			return out[i].IngredientID < out[j].IngredientID
+		}
+		return out[i].SubstituteID < out[j].SubstituteID
+	})
+	return out
+}
+
+// SubstituteUsage returns how often each substitute has been used in
+// returned matches, most used first. It is empty when usage counting is
+// disabled.
+func (s *Service) SubstituteUsage() []SubstituteUsage {
+	if s.usage == nil {
+		return []SubstituteUsage{}
+	}
+	return s.usage.snapshot()
+}
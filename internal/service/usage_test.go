@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mwhite7112/woodpantry-matching/internal/clients"
+	"github.com/mwhite7112/woodpantry-matching/internal/mocks"
+)
+
+func usageService(t *testing.T, opts ...Option) *Service {
+	t.Helper()
+
+	pantryMock := mocks.NewMockPantryFetcher(t)
+	recipeMock := mocks.NewMockRecipeFetcher(t)
+	dictMock := mocks.NewMockDictionaryFetcher(t)
+
+	pantryMock.EXPECT().GetPantry(mock.Anything).Return([]clients.PantryItem{
+		{IngredientID: "flour"}, {IngredientID: "oil"},
+	}, nil)
+	recipeMock.EXPECT().GetRecipes(mock.Anything).Return([]clients.Recipe{
+		{ID: "cake", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}, {IngredientID: "butter"}}},
+		{ID: "cookies", Ingredients: []clients.RecipeIngredient{{IngredientID: "butter"}}},
+		{ID: "bread", Ingredients: []clients.RecipeIngredient{{IngredientID: "flour"}}},
+	}, nil)
+	dictMock.EXPECT().GetSubstitutes(mock.Anything, "butter").Return([]clients.IngredientSubstitute{
+		{IngredientID: "butter", SubstituteID: "oil", Ratio: 1},
+	}, nil).Maybe()
+
+	return New(pantryMock, recipeMock, dictMock, opts...)
+}
+
+func TestScore_RecordsSubstituteUsage(t *testing.T) {
+	t.Parallel()
+
+	svc := usageService(t)
+	assert.Empty(t, svc.SubstituteUsage())
+
+	const calls = 8
+	var wg sync.WaitGroup
+	for range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true, skipNames: true})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// cake and cookies each use oil for butter on every call.
+	assert.Equal(t, []SubstituteUsage{
+		{IngredientID: "butter", SubstituteID: "oil", Count: 2 * calls},
+	}, svc.SubstituteUsage())
+
+	// Direct-only scoring and aggregate callers don't count.
+	_, err := svc.Score(context.Background(), ScoreOptions{skipNames: true})
+	require.NoError(t, err)
+	_, err = svc.Stats(context.Background(), ScoreOptions{AllowSubs: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*calls), svc.SubstituteUsage()[0].Count)
+}
+
+func TestScore_SubstituteUsageDisabled(t *testing.T) {
+	t.Parallel()
+
+	svc := usageService(t, WithSubstituteUsage(false))
+	_, err := svc.Score(context.Background(), ScoreOptions{AllowSubs: true, skipNames: true})
+	require.NoError(t, err)
+
+	assert.Empty(t, svc.SubstituteUsage())
+	assert.NotNil(t, svc.SubstituteUsage())
+}